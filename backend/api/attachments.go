@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/storage"
+	"real-time-forum/backend/store"
+	"real-time-forum/backend/utils"
+	"slices"
+)
+
+// storeAttachment writes the file of an attachment, and the thumbnail of an image,
+// to storage and inserts its row. Images that cannot be decoded are rejected with
+// utils.FieldErrors.
+func (h *Handler) storeAttachment(attachment *database.Attachment, data []byte) error {
+	var thumbnail []byte
+	if utils.IsImage(attachment.MimeType) {
+		width, height, small, err := utils.ProcessImage(data)
+		if err != nil {
+			return err
+		}
+		attachment.Width, attachment.Height, thumbnail = &width, &height, small
+	}
+
+	key, err := h.files.Put(data)
+	if err != nil {
+		return err
+	}
+	attachment.StorageKey = key
+	if utils.IsImage(attachment.MimeType) {
+		// small images are their own thumbnail
+		thumbnailKey := key
+		if thumbnail != nil {
+			if thumbnailKey, err = h.files.Put(thumbnail); err != nil {
+				return err
+			}
+		}
+		attachment.ThumbnailKey = &thumbnailKey
+	}
+
+	return h.store.Attachments.Create(attachment)
+}
+
+// serveAttachment writes the file of attachment {id}, or its thumbnail, when the
+// current user can see it: any post attachment they can read, the attachments of
+// their own private messages, the unattached files they uploaded and the avatars
+// not hidden from them. Others get a 404 so that attachment IDs reveal nothing.
+func (h *Handler) serveAttachment(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.store.Attachments.Get(id)
+	if err != nil && err != store.ErrNotFound {
+		log.Println("Error loading attachment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	visible := false
+	if err == nil {
+		if visible, err = h.canSeeAttachment(principal, attachment); err != nil {
+			log.Println("Error checking attachment access:", err)
+			response.WriteError(w, response.Internal())
+			return
+		}
+	}
+	if !visible || thumbnail && attachment.ThumbnailKey == nil {
+		response.WriteError(w, response.NotFound("Attachment not found"))
+		return
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		key = *attachment.ThumbnailKey
+	}
+	file, err := h.files.Open(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Println("Missing file of attachment", attachment.ID)
+			response.WriteError(w, response.NotFound("Attachment not found"))
+			return
+		}
+		log.Println("Error opening attachment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	defer file.Close()
+
+	// only sniffed, allow-listed types are served, and never as anything else
+	header := w.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "private, max-age=86400")
+	if !thumbnail {
+		header.Set("Content-Type", attachment.MimeType)
+	}
+	disposition := "attachment"
+	if utils.IsImage(attachment.MimeType) {
+		disposition = "inline"
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+
+	http.ServeContent(w, r, "", attachment.CreatedAt, file)
+}
+
+// canSeeAttachment reports whether the principal can see an attachment.
+func (h *Handler) canSeeAttachment(principal *Principal, attachment database.Attachment) (bool, error) {
+	switch {
+	case attachment.PostID != nil:
+		_, err := h.store.Posts.Get(principal.UserID, *attachment.PostID)
+		if err == store.ErrNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	case attachment.MessageID != nil:
+		message, err := h.store.Messages.Get(*attachment.MessageID)
+		if err == store.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return message.SenderID.String() == principal.UserID || message.ReceiverID.String() == principal.UserID, nil
+	}
+	if attachment.UploaderID.String() == principal.UserID {
+		return true, nil
+	}
+	return h.isVisibleAvatar(principal.UserID, attachment)
+}
+
+// checkAttachments loads the attachments with the given IDs for a new post or
+// message, as targetType says. They must be unattached files of the uploader, at
+// most utils.MaxAttachments of them, and only images can go on posts.
+func (h *Handler) checkAttachments(uploaderID string, ids []int, targetType string) ([]database.Attachment, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) > utils.MaxAttachments {
+		return nil, response.InvalidField("attachment_ids", "Too many attachments")
+	}
+
+	attachments, err := h.store.Attachments.Unattached(uploaderID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(ids) {
+		return nil, response.InvalidField("attachment_ids", "Unknown attachment")
+	}
+	if targetType == database.TargetPost {
+		for _, attachment := range attachments {
+			if !utils.IsImage(attachment.MimeType) {
+				return nil, response.InvalidField("attachment_ids", "Only images can be attached to posts")
+			}
+		}
+	}
+	return attachments, nil
+}
+
+// attach links checked attachments to the post or message they were sent with,
+// as targetType says.
+func (h *Handler) attach(attachments []database.Attachment, targetType string, targetID int) error {
+	ids := make([]int, len(attachments))
+	for i := range attachments {
+		ids[i] = attachments[i].ID
+		if targetType == database.TargetPost {
+			attachments[i].PostID = &targetID
+		} else {
+			attachments[i].MessageID = &targetID
+		}
+	}
+	return h.store.Attachments.Attach(ids, targetType, targetID)
+}
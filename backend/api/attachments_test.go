@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// upload sends data as u's file named filename, claimed to be of contentType, and
+// returns the status and body of the response.
+func (s *testServer) upload(t *testing.T, u *testUser, filename, contentType string, data []byte) (int, string) {
+	t.Helper()
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := u.client.Post(s.URL+"/api/attachments", writer.FormDataContentType(), &form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// uploadID uploads a file as u and returns the ID of its attachment.
+func (s *testServer) uploadID(t *testing.T, u *testUser, filename, contentType string, data []byte) int {
+	t.Helper()
+	status, body := s.upload(t, u, filename, contentType, data)
+	if status != http.StatusCreated {
+		t.Fatalf("uploading %s: %d %s", filename, status, body)
+	}
+	var attachment database.Attachment
+	if err := json.Unmarshal([]byte(body), &attachment); err != nil {
+		t.Fatal(err)
+	}
+	return attachment.ID
+}
+
+// testPNG returns a small PNG image.
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMessageAttachmentOnlyForParticipants(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	carol := s.signUp(t, "carol")
+
+	id := s.uploadID(t, alice, "notes.txt", "text/plain", []byte("meeting notes"))
+	path := "/api/attachments/" + strconv.Itoa(id)
+	if status, _ := s.do(t, bob, http.MethodGet, path, nil); status != http.StatusNotFound {
+		t.Errorf("fetching another user's unattached upload: %d, want %d", status, http.StatusNotFound)
+	}
+
+	message := map[string]any{"content": "see attached", "attachment_ids": []int{id}}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/messages/"+bob.id, message); status != http.StatusOK {
+		t.Fatalf("sending the message: %d %s", status, body)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		u      *testUser
+		status int
+	}{
+		{"sender", alice, http.StatusOK},
+		{"receiver", bob, http.StatusOK},
+		{"someone else", carol, http.StatusNotFound},
+	} {
+		status, body := s.do(t, tt.u, http.MethodGet, path, nil)
+		if status != tt.status {
+			t.Errorf("fetching as the %s: %d, want %d", tt.name, status, tt.status)
+		}
+		if status == http.StatusOK && body != "meeting notes" {
+			t.Errorf("fetched %q as the %s, want the uploaded file", body, tt.name)
+		}
+	}
+}
+
+func TestAttachmentQuota(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+
+	// earlier uploads leaving room for a ten byte file
+	previous := database.Attachment{
+		UploaderID: uuid.FromStringOrNil(alice.id),
+		Filename:   "archive.zip",
+		MimeType:   "application/zip",
+		Size:       utils.AttachmentQuota - 10,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.store.Attachments.Create(&previous); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, body := s.upload(t, alice, "big.txt", "text/plain", []byte("eleven byte")); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload over the quota: %d %s, want %d", status, body, http.StatusRequestEntityTooLarge)
+	}
+	if status, body := s.upload(t, alice, "fits.txt", "text/plain", []byte("ten bytes!")); status != http.StatusCreated {
+		t.Errorf("upload filling the quota: %d %s, want %d", status, body, http.StatusCreated)
+	}
+	if status, body := s.upload(t, bob, "big.txt", "text/plain", []byte("eleven byte")); status != http.StatusCreated {
+		t.Errorf("upload by another user: %d %s, want %d", status, body, http.StatusCreated)
+	}
+}
+
+func TestAttachmentContentMismatch(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	pngHeader := testPNG(t)[:16]
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		status   int
+	}{
+		{"HTML as an image", "photo.png", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{"executable as an image", "photo.png", append([]byte("MZ\x90\x00"), make([]byte, 64)...), http.StatusUnsupportedMediaType},
+		{"truncated image", "photo.png", pngHeader, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := s.upload(t, alice, tt.filename, "image/png", tt.data); status != tt.status {
+				t.Errorf("status = %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+
+	if used, err := s.store.Attachments.UsedBytes(alice.id); err != nil || used != 0 {
+		t.Errorf("rejected uploads use %d bytes, %v, want none", used, err)
+	}
+}
+
+func TestAttachmentNotReused(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	id := s.uploadID(t, alice, "photo.png", "image/png", testPNG(t))
+
+	post := map[string]any{"title": "Photo", "content": "Look", "categories": []string{testCategory}, "attachment_ids": []int{id}}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/create-post", post); status != http.StatusCreated {
+		t.Fatalf("first post with the attachment: %d %s", status, body)
+	}
+
+	post["title"] = "Same photo"
+	if status, body := s.do(t, alice, http.MethodPost, "/api/create-post", post); status != http.StatusBadRequest {
+		t.Errorf("second post with the attachment: %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+	message := map[string]any{"content": "Same photo", "attachment_ids": []int{id}}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/messages/"+bob.id, message); status != http.StatusBadRequest {
+		t.Errorf("message with the attachment: %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ClientConfig tunes keepalive, limits and buffering of WebSocket connections.
+type ClientConfig struct {
+	// WriteWait is the time allowed to write a frame to the peer.
+	WriteWait time.Duration
+	// PongWait is the time allowed to read the next pong from the peer.
+	PongWait time.Duration
+	// PingPeriod is how often pings are sent, it must be less than PongWait.
+	PingPeriod time.Duration
+	// MaxMessageSize is the largest inbound frame accepted, in bytes.
+	MaxMessageSize int64
+	// SendBufferSize is the number of outbound events queued before the slow consumer policy applies.
+	SendBufferSize int
+}
+
+// DefaultClientConfig returns the settings used when none are given.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		WriteWait:      10 * time.Second,
+		PongWait:       60 * time.Second,
+		PingPeriod:     54 * time.Second,
+		MaxMessageSize: 8192,
+		SendBufferSize: 256,
+	}
+}
+
+// frame is an encoded event waiting to be written to a client.
+type frame struct {
+	eventType string
+	// subject is the user a presence event is about, used to coalesce updates.
+	subject string
+	data    []byte
+}
+
+// critical reports whether the frame must never be dropped for a slow client.
+func (f frame) critical() bool {
+	switch f.eventType {
+	case EventPresence, EventTypingStart, EventTypingStop, EventReaction:
+		return false
+	}
+	return true
+}
+
+type Client struct {
+	conn      *websocket.Conn
+	id        string
+	sessionID string
+	config    ClientConfig
+
+	mu          sync.Mutex
+	queue       []frame
+	notify      chan struct{}
+	closed      bool
+	closeCode   int
+	closeReason string
+}
+
+func newClient(conn *websocket.Conn, userID, sessionID string, config ClientConfig) *Client {
+	return &Client{
+		conn:      conn,
+		id:        userID,
+		sessionID: sessionID,
+		config:    config,
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// enqueue queues a frame for the write pump. Presence updates about the same user
+// are coalesced, and when the queue is full the oldest non-critical frame is
+// dropped; it returns false when no room can be made for a critical frame.
+func (c *Client) enqueue(f frame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return true
+	}
+
+	if f.eventType == EventPresence {
+		for i, queued := range c.queue {
+			if queued.eventType == EventPresence && queued.subject == f.subject {
+				c.queue[i] = f
+				return true
+			}
+		}
+	}
+
+	if len(c.queue) >= c.config.SendBufferSize {
+		dropped := false
+		for i, queued := range c.queue {
+			if !queued.critical() {
+				c.queue = append(c.queue[:i], c.queue[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			// nothing older can go, so a non-critical frame is the one dropped
+			return !f.critical()
+		}
+	}
+
+	c.queue = append(c.queue, f)
+	c.wake()
+	return true
+}
+
+// close stops the write pump; a non-zero code is sent to the peer in a close frame.
+func (c *Client) close(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	c.wake()
+}
+
+// wake signals the write pump without blocking. The caller must hold c.mu.
+func (c *Client) wake() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain takes the queued frames and reports whether the client is closing.
+func (c *Client) drain() ([]frame, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frames := c.queue
+	c.queue = nil
+	return frames, c.closed
+}
+
+// readPump reads inbound frames until the connection fails, passing each to handle.
+func (c *Client) readPump(handle func(data []byte)) {
+	c.conn.SetReadLimit(c.config.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			log.Println("Error reading message:", err)
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
+		handle(message)
+	}
+}
+
+// writePump writes queued frames and keepalive pings until the client is closed or a write fails.
+func (c *Client) writePump() {
+	ping := time.NewTicker(c.config.PingPeriod)
+	defer func() {
+		ping.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.notify:
+			frames, closing := c.drain()
+			for _, f := range frames {
+				_ = c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, f.data); err != nil {
+					log.Println("WebSocket write error for client:", c.id, err)
+					return
+				}
+			}
+			if closing {
+				c.writeClose()
+				return
+			}
+
+		case <-ping.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Println("WebSocket ping error for client:", c.id, err)
+				return
+			}
+		}
+	}
+}
+
+// writeClose sends the close frame requested by close, if any.
+func (c *Client) writeClose() {
+	c.mu.Lock()
+	code, reason := c.closeCode, c.closeReason
+	c.mu.Unlock()
+
+	if code == 0 {
+		return
+	}
+	deadline := time.Now().Add(c.config.WriteWait)
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTestClient(bufferSize int) *Client {
+	return newClient(nil, "reader", "session", ClientConfig{SendBufferSize: bufferSize})
+}
+
+func presenceFrame(userID string, status PresenceStatus) frame {
+	return frame{eventType: EventPresence, subject: userID, data: []byte(userID + ":" + string(status))}
+}
+
+func queuedData(c *Client) []string {
+	frames, _ := c.drain()
+	data := make([]string, len(frames))
+	for i, f := range frames {
+		data[i] = string(f.data)
+	}
+	return data
+}
+
+func TestEnqueueCoalescesPresence(t *testing.T) {
+	c := newTestClient(8)
+	c.enqueue(presenceFrame("alice", StatusOnline))
+	c.enqueue(frame{eventType: EventMessage, data: []byte("message")})
+	c.enqueue(presenceFrame("bob", StatusOnline))
+	c.enqueue(presenceFrame("alice", StatusAway))
+
+	want := []string{"alice:away", "message", "bob:online"}
+	if got := queuedData(c); !slices.Equal(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestEnqueueDropsOldestNonCritical(t *testing.T) {
+	c := newTestClient(3)
+	c.enqueue(frame{eventType: EventMessage, data: []byte("first")})
+	c.enqueue(frame{eventType: EventTypingStart, data: []byte("typing")})
+	c.enqueue(frame{eventType: EventReaction, data: []byte("reaction")})
+
+	if !c.enqueue(frame{eventType: EventMessage, data: []byte("second")}) {
+		t.Fatal("enqueue reported a full buffer with non-critical frames queued")
+	}
+
+	want := []string{"first", "reaction", "second"}
+	if got := queuedData(c); !slices.Equal(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestEnqueueFullOfCriticalFrames(t *testing.T) {
+	c := newTestClient(2)
+	c.enqueue(frame{eventType: EventMessage, data: []byte("first")})
+	c.enqueue(frame{eventType: EventMessage, data: []byte("second")})
+
+	if !c.enqueue(frame{eventType: EventTypingStart, data: []byte("typing")}) {
+		t.Error("enqueue of a non-critical frame reported a slow consumer")
+	}
+	if c.enqueue(frame{eventType: EventMessage, data: []byte("third")}) {
+		t.Error("enqueue of a critical frame succeeded with no room left")
+	}
+
+	want := []string{"first", "second"}
+	if got := queuedData(c); !slices.Equal(got, want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+}
+
+func TestEnqueueAfterClose(t *testing.T) {
+	c := newTestClient(1)
+	c.close(0, "")
+
+	if !c.enqueue(frame{eventType: EventMessage, data: []byte("late")}) {
+		t.Error("enqueue on a closed client reported a slow consumer")
+	}
+	if got := queuedData(c); len(got) != 0 {
+		t.Errorf("queue = %v, want it empty", got)
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/utils"
+	"time"
+)
+
+// ProtocolVersion is the version of the WebSocket event envelope spoken by this server.
+const ProtocolVersion = 1
+
+// Event types exchanged over the WebSocket connection.
+const (
+	EventMessage           = "message"
+	EventSendMessage       = "send_message"
+	EventAck               = "ack"
+	EventTypingStart       = "typing_start"
+	EventTypingStop        = "typing_stop"
+	EventMarkRead          = "mark_read"
+	EventMessagesRead      = "messages_read"
+	EventPresence          = "presence"
+	EventHeartbeat         = "heartbeat"
+	EventReaction          = "reaction"
+	EventPostUpdated       = "post_updated"
+	EventPostDeleted       = "post_deleted"
+	EventCommentUpdated    = "comment_updated"
+	EventCommentDeleted    = "comment_deleted"
+	EventMessageUpdated    = "message_updated"
+	EventMessageDeleted    = "message_deleted"
+	EventReportCreated     = "report_created"
+	EventReportUpdated     = "report_updated"
+	EventNotification      = "notification"
+	EventNotificationsRead = "notifications_read"
+	EventError             = "error"
+)
+
+// Error codes carried by error frames.
+const (
+	ErrCodeMalformedEvent     = "malformed_event"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownEvent       = "unknown_event"
+	ErrCodeInvalidPayload     = "invalid_payload"
+	ErrCodeInternal           = "internal_error"
+)
+
+// Event is the envelope wrapping every frame sent over the WebSocket.
+type Event struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	ID        string          `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// SendMessagePayload is sent by a client to post a private message.
+type SendMessagePayload struct {
+	ReceiverID    string `json:"receiver_id"`
+	Content       string `json:"content"`
+	AttachmentIDs []int  `json:"attachment_ids,omitempty"`
+}
+
+// AckPayload confirms that the event identified by Ref was processed.
+type AckPayload struct {
+	Ref     string            `json:"ref"`
+	Message *database.Message `json:"message,omitempty"`
+}
+
+// TypingTargetPayload is sent by a client with typing_start and typing_stop.
+type TypingTargetPayload struct {
+	ReceiverID string `json:"receiver_id"`
+}
+
+// TypingPayload tells the receiver which user started or stopped typing.
+type TypingPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// MarkReadPayload is sent by a client to mark messages from UserID read up to message UpTo.
+type MarkReadPayload struct {
+	UserID string `json:"user_id"`
+	UpTo   int    `json:"up_to"`
+}
+
+// ReadReceiptPayload tells both participants that messages were read.
+type ReadReceiptPayload struct {
+	ReaderID string    `json:"reader_id"`
+	SenderID string    `json:"sender_id"`
+	UpTo     int       `json:"up_to"`
+	ReadAt   time.Time `json:"read_at"`
+}
+
+// PresencePayload announces the new status of a user.
+type PresencePayload struct {
+	UserID     string         `json:"user_id"`
+	Status     PresenceStatus `json:"status"`
+	LastSeenAt *time.Time     `json:"last_seen_at,omitempty"`
+}
+
+// HeartbeatPayload is sent periodically by clients; Active reports user interaction since the last one.
+type HeartbeatPayload struct {
+	Active bool `json:"active"`
+}
+
+// ReactionPayload carries the new counts of a post or comment after UserID reacted
+// to it; Reaction is that user's resulting reaction, empty when it was removed.
+type ReactionPayload struct {
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	Likes      int    `json:"likes"`
+	Dislikes   int    `json:"dislikes"`
+	UserID     string `json:"user_id"`
+	Reaction   string `json:"reaction"`
+}
+
+// DeletedPayload identifies a deleted post, comment or message; PostID is set for
+// comments and ParentID for replies.
+type DeletedPayload struct {
+	ID       int  `json:"id"`
+	PostID   int  `json:"post_id,omitempty"`
+	ParentID *int `json:"parent_id,omitempty"`
+}
+
+// NotificationPayload pushes a new notification to its recipient along with their
+// number of unread notifications.
+type NotificationPayload struct {
+	Notification database.Notification `json:"notification"`
+	Unread       int                   `json:"unread"`
+}
+
+// NotificationsReadPayload tells a user's clients that notification ID, or every
+// notification when ID is 0, was read.
+type NotificationsReadPayload struct {
+	ID     int `json:"id,omitempty"`
+	Unread int `json:"unread"`
+}
+
+// ErrorPayload describes why an inbound event was rejected.
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`
+}
+
+// ProtocolError is returned by event handlers to reject an event with a specific code.
+type ProtocolError struct {
+	Code    string
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
+
+// EventHandler handles an inbound event sent by a client.
+type EventHandler func(c *Client, e Event) error
+
+// newEvent wraps a payload in a versioned envelope and encodes it.
+func newEvent(eventType string, payload any) ([]byte, error) {
+	id, err := utils.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Event{
+		Type:      eventType,
+		Version:   ProtocolVersion,
+		ID:        id.String(),
+		Timestamp: time.Now().UTC(),
+		Payload:   data,
+	})
+}
+
+// decodeEvent parses and validates an inbound frame.
+func decodeEvent(data []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil || e.Type == "" {
+		return e, &ProtocolError{Code: ErrCodeMalformedEvent, Message: "Event must be a JSON object with a type"}
+	}
+	if e.Version != ProtocolVersion {
+		return e, &ProtocolError{Code: ErrCodeUnsupportedVersion, Message: "Unsupported protocol version"}
+	}
+	return e, nil
+}
+
+// decodePayload unmarshals an event payload into v.
+func decodePayload(e Event, v any) error {
+	if len(e.Payload) == 0 {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: "Event payload is required"}
+	}
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: "Invalid event payload"}
+	}
+	return nil
+}
+
+// errorPayload converts a handler error into an error frame payload.
+func errorPayload(err error, ref string) ErrorPayload {
+	var evErr *ProtocolError
+	if errors.As(err, &evErr) {
+		return ErrorPayload{Code: evErr.Code, Message: evErr.Message, Ref: ref}
+	}
+	return ErrorPayload{Code: ErrCodeInternal, Message: "Internal server error", Ref: ref}
+}
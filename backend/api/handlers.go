@@ -1,22 +1,39 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/storage"
+	"real-time-forum/backend/store"
 	"real-time-forum/backend/utils"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gofrs/uuid/v5"
 )
 
 type Handler struct {
-	db    *database.Database
+	store *store.Store
 	wsHub *Hub
+	files storage.Storage
+}
+
+// validationError turns the error of a utils validator into a response listing the invalid fields.
+func validationError(err error) *response.Error {
+	var fields utils.FieldErrors
+	if errors.As(err, &fields) {
+		return response.Invalid(err.Error(), fields)
+	}
+	return response.Invalid(err.Error(), nil)
 }
 
 /* -------------------- Authentication -------------------- */
@@ -25,38 +42,88 @@ type Handler struct {
 func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	var user database.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, `{"message": "Invalid request payload"}`, http.StatusBadRequest)
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
 		return
 	}
 
+	utils.NormalizeUser(&user)
 	if err := utils.ValidateUser(user); err != nil {
-		http.Error(w, `{"message": "`+err.Error()+`"}`, http.StatusBadRequest)
+		response.WriteError(w, validationError(err))
 		return
 	}
 
 	hash, err := utils.HashPassword(user.Password)
 	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		response.WriteError(w, response.Internal())
 		return
 	}
 	user.ID, err = utils.NewUUID()
 	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		response.WriteError(w, response.Internal())
 		return
 	}
-
-	query := `INSERT INTO user (id, username, email, password, first_name, last_name, age, gender) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = h.db.DB.Exec(query, user.ID.String(), user.Username, user.Email, hash, user.FirstName, user.LastName, user.Age, user.Gender)
-	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	user.CreatedAt = time.Now()
+
+	if err := h.store.Users.Create(user, hash); err != nil {
+		switch err {
+		case store.ErrUsernameTaken:
+			response.WriteError(w, response.ConflictField("username", "Username is already taken"))
+		case store.ErrEmailTaken:
+			response.WriteError(w, response.ConflictField("email", "Email is already registered"))
+		default:
+			log.Println("Error creating user:", err)
+			response.WriteError(w, response.Internal())
+		}
 		return
 	}
 
 	user.Password = ""
 
-	w.WriteHeader(http.StatusCreated)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	response.WriteJSON(w, http.StatusCreated, user)
+}
+
+// Availability is the answer of the username and email availability checks.
+type Availability struct {
+	Available bool   `json:"available"`
+	Message   string `json:"message,omitempty"`
+}
+
+// CheckUsername tells the register form whether ?username= is valid and free
+func (h *Handler) CheckUsername(w http.ResponseWriter, r *http.Request) {
+	username := strings.TrimSpace(r.URL.Query().Get("username"))
+	h.checkAvailability(w, utils.ValidateUsername(username), "Username is already taken", func() (bool, error) {
+		return h.store.Users.UsernameExists(username)
+	})
+}
+
+// CheckEmail tells the register form whether ?email= is valid and not yet registered
+func (h *Handler) CheckEmail(w http.ResponseWriter, r *http.Request) {
+	email := utils.NormalizeEmail(r.URL.Query().Get("email"))
+	h.checkAvailability(w, utils.ValidateEmail(email), "Email is already registered", func() (bool, error) {
+		return h.store.Users.EmailExists(email)
+	})
+}
+
+// checkAvailability answers an availability check: invalid values are rejected
+// with their validation error, valid ones are looked up with exists.
+func (h *Handler) checkAvailability(w http.ResponseWriter, invalid error, takenMessage string, exists func() (bool, error)) {
+	if invalid != nil {
+		response.WriteError(w, validationError(invalid))
+		return
+	}
+
+	taken, err := exists()
+	if err != nil {
+		log.Println("Error checking availability:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	availability := Availability{Available: !taken}
+	if taken {
+		availability.Message = takenMessage
+	}
+	response.WriteJSON(w, http.StatusOK, availability)
 }
 
 // LoginUser  logs in a user
@@ -67,525 +134,2018 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		http.Error(w, `{"message": "Invalid request payload"}`, http.StatusBadRequest)
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
 		return
 	}
-	log.Println(credentials)
 
-	var user database.User
-	query := `SELECT id, username, email, password, first_name, last_name, age, gender FROM user WHERE email = ? OR username = ?`
-	row := h.db.DB.QueryRow(query, credentials.EmailOrUsername, credentials.EmailOrUsername)
-
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.Age, &user.Gender)
+	user, err := h.store.Users.GetByLogin(credentials.EmailOrUsername)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, `{"message": "User  not found"}`, http.StatusNotFound)
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("User not found"))
 			return
 		}
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		response.WriteError(w, response.Internal())
 		return
 	}
 
 	if err := utils.CheckPassword(credentials.Password, user.Password); err != nil {
-		http.Error(w, `{"message": "Invalid credentials"}`, http.StatusUnauthorized)
+		response.WriteError(w, response.Unauthorized("Invalid credentials"))
+		return
+	}
+	if user.BannedAt != nil {
+		response.WriteError(w, response.Forbidden("This account is banned"))
+		return
+	}
+	if user.Sanctioned(time.Now()) {
+		response.WriteError(w, response.Forbidden("This account is suspended until "+user.SuspendedUntil.Format("Jan 2, 2006 15:04 MST")))
 		return
 	}
 
-	token, err := utils.NewUUID()
+	token, session, err := utils.CreateSession(h.store.Sessions, user.ID.String(), r)
 	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		log.Println("Error creating session:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	utils.SetSessionCookie(w, token, session.ExpiresAt)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    token.String(),
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(time.Hour * 24),
-	})
+	user.Password = ""
 
-	query = `UPDATE user SET token = ? WHERE id = ?`
-	_, err = h.db.DB.Exec(query, token, user.ID)
-	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	response.WriteJSON(w, http.StatusOK, user)
+}
+
+// LogoutUser  logs out the current session of a user
+func (h *Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
 
-	user.Password = ""
+	if err := utils.RevokeSession(h.store.Sessions, principal.UserID, principal.SessionID); err != nil {
+		log.Println("Error revoking session:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.wsHub.CloseSession(principal.SessionID)
 
-	h.wsHub.login(user.ID.String())
+	utils.SetSessionCookie(w, "", time.Time{})
 
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "Logout successful"})
 }
 
-// LogoutUser  logs out a user
-func (h *Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
+// GetSessions lists the live sessions of the current user
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := utils.ListSessions(h.store.Sessions, principal.UserID)
 	if err != nil {
-		http.Error(w, `{"message": "Unauthorized"}`, http.StatusUnauthorized)
+		log.Println("Error listing sessions:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == principal.SessionID
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now(),
-	})
+	response.WriteJSON(w, http.StatusOK, sessions)
+}
 
-	query := `UPDATE user SET token = NULL WHERE id = ?`
-	_, err = h.db.DB.Exec(query, token)
-	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+// RevokeSession revokes one of the current user's sessions and closes its sockets
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.WriteError(w, response.MethodNotAllowed())
 		return
 	}
 
-	userID, err := utils.GetUserID(h.db, token)
-	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
-	h.wsHub.logout(userID)
 
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
+	sessionID := r.PathValue("id")
+	if err := utils.RevokeSession(h.store.Sessions, principal.UserID, sessionID); err != nil {
+		if err == utils.ErrSessionNotFound {
+			response.WriteError(w, response.NotFound("Session not found"))
+			return
+		}
+		log.Println("Error revoking session:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.wsHub.CloseSession(sessionID)
+
+	if sessionID == principal.SessionID {
+		utils.SetSessionCookie(w, "", time.Time{})
+	}
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
 }
 
 /* -------------------- Websocket -------------------- */
 
 // GetUsers returns a list of users along with their online status
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
-	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, "Error getting session token", http.StatusInternalServerError)
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
+	userID := principal.UserID
 
-	userID, err := utils.GetUserID(h.db, token)
+	users, err := h.store.Users.ListContacts(userID)
 	if err != nil {
-		log.Println("Error getting user ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		log.Println("Error listing users:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	for i := range users {
+		users[i].Status = string(h.wsHub.Presence().Status(users[i].ID.String()))
+		users[i].Online = users[i].Status != string(StatusOffline)
+	}
 
-	query := `
-        SELECT
-            u.id,
-            u.username
-        FROM
-            user u
-        LEFT JOIN (
-            SELECT
-                CASE
-                    WHEN sender_id = ? THEN receiver_id
-                    ELSE sender_id
-                END AS user_id,
-                MAX(created_at) AS latest_message_time
-            FROM message
-            WHERE sender_id = ? OR receiver_id = ?
-            GROUP BY
-                CASE
-                    WHEN sender_id = ? THEN receiver_id
-                    ELSE sender_id
-                END
-        ) m ON u.id = m.user_id
-        WHERE
-            u.id <> ?
-        ORDER BY
-            m.latest_message_time DESC,
-            u.username ASC;
-    `
+	response.WriteJSON(w, http.StatusOK, users)
+}
 
-	rows, err := h.db.DB.Query(query, userID, userID, userID, userID, userID)
-	if err != nil {
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+// GetMessages returns a page of the messages between two users
+func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
+	userID := principal.UserID
 
-	var users []database.User
-	connectedUsers := make(map[string]bool)
-
-	for client := range h.wsHub.clients {
-		connectedUsers[client.id] = true
+	otherUserID := r.URL.Path[len("/api/messages/"):]
+	if otherUserID == "" {
+		response.WriteError(w, response.InvalidField("id", "Other user ID is required"))
+		return
 	}
 
-	for rows.Next() {
-		var user database.User
-		if err := rows.Scan(&user.ID, &user.Username); err != nil {
-			http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-			return
-		}
-		user.Online = connectedUsers[user.ID.String()]
-		if !user.Online {
-			user.Online = false
-		}
-		users = append(users, user)
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
 	}
 
-	if users == nil {
-		users = []database.User{}
+	messages, more, err := h.store.Messages.ListConversation(userID, otherUserID, page)
+	if err != nil {
+		log.Println("Error querying messages:", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(users)
+	result := newCursorPage(messages, more, page, func(m database.Message) store.Cursor {
+		return store.Cursor{CreatedAt: m.CreatedAt, ID: m.ID}
+	})
+	response.WriteJSON(w, http.StatusOK, result)
 }
 
-// GetMessages returns all messages between two users
-func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
-	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, "Error getting session token", http.StatusInternalServerError)
+// SendMessage sends a message to another user
+func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
+	userID := principal.UserID
 
-	userID, err := utils.GetUserID(h.db, token)
-	if err != nil {
-		log.Println("Error getting user ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	otherUserID := r.URL.Path[len("/api/messages/"):]
+	if otherUserID == "" {
+		response.WriteError(w, response.InvalidField("id", "Other user ID is required"))
 		return
 	}
 
-	otherUserID := r.URL.Path[len("/api/messages/"):]
-	if otherUserID == "" {
-		http.Error(w, `{"message": "Other user ID is required"}`, http.StatusBadRequest)
+	var body struct {
+		database.Message
+		AttachmentIDs []int `json:"attachment_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println("Error decoding message:", err)
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
 		return
 	}
+	message := body.Message
 
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	limit, err := strconv.Atoi(limitStr)
+	senderID, err := uuid.FromString(userID)
 	if err != nil {
-		limit = 10
+		log.Println("Error parsing sender ID:", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
-	offset, err := strconv.Atoi(offsetStr)
+	message.SenderID = senderID
+	message.ReceiverID, err = uuid.FromString(otherUserID)
 	if err != nil {
-		offset = 0
+		log.Println("Error parsing receiver ID:", err)
+		response.WriteError(w, response.InvalidField("id", "Invalid receiver ID"))
+		return
 	}
-
-	query := `
-		SELECT sender_id, receiver_id, content, created_at 
-		FROM message 
-		WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?) 
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`
-	rows, err := h.db.DB.Query(query, userID, otherUserID, otherUserID, userID, limit, offset)
+	if err := h.checkReceiver(otherUserID); err != nil {
+		log.Println("Error checking receiver:", err)
+		response.WriteError(w, err)
+		return
+	}
+	message.Attachments, err = h.checkAttachments(userID, body.AttachmentIDs, database.TargetMessage)
 	if err != nil {
-		log.Println("Error querying messages:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+		log.Println("Error checking attachments:", err)
+		response.WriteError(w, err)
 		return
 	}
-	defer rows.Close()
 
-	var messages []database.Message
-	for rows.Next() {
-		var message database.Message
-		if err := rows.Scan(&message.SenderID, &message.ReceiverID, &message.Content, &message.CreatedAt); err != nil {
-			log.Println("Error scanning message:", err)
-			http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-			return
-		}
-		messages = append(messages, message)
-	}
-	if messages == nil {
-		messages = []database.Message{}
+	if err := utils.ValidateMessage(message); err != nil {
+		response.WriteError(w, validationError(err))
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(messages); err != nil {
-		log.Println("Error encoding messages:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	if err := h.storeMessage(&message); err != nil {
+		log.Println("Error inserting message:", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
+
+	response.WriteJSON(w, http.StatusOK, message)
 }
 
-// SendMessage sends a message to another user
-func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
-	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, "Error getting session token", http.StatusInternalServerError)
+// UpdateMessage lets the sender of a message change its content
+func (h *Handler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	message, ok := h.sentMessage(w, r, principal)
+	if !ok {
 		return
 	}
 
-	userID, err := utils.GetUserID(h.db, token)
-	if err != nil {
-		log.Println("Error getting user ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	message.Content = body.Content
+	if err := utils.ValidateMessage(message); err != nil {
+		response.WriteError(w, validationError(err))
 		return
 	}
 
-	otherUserID := r.URL.Path[len("/api/messages/"):]
-	if otherUserID == "" {
-		http.Error(w, `{"message": "Other user ID is required"}`, http.StatusBadRequest)
+	now := time.Now()
+	message.EditedAt = &now
+	if err := h.store.Messages.Update(&message); err != nil {
+		log.Println("Error updating message:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	previous := message.Mentions
+	message.Mentions = h.resolveMentions(message.Content, message.SenderID, message.ReceiverID)
+	h.saveMentions(database.TargetMessage, message.ID, message.Mentions, previous, database.Notification{ActorID: message.SenderID, MessageID: &message.ID})
+	h.wsHub.SendEvent(message.SenderID.String(), EventMessageUpdated, message)
+	h.wsHub.SendEvent(message.ReceiverID.String(), EventMessageUpdated, message)
 
-	var message database.Message
-	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
-		log.Println("Error decoding message:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	response.WriteJSON(w, http.StatusOK, message)
+}
+
+// DeleteMessage lets the sender of a message delete it
+func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	message, ok := h.sentMessage(w, r, principal)
+	if !ok {
 		return
 	}
 
-	if message.Content == "" || strings.TrimSpace(message.Content) == "" {
-		http.Error(w, `{"message": "Message cannot be empty"}`, http.StatusBadRequest)
+	if err := h.store.Messages.Delete(message.ID, time.Now()); err != nil {
+		log.Println("Error deleting message:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	h.wsHub.SendEvent(message.SenderID.String(), EventMessageDeleted, DeletedPayload{ID: message.ID})
+	h.wsHub.SendEvent(message.ReceiverID.String(), EventMessageDeleted, DeletedPayload{ID: message.ID})
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "Message deleted"})
+}
+
+// sentMessage loads the message {messageID} of the conversation with user {id},
+// answering 404 when there is none and 403 when the principal did not send it.
+func (h *Handler) sentMessage(w http.ResponseWriter, r *http.Request, principal *Principal) (database.Message, bool) {
+	id, err := strconv.Atoi(r.PathValue("messageID"))
+	if err != nil || id <= 0 {
+		response.WriteError(w, response.InvalidField("messageID", "Invalid message ID"))
+		return database.Message{}, false
+	}
+
+	message, err := h.store.Messages.Get(id)
+	if err != nil && err != store.ErrNotFound {
+		log.Println("Error loading message:", err)
+		response.WriteError(w, response.Internal())
+		return message, false
+	}
+	otherUserID := r.PathValue("id")
+	sender, receiver := message.SenderID.String(), message.ReceiverID.String()
+	if err == store.ErrNotFound || !(sender == principal.UserID && receiver == otherUserID || sender == otherUserID && receiver == principal.UserID) {
+		response.WriteError(w, response.NotFound("Message not found"))
+		return message, false
+	}
+	if sender != principal.UserID {
+		response.WriteError(w, response.Forbidden("Only the sender can change this message"))
+		return message, false
+	}
+	return message, true
+}
+
+// HandleSendMessageEvent persists a message sent over the WebSocket and acknowledges it
+// to the sending connection using the event ID as correlation ID.
+func (h *Handler) HandleSendMessageEvent(c *Client, e Event) error {
+	var payload SendMessagePayload
+	if err := decodePayload(e, &payload); err != nil {
+		return err
+	}
 
-	message.SenderID, err = uuid.FromString(userID)
+	var message database.Message
+	var err error
+	message.SenderID, err = uuid.FromString(c.id)
 	if err != nil {
-		log.Println("Error parsing sender ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-		return
+		return err
 	}
-	message.ReceiverID, err = uuid.FromString(otherUserID)
+	message.ReceiverID, err = uuid.FromString(payload.ReceiverID)
 	if err != nil {
-		log.Println("Error parsing receiver ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-		return
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: "Invalid receiver ID"}
 	}
-
-	query := `INSERT INTO message (sender_id, receiver_id, content, created_at) VALUES (?, ?, ?, ?)`
-	_, err = h.db.DB.Exec(query, message.SenderID, message.ReceiverID, message.Content, time.Now())
+	if err := h.checkReceiver(payload.ReceiverID); err != nil {
+		return protocolError(err)
+	}
+	message.Content = payload.Content
+	message.Attachments, err = h.checkAttachments(c.id, payload.AttachmentIDs, database.TargetMessage)
 	if err != nil {
+		return protocolError(err)
+	}
+
+	if err := utils.ValidateMessage(message); err != nil {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: err.Error()}
+	}
+
+	if err := h.storeMessage(&message); err != nil {
 		log.Println("Error inserting message:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-		return
+		return err
 	}
 
-	// broadcast message to other user
-	h.wsHub.broadcast <- []byte(`{"type": "message", "content": "` + message.SenderID.String() + `,` + message.ReceiverID.String() + `"}`)
+	h.wsHub.reply(c, EventAck, AckPayload{Ref: e.ID, Message: &message})
+	return nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+// checkReceiver makes sure a message or typing event is addressed to an existing user.
+func (h *Handler) checkReceiver(receiverID string) error {
+	if _, err := h.store.Users.Get(receiverID); err != nil {
+		if err == store.ErrNotFound {
+			return response.NotFound("User not found")
+		}
+		return err
+	}
+	return nil
 }
 
-/* -------------------- Posts&Comments -------------------- */
+// protocolError turns a rejected request into an invalid_payload error for the
+// websocket client. Other errors are returned as they are.
+func protocolError(err error) error {
+	var rejected *response.Error
+	if errors.As(err, &rejected) {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: rejected.Message}
+	}
+	return err
+}
 
-// GetPosts gets all posts
-func (h *Handler) GetPosts(w http.ResponseWriter, r *http.Request) {
-	var posts []database.Post
+// storeMessage inserts a message, fills in its ID and creation time, links its
+// checked attachments and pushes it to both participants of the conversation.
+func (h *Handler) storeMessage(message *database.Message) error {
+	message.CreatedAt = time.Now()
 
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	if err := h.store.Messages.Create(message); err != nil {
+		return err
+	}
+	if err := h.attach(message.Attachments, database.TargetMessage, message.ID); err != nil {
+		return err
+	}
+	message.Mentions = h.resolveMentions(message.Content, message.SenderID, message.ReceiverID)
+	h.saveMentions(database.TargetMessage, message.ID, message.Mentions, nil, database.Notification{ActorID: message.SenderID, MessageID: &message.ID})
+
+	// notify only the two participants of the conversation
+	h.wsHub.stopTyping(message.SenderID.String(), message.ReceiverID.String())
+	h.wsHub.SendEvent(message.SenderID.String(), EventMessage, message)
+	h.wsHub.SendEvent(message.ReceiverID.String(), EventMessage, message)
+	h.notifyMessage(*message)
+	return nil
+}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+// MarkMessagesRead marks the messages received from another user as read up to a message ID
+func (h *Handler) MarkMessagesRead(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	userID := principal.UserID
+
+	otherUserID := r.PathValue("id")
+	if otherUserID == "" {
+		response.WriteError(w, response.InvalidField("id", "Other user ID is required"))
+		return
 	}
 
-	query := `
-        SELECT id, user_id, title, content, category, created_at
-        FROM post
-        ORDER BY created_at DESC
-        LIMIT ? OFFSET ?
-    `
-	rows, err := h.db.DB.Query(query, limit, offset)
+	var body struct {
+		UpTo int `json:"up_to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.UpTo <= 0 {
+		response.WriteError(w, response.InvalidField("up_to", "A positive up_to message ID is required"))
+		return
+	}
+
+	receipt, err := h.markRead(userID, otherUserID, body.UpTo)
 	if err != nil {
-		log.Printf("Failed to fetch posts: %v", err)
-		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		log.Println("Error marking messages read:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var post database.Post
-		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.Category, &post.CreatedAt); err != nil {
-			log.Printf("Failed to scan post: %v", err)
-			http.Error(w, "Failed to scan post", http.StatusInternalServerError)
-			return
-		}
-		posts = append(posts, post)
+	response.WriteJSON(w, http.StatusOK, receipt)
+}
+
+// HandleMarkReadEvent marks a conversation read up to a message ID over the WebSocket.
+func (h *Handler) HandleMarkReadEvent(c *Client, e Event) error {
+	var payload MarkReadPayload
+	if err := decodePayload(e, &payload); err != nil {
+		return err
+	}
+	if payload.UserID == "" || payload.UpTo <= 0 {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: "user_id and a positive up_to are required"}
+	}
+
+	if _, err := h.markRead(c.id, payload.UserID, payload.UpTo); err != nil {
+		log.Println("Error marking messages read:", err)
+		return err
+	}
+	return nil
+}
+
+// HandleTypingEvent relays typing_start and typing_stop to the conversation partner.
+func (h *Handler) HandleTypingEvent(c *Client, e Event) error {
+	var payload TypingTargetPayload
+	if err := decodePayload(e, &payload); err != nil {
+		return err
+	}
+	if payload.ReceiverID == "" || payload.ReceiverID == c.id {
+		return &ProtocolError{Code: ErrCodeInvalidPayload, Message: "Invalid receiver ID"}
+	}
+	// Typing indicators follow the same rules as the messages they announce.
+	if err := h.checkReceiver(payload.ReceiverID); err != nil {
+		return protocolError(err)
+	}
+
+	if e.Type == EventTypingStart {
+		h.wsHub.startTyping(c.id, payload.ReceiverID)
+	} else {
+		h.wsHub.stopTyping(c.id, payload.ReceiverID)
+	}
+	return nil
+}
+
+// markRead stamps unread messages sent by senderID to readerID up to a message ID
+// and sends the read receipt to both participants.
+func (h *Handler) markRead(readerID, senderID string, upTo int) (ReadReceiptPayload, error) {
+	receipt := ReadReceiptPayload{ReaderID: readerID, SenderID: senderID, UpTo: upTo, ReadAt: time.Now()}
+
+	updated, err := h.store.Messages.MarkRead(readerID, senderID, upTo, receipt.ReadAt)
+	if err != nil {
+		return receipt, err
+	}
+
+	if updated > 0 {
+		h.wsHub.SendEvent(senderID, EventMessagesRead, receipt)
+		h.wsHub.SendEvent(readerID, EventMessagesRead, receipt)
+	}
+	return receipt, nil
+}
+
+/* -------------------- Posts&Comments -------------------- */
+
+// GetPosts gets a page of posts
+func (h *Handler) GetPosts(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Failed to iterate over posts: %v", err)
-		http.Error(w, "Failed to iterate over posts", http.StatusInternalServerError)
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if len(posts) == 0 {
-		posts = []database.Post{}
+	posts, more, err := h.store.Posts.List(principal.UserID, categoriesParam(r), page)
+	if err != nil {
+		log.Printf("Failed to fetch posts: %v", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
-		log.Println("Error encoding posts:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+
+	result := newCursorPage(posts, more, page, func(p database.Post) store.Cursor {
+		return store.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+	})
+	response.WriteJSON(w, http.StatusOK, result)
+}
+
+// categoriesParam reads the category filter of a request; ?category=a&category=b
+// and ?category=a,b both select posts in either category.
+func categoriesParam(r *http.Request) []string {
+	var categories []string
+	for _, value := range r.URL.Query()["category"] {
+		categories = append(categories, strings.Split(value, ",")...)
 	}
+	return utils.NormalizeCategories(categories)
 }
 
 // CreatePost creates a new post
 func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
-	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-		return
-	}
-    userID, err := utils.GetUserID(h.db, token)
-    if err != nil {
-        log.Println("Error getting user ID:", err)
-        http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-        return
-    }
-
-    var post database.Post
-    if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-        log.Println("Error decoding post:", err)
-        http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-        return
-    }
-
-    err = utils.ValidatePost(post)
-    if err != nil {
-        log.Println("Error validating post:", err)
-        http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-        return
-    }
-
-    post.UserID, err = uuid.FromString(userID)
-    if err != nil {
-        log.Println("Error parsing user ID:", err)
-        http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-        return
-    }
-
-	log.Println("Post:", post)
-
-    query := `INSERT INTO post (user_id, title, content, category, created_at) VALUES (?, ?, ?, ?, ?)`
-    _, err = h.db.DB.Exec(query, post.UserID, post.Title, post.Content, post.Category, time.Now())
-    if err != nil {
-        log.Println("Error inserting post:", err)
-        http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
-        return
-    }
-
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusOK)
-}
-
-// GetComments gets all comments for a post
-func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
-	postIDStr := r.URL.Query().Get("post_id")
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	postID, err := strconv.Atoi(postIDStr)
-	if err != nil || postID <= 0 {
-		http.Error(w, `{"message": "Invalid post ID"}`, http.StatusBadRequest)
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
+	userID := principal.UserID
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10 // Default limit
+	var body struct {
+		database.Post
+		AttachmentIDs []int `json:"attachment_ids"`
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0 // Default offset
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println("Error decoding post:", err)
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
 	}
+	post := body.Post
 
-	var comments []database.Comment
-
-	query := `
-        SELECT id, content, user_id, post_id, created_at
-        FROM comment
-        WHERE post_id = ?
-        ORDER BY created_at DESC
-        LIMIT ? OFFSET ?
-    `
-	rows, err := h.db.DB.Query(query, postID, limit, offset)
+	post.Categories = utils.NormalizeCategories(post.Categories)
+	err := utils.ValidatePost(post)
 	if err != nil {
-		log.Printf("Failed to fetch comments: %v", err)
-		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		log.Println("Error validating post:", err)
+		response.WriteError(w, validationError(err))
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var comment database.Comment
-		if err := rows.Scan(&comment.ID, &comment.Content, &comment.UserID, &comment.PostID, &comment.CreatedAt); err != nil {
-			log.Printf("Failed to scan comment: %v", err)
-			http.Error(w, "Failed to scan comment", http.StatusInternalServerError)
-			return
-		}
-		comments = append(comments, comment)
+	if err := h.checkCategories(post.Categories); err != nil {
+		log.Println("Error checking categories:", err)
+		response.WriteError(w, err)
+		return
+	}
+
+	post.Attachments, err = h.checkAttachments(userID, body.AttachmentIDs, database.TargetPost)
+	if err != nil {
+		log.Println("Error checking attachments:", err)
+		response.WriteError(w, err)
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("Failed to iterate over comments: %v", err)
-		http.Error(w, "Failed to iterate over comments", http.StatusInternalServerError)
+	post.UserID, err = uuid.FromString(userID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if len(comments) == 0 {
-		comments = []database.Comment{}
+	post.CreatedAt = time.Now()
+	post.Mentions = h.resolveMentions(post.Content)
+	post.ContentHTML = utils.RenderMarkdown(post.Content, post.Mentions)
+	if err := h.store.Posts.Create(&post); err != nil {
+		log.Println("Error inserting post:", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
-	if err := json.NewEncoder(w).Encode(comments); err != nil {
-		log.Println("Error encoding comments:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	if err := h.attach(post.Attachments, database.TargetPost, post.ID); err != nil {
+		log.Println("Error attaching files to post:", err)
+		response.WriteError(w, response.Internal())
+		return
 	}
+	h.saveMentions(database.TargetPost, post.ID, post.Mentions, nil, database.Notification{ActorID: post.UserID, PostID: &post.ID})
+
+	response.WriteJSON(w, http.StatusCreated, post)
 }
 
-// CreateComment creates a new comment
-func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
-	token, err := utils.GetCookie(r, "session_token")
+// checkCategories rejects slugs that are not those of a category
+func (h *Handler) checkCategories(slugs []string) error {
+	categories, err := h.store.Categories.List()
 	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, "Error getting session token", http.StatusInternalServerError)
+		return err
+	}
+	known := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		known[category.Slug] = true
+	}
+	for _, slug := range slugs {
+		if !known[slug] {
+			return response.InvalidField("categories", "Unknown category")
+		}
+	}
+	return nil
+}
+
+// UpdatePost lets the author of a post change its title, content and categories
+func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
-	userID, err := utils.GetUserID(h.db, token)
-	if err != nil {
-		log.Println("Error getting user ID:", err)
-		http.Error(w, "Error getting user ID", http.StatusInternalServerError)
+	post, ok := h.authoredPost(w, r, principal, noOverride)
+	if !ok {
 		return
 	}
 
-	var comment database.Comment
-	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
-		log.Println("Error decoding comment:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	var body struct {
+		Title      string   `json:"title"`
+		Content    string   `json:"content"`
+		Categories []string `json:"categories"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
 		return
 	}
-	
-	if comment.Content == "" || strings.TrimSpace(comment.Content) == "" {
-		http.Error(w, `{"message": "Comment content is required"}`, http.StatusBadRequest)
+	post.Title, post.Content = body.Title, body.Content
+	post.Categories = utils.NormalizeCategories(body.Categories)
+	if err := utils.ValidatePost(post); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+	if err := h.checkCategories(post.Categories); err != nil {
+		log.Println("Error checking categories:", err)
+		response.WriteError(w, err)
 		return
 	}
 
-	comment.UserID, err = uuid.FromString(userID)
-	if err != nil {
-		log.Println("Error parsing user ID:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	now := time.Now()
+	post.EditedAt = &now
+	previous := post.Mentions
+	post.Mentions = h.resolveMentions(post.Content)
+	post.ContentHTML = utils.RenderMarkdown(post.Content, post.Mentions)
+	if err := h.store.Posts.Update(&post, principal.UserID); err != nil {
+		log.Println("Error updating post:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	h.saveMentions(database.TargetPost, post.ID, post.Mentions, previous, database.Notification{ActorID: post.UserID, PostID: &post.ID})
 
-	query := `INSERT INTO comment (post_id, user_id, content, created_at) VALUES (?, ?, ?, ?)`
-	_, err = h.db.DB.Exec(query, comment.PostID, comment.UserID, comment.Content, time.Now())
-	if err != nil {
-		log.Println("Error inserting comment:", err)
-		http.Error(w, `{"message": "Internal server error"}`, http.StatusInternalServerError)
+	// the viewer's own reaction is not shared with everyone else
+	broadcast := post
+	broadcast.MyReaction = ""
+	h.wsHub.BroadcastEvent(EventPostUpdated, broadcast)
+
+	response.WriteJSON(w, http.StatusOK, post)
+}
+
+// DeletePost lets the author of a post, or a moderator, delete it along with its comments
+func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	post, ok := h.authoredPost(w, r, principal, PermDeleteAnyContent)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Posts.Delete(post.ID, time.Now()); err != nil {
+		log.Println("Error deleting post:", err)
+		response.WriteError(w, response.Internal())
 		return
 	}
+	if post.UserID.String() != principal.UserID {
+		h.audit(principal, database.ModerationAction{Action: database.ActionDeletePost, TargetType: database.TargetPost, TargetID: strconv.Itoa(post.ID)})
+	}
+	h.wsHub.BroadcastEvent(EventPostDeleted, DeletedPayload{ID: post.ID})
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "Post deleted"})
+}
+
+// authoredPost loads the post named by the {id} path value, answering 404 when
+// there is none and 403 when the principal did not write it and none of their
+// roles grants override.
+func (h *Handler) authoredPost(w http.ResponseWriter, r *http.Request, principal *Principal, override Permission) (database.Post, bool) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return database.Post{}, false
+	}
+	post, err := h.store.Posts.Get(principal.UserID, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Post not found"))
+			return post, false
+		}
+		log.Println("Error loading post:", err)
+		response.WriteError(w, response.Internal())
+		return post, false
+	}
+	if post.UserID.String() != principal.UserID && !principal.Can(override) {
+		response.WriteError(w, response.Forbidden("Only the author can change this post"))
+		return post, false
+	}
+	return post, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+// GetPostRevisions lists the previous versions of a post, for moderators
+func (h *Handler) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	h.getRevisions(w, r, database.TargetPost)
+}
+
+// GetCategories lists the categories along with their post counts
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.store.Categories.List()
+	if err != nil {
+		log.Println("Error listing categories:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, categories)
+}
+
+// GetComments gets a page of the top-level comments of a post; their replies are
+// loaded with GetReplies
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	postIDStr := r.URL.Query().Get("post_id")
+
+	postID, err := strconv.Atoi(postIDStr)
+	if err != nil || postID <= 0 {
+		response.WriteError(w, response.InvalidField("post_id", "Invalid post ID"))
+		return
+	}
+
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	comments, more, err := h.store.Comments.ListByPost(principal.UserID, postID, page)
+	if err != nil {
+		log.Printf("Failed to fetch comments: %v", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	result := newCursorPage(comments, more, page, func(c database.Comment) store.Cursor {
+		return store.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	response.WriteJSON(w, http.StatusOK, result)
+}
+
+// CreateComment creates a new comment, or a reply when parent_id is set
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		PostID   int    `json:"post_id"`
+		ParentID *int   `json:"parent_id"`
+		Content  string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println("Error decoding comment:", err)
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	comment := database.Comment{Content: body.Content, PostID: body.PostID, ParentID: body.ParentID}
+
+	var parent *database.Comment
+	if comment.ParentID != nil {
+		replied, ok := h.repliedComment(w, principal, *comment.ParentID)
+		if !ok {
+			return
+		}
+		parent = &replied
+	}
+	h.createComment(w, principal, comment, parent)
+}
+
+// GetReplies gets a page of the direct replies to a comment
+func (h *Handler) GetReplies(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	replies, more, err := h.store.Comments.ListReplies(principal.UserID, id, page)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Comment not found"))
+			return
+		}
+		log.Printf("Failed to fetch replies: %v", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	result := newCursorPage(replies, more, page, func(c database.Comment) store.Cursor {
+		return store.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	response.WriteJSON(w, http.StatusOK, result)
+}
+
+// CreateReply replies to the comment named by the {id} path value
+func (h *Handler) CreateReply(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	parent, ok := h.repliedComment(w, principal, id)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	reply := database.Comment{Content: body.Content, PostID: parent.PostID, ParentID: &parent.ID}
+	h.createComment(w, principal, reply, &parent)
+}
+
+// repliedComment loads the comment a reply answers, answering 404 when there is
+// none or it was deleted.
+func (h *Handler) repliedComment(w http.ResponseWriter, principal *Principal, id int) (database.Comment, bool) {
+	parent, err := h.store.Comments.Get(principal.UserID, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Comment not found"))
+			return parent, false
+		}
+		log.Println("Error loading comment:", err)
+		response.WriteError(w, response.Internal())
+		return parent, false
+	}
+	return parent, true
+}
+
+// createComment validates and stores a comment by the principal, nested under
+// parent when it is a reply, unless the thread of its post is locked to them.
+func (h *Handler) createComment(w http.ResponseWriter, principal *Principal, comment database.Comment, parent *database.Comment) {
+	userID := principal.UserID
+
+	if err := utils.ValidateComment(comment); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+	if parent != nil {
+		if err := utils.ValidateReply(comment, *parent); err != nil {
+			response.WriteError(w, validationError(err))
+			return
+		}
+		comment.Depth = parent.Depth + 1
+	}
+	post, err := h.store.Posts.Get(userID, comment.PostID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Post not found"))
+			return
+		}
+		log.Println("Error loading post:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if post.LockedAt != nil && !principal.Can(PermLockThread) {
+		response.WriteError(w, response.Forbidden("This thread is locked"))
+		return
+	}
+
+	commenterID, err := uuid.FromString(userID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	comment.UserID = commenterID
+
+	comment.CreatedAt = time.Now()
+	comment.Mentions = h.resolveMentions(comment.Content)
+	comment.ContentHTML = utils.RenderMarkdown(comment.Content, comment.Mentions)
+	if err := h.store.Comments.Create(&comment); err != nil {
+		log.Println("Error inserting comment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.notifyComment(comment, post, parent)
+	h.saveMentions(database.TargetComment, comment.ID, comment.Mentions, nil, database.Notification{ActorID: comment.UserID, PostID: &comment.PostID, CommentID: &comment.ID})
+
+	response.WriteJSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment lets the author of a comment change its content
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	comment, ok := h.authoredComment(w, r, principal, noOverride)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	comment.Content = body.Content
+	if err := utils.ValidateComment(comment); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+
+	now := time.Now()
+	comment.EditedAt = &now
+	previous := comment.Mentions
+	comment.Mentions = h.resolveMentions(comment.Content)
+	comment.ContentHTML = utils.RenderMarkdown(comment.Content, comment.Mentions)
+	if err := h.store.Comments.Update(&comment, principal.UserID); err != nil {
+		log.Println("Error updating comment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.saveMentions(database.TargetComment, comment.ID, comment.Mentions, previous, database.Notification{ActorID: comment.UserID, PostID: &comment.PostID, CommentID: &comment.ID})
+
+	broadcast := comment
+	broadcast.MyReaction = ""
+	h.wsHub.BroadcastEvent(EventCommentUpdated, broadcast)
+
+	response.WriteJSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment lets the author of a comment, or a moderator, delete it
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	comment, ok := h.authoredComment(w, r, principal, PermDeleteAnyContent)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Comments.Delete(comment.ID, time.Now()); err != nil {
+		log.Println("Error deleting comment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if comment.UserID.String() != principal.UserID {
+		h.audit(principal, database.ModerationAction{Action: database.ActionDeleteComment, TargetType: database.TargetComment, TargetID: strconv.Itoa(comment.ID)})
+	}
+	h.wsHub.BroadcastEvent(EventCommentDeleted, DeletedPayload{ID: comment.ID, PostID: comment.PostID, ParentID: comment.ParentID})
+
+	response.WriteJSON(w, http.StatusOK, map[string]string{"message": "Comment deleted"})
+}
+
+// authoredComment loads the comment named by the {id} path value, answering 404
+// when there is none and 403 when the principal did not write it and none of
+// their roles grants override.
+func (h *Handler) authoredComment(w http.ResponseWriter, r *http.Request, principal *Principal, override Permission) (database.Comment, bool) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return database.Comment{}, false
+	}
+	comment, err := h.store.Comments.Get(principal.UserID, id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Comment not found"))
+			return comment, false
+		}
+		log.Println("Error loading comment:", err)
+		response.WriteError(w, response.Internal())
+		return comment, false
+	}
+	if comment.UserID.String() != principal.UserID && !principal.Can(override) {
+		response.WriteError(w, response.Forbidden("Only the author can change this comment"))
+		return comment, false
+	}
+	return comment, true
+}
+
+// GetCommentRevisions lists the previous versions of a comment, for moderators
+func (h *Handler) GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	h.getRevisions(w, r, database.TargetComment)
+}
+
+// getRevisions lists the revisions of the post or comment named by the {id} path value
+func (h *Handler) getRevisions(w http.ResponseWriter, r *http.Request, targetType string) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.store.Revisions.List(targetType, id)
+	if err != nil {
+		log.Println("Error listing revisions:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, revisions)
+}
+
+// pathID reads the positive integer {id} path value, answering 400 when it is not one.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.WriteError(w, response.InvalidField("id", "Invalid ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+/* -------------------- Reactions -------------------- */
+
+// React toggles the current user's like or dislike on a post or comment and
+// broadcasts the new counts
+func (h *Handler) React(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.WriteError(w, response.MethodNotAllowed())
+		return
+	}
+
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		TargetType string `json:"target_type"`
+		TargetID   int    `json:"target_id"`
+		Reaction   string `json:"reaction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	if body.TargetType != database.TargetPost && body.TargetType != database.TargetComment {
+		response.WriteError(w, response.InvalidField("target_type", "target_type must be post or comment"))
+		return
+	}
+	if body.Reaction != database.ReactionLike && body.Reaction != database.ReactionDislike {
+		response.WriteError(w, response.InvalidField("reaction", "reaction must be like or dislike"))
+		return
+	}
+
+	reaction, err := h.store.Reactions.Toggle(principal.UserID, body.TargetType, body.TargetID, body.Reaction)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Target not found"))
+			return
+		}
+		log.Println("Error saving reaction:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	counts, err := h.store.Reactions.Counts(body.TargetType, body.TargetID)
+	if err != nil {
+		log.Println("Error counting reactions:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	payload := ReactionPayload{
+		TargetType: body.TargetType,
+		TargetID:   body.TargetID,
+		Likes:      counts.Likes,
+		Dislikes:   counts.Dislikes,
+		UserID:     principal.UserID,
+		Reaction:   reaction,
+	}
+	h.wsHub.BroadcastEvent(EventReaction, payload)
+	if reaction != "" {
+		h.notifyReaction(principal.UserID, body.TargetType, body.TargetID)
+	}
+
+	response.WriteJSON(w, http.StatusOK, payload)
+}
+
+/* -------------------- Search -------------------- */
+
+// maxSearchTerms bounds the number of words a search query is split into.
+const maxSearchTerms = 10
+
+// SearchResults holds the matches of a search, grouped by kind.
+type SearchResults struct {
+	Posts    []database.PostResult    `json:"posts"`
+	Comments []database.CommentResult `json:"comments"`
+	Users    []database.User          `json:"users"`
+}
+
+// Search finds posts, comments and users matching ?q=, optionally narrowed with
+// ?type=posts|comments|users, ?category=, ?author= and the days ?from= and ?to=,
+// both YYYY-MM-DD and inclusive
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	terms := strings.FieldsFunc(params.Get("q"), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	})
+	if len(terms) == 0 {
+		response.WriteError(w, response.InvalidField("q", "Search query is required"))
+		return
+	}
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+
+	kind := params.Get("type")
+	if kind != "" && kind != "posts" && kind != "comments" && kind != "users" {
+		response.WriteError(w, response.InvalidField("type", "type must be posts, comments or users"))
+		return
+	}
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	offset, err := strconv.Atoi(params.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	query := store.SearchQuery{
+		Terms:      terms,
+		Categories: categoriesParam(r),
+		Author:     strings.TrimSpace(params.Get("author")),
+		ViewerID:   principal.UserID,
+		Limit:      limit,
+		Offset:     offset,
+	}
+	for _, day := range []struct {
+		param string
+		bound **time.Time
+		shift time.Duration
+	}{
+		{"from", &query.Since, 0},
+		// to includes its whole day
+		{"to", &query.Until, 24 * time.Hour},
+	} {
+		value := params.Get(day.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			response.WriteError(w, response.InvalidField(day.param, day.param+" must be a date like 2006-01-02"))
+			return
+		}
+		t = t.Add(day.shift)
+		*day.bound = &t
+	}
+
+	results := SearchResults{
+		Posts:    []database.PostResult{},
+		Comments: []database.CommentResult{},
+		Users:    []database.User{},
+	}
+	search := func() (err error) {
+		if kind == "" || kind == "posts" {
+			if results.Posts, err = h.store.Search.Posts(query); err != nil {
+				return err
+			}
+		}
+		if kind == "" || kind == "comments" {
+			if results.Comments, err = h.store.Search.Comments(query); err != nil {
+				return err
+			}
+		}
+		// users have no category, author or date, so those filters leave them out
+		filtered := len(query.Categories) > 0 || query.Author != "" || query.Since != nil || query.Until != nil
+		if (kind == "" || kind == "users") && !filtered {
+			if results.Users, err = h.store.Search.Users(query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := search(); err != nil {
+		log.Println("Error searching:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	for i := range results.Users {
+		results.Users[i].Status = string(h.wsHub.Presence().Status(results.Users[i].ID.String()))
+		results.Users[i].Online = results.Users[i].Status != string(StatusOffline)
+	}
+
+	response.WriteJSON(w, http.StatusOK, results)
+}
+
+/* -------------------- Moderation -------------------- */
+
+// maxSuspensionHours is the longest a suspension can last; longer sanctions are bans.
+const maxSuspensionHours = 24 * 365
+
+// noOverride is passed to authoredPost and authoredComment when only the author may act.
+const noOverride Permission = ""
+
+// LockPost locks the thread of a post, after which only moderators can comment on it
+func (h *Handler) LockPost(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	h.setLocked(w, r, &now)
+}
+
+// UnlockPost reopens a locked thread
+func (h *Handler) UnlockPost(w http.ResponseWriter, r *http.Request) {
+	h.setLocked(w, r, nil)
+}
+
+// setLocked locks the post named by the {id} path value from lockedAt, or unlocks it when nil
+func (h *Handler) setLocked(w http.ResponseWriter, r *http.Request, lockedAt *time.Time) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	post, ok := h.authoredPost(w, r, principal, PermLockThread)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Posts.SetLocked(post.ID, lockedAt); err != nil {
+		log.Println("Error locking post:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	post.LockedAt = lockedAt
+
+	action := database.ModerationAction{Action: database.ActionUnlockPost, TargetType: database.TargetPost, TargetID: strconv.Itoa(post.ID)}
+	if lockedAt != nil {
+		action.Action = database.ActionLockPost
+	}
+	h.audit(principal, action)
+
+	broadcast := post
+	broadcast.MyReaction = ""
+	h.wsHub.BroadcastEvent(EventPostUpdated, broadcast)
+
+	response.WriteJSON(w, http.StatusOK, post)
+}
+
+// BanUser bans a user indefinitely and logs them out everywhere
+func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	h.sanction(w, r, &now, nil)
+}
+
+// SuspendUser suspends a user for the number of hours in the request and logs them out everywhere
+func (h *Handler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Hours int `json:"hours"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	if body.Hours < 1 || body.Hours > maxSuspensionHours {
+		response.WriteError(w, response.InvalidField("hours", fmt.Sprintf("A suspension lasts from 1 to %d hours", maxSuspensionHours)))
+		return
+	}
+
+	until := time.Now().Add(time.Duration(body.Hours) * time.Hour)
+	h.sanction(w, r, nil, &until)
+}
+
+// LiftSanction lifts the ban or suspension of a user
+func (h *Handler) LiftSanction(w http.ResponseWriter, r *http.Request) {
+	h.sanction(w, r, nil, nil)
+}
+
+// sanction bans or suspends the user named by the {id} path value, revoking their
+// sessions and closing their sockets, or lifts their sanction when both times are nil
+func (h *Handler) sanction(w http.ResponseWriter, r *http.Request, bannedAt, suspendedUntil *time.Time) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.moderatedUser(w, r, principal)
+	if !ok {
+		return
+	}
+
+	userID := user.ID.String()
+	if err := h.store.Users.SetSanction(userID, bannedAt, suspendedUntil); err != nil {
+		log.Println("Error sanctioning user:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if bannedAt != nil || suspendedUntil != nil {
+		if err := utils.RevokeUserSessions(h.store.Sessions, userID); err != nil {
+			log.Println("Error revoking sessions:", err)
+			response.WriteError(w, response.Internal())
+			return
+		}
+		h.wsHub.DisconnectUser(userID)
+	}
+
+	action := database.ModerationAction{Action: database.ActionLiftSanction, TargetType: database.TargetUser, TargetID: userID}
+	switch {
+	case bannedAt != nil:
+		action.Action = database.ActionBanUser
+	case suspendedUntil != nil:
+		action.Action = database.ActionSuspendUser
+		action.Note = "until " + suspendedUntil.Format(time.RFC3339)
+	}
+	h.audit(principal, action)
+
+	user.BannedAt, user.SuspendedUntil = bannedAt, suspendedUntil
+	response.WriteJSON(w, http.StatusOK, user)
+}
+
+// SetUserRole changes the role of a user, for admins
+func (h *Handler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	if !IsRole(body.Role) {
+		response.WriteError(w, response.InvalidField("role", "Role must be 'member', 'moderator' or 'admin'"))
+		return
+	}
+
+	user, ok := h.moderatedUser(w, r, principal)
+	if !ok {
+		return
+	}
+	if err := h.store.Users.SetRole(user.ID.String(), body.Role); err != nil {
+		log.Println("Error setting role:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.audit(principal, database.ModerationAction{
+		Action: database.ActionSetRole, TargetType: database.TargetUser, TargetID: user.ID.String(),
+		Note: user.Role + " -> " + body.Role,
+	})
+
+	user.Role = body.Role
+	response.WriteJSON(w, http.StatusOK, user)
+}
+
+// moderatedUser loads the user named by the {id} path value, answering 404 when
+// there is none and 403 unless the principal outranks them, which also keeps
+// moderators from acting on themselves.
+func (h *Handler) moderatedUser(w http.ResponseWriter, r *http.Request, principal *Principal) (database.User, bool) {
+	user, err := h.store.Users.Get(r.PathValue("id"))
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("User not found"))
+			return user, false
+		}
+		log.Println("Error loading user:", err)
+		response.WriteError(w, response.Internal())
+		return user, false
+	}
+	if !principal.Outranks(user.Role) {
+		response.WriteError(w, response.Forbidden("You can only moderate users below your role"))
+		return user, false
+	}
+	return user, true
+}
+
+// audit records a moderator action in the moderation log. The action has already
+// been carried out, so a failure to record it is only logged.
+func (h *Handler) audit(principal *Principal, action database.ModerationAction) {
+	moderatorID, err := uuid.FromString(principal.UserID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		return
+	}
+	action.ModeratorID = moderatorID
+	action.CreatedAt = time.Now()
+	if err := h.store.ModerationLog.Add(&action); err != nil {
+		log.Println("Error recording moderation action:", err)
+	}
+}
+
+// GetModerationLog lists the moderator actions, newest first, for admins
+func (h *Handler) GetModerationLog(w http.ResponseWriter, r *http.Request) {
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	actions, more, err := h.store.ModerationLog.List(page)
+	if err != nil {
+		log.Println("Error listing moderation log:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, newCursorPage(actions, more, page, func(a database.ModerationAction) store.Cursor {
+		return store.Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+	}))
+}
+
+/* -------------------- Reports -------------------- */
+
+// reportTransitions lists the statuses a report can move to from each status.
+// Settled reports can only be reopened.
+var reportTransitions = map[string][]string{
+	database.ReportOpen:      {database.ReportReviewing, database.ReportActioned, database.ReportDismissed},
+	database.ReportReviewing: {database.ReportOpen, database.ReportActioned, database.ReportDismissed},
+	database.ReportActioned:  {database.ReportOpen},
+	database.ReportDismissed: {database.ReportOpen},
+}
+
+// CreateReport flags a post, comment or message for moderators and notifies those online
+func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		TargetType string `json:"target_type"`
+		TargetID   int    `json:"target_id"`
+		Reason     string `json:"reason"`
+		Details    string `json:"details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	report := database.Report{
+		TargetType: body.TargetType,
+		TargetID:   body.TargetID,
+		Reason:     body.Reason,
+		Details:    strings.TrimSpace(body.Details),
+		Status:     database.ReportOpen,
+	}
+	if err := utils.ValidateReport(report); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+
+	authorID, content, err := h.reportedContent(principal, report.TargetType, report.TargetID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Reported content not found"))
+			return
+		}
+		log.Println("Error loading reported content:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if authorID.String() == principal.UserID {
+		response.WriteError(w, response.InvalidField("target_id", "You cannot report your own content"))
+		return
+	}
+
+	reporterID, err := uuid.FromString(principal.UserID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	report.ReporterID = reporterID
+	report.Content = content
+	report.CreatedAt = time.Now()
+	report.UpdatedAt = report.CreatedAt
+	if err := h.store.Reports.Create(&report); err != nil {
+		if err == store.ErrAlreadyReported {
+			response.WriteError(w, response.Conflict("You already reported this"))
+			return
+		}
+		log.Println("Error creating report:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.notifyModerators(EventReportCreated, report)
+
+	response.WriteJSON(w, http.StatusCreated, report)
+}
+
+// reportedContent returns the author and text of the post, comment or message a report
+// targets, or store.ErrNotFound when there is none the principal can see: messages
+// can only be reported by the people in their conversation.
+func (h *Handler) reportedContent(principal *Principal, targetType string, targetID int) (uuid.UUID, string, error) {
+	switch targetType {
+	case database.TargetPost:
+		post, err := h.store.Posts.Get(principal.UserID, targetID)
+		return post.UserID, post.Title + "\n\n" + post.Content, err
+	case database.TargetComment:
+		comment, err := h.store.Comments.Get(principal.UserID, targetID)
+		return comment.UserID, comment.Content, err
+	}
+	message, err := h.store.Messages.Get(targetID)
+	if err == nil && message.SenderID.String() != principal.UserID && message.ReceiverID.String() != principal.UserID {
+		err = store.ErrNotFound
+	}
+	return message.SenderID, message.Content, err
+}
+
+// notifyModerators sends an event to every connection of the moderators and admins.
+func (h *Handler) notifyModerators(eventType string, payload any) {
+	moderators, err := h.store.Users.ListIDsByRole([]string{RoleModerator, RoleAdmin})
+	if err != nil {
+		log.Println("Error listing moderators:", err)
+		return
+	}
+	for _, id := range moderators {
+		h.wsHub.SendEvent(id, eventType, payload)
+	}
+}
+
+// GetReports lists the moderation queue, newest first, narrowed by the optional
+// ?status=, ?target_type= and ?reason= parameters
+func (h *Handler) GetReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.ReportFilter{
+		Status:     query.Get("status"),
+		TargetType: query.Get("target_type"),
+		Reason:     query.Get("reason"),
+	}
+	if err := utils.ValidateReportFilter(filter.Status, filter.TargetType, filter.Reason); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+
+	reports, more, err := h.store.Reports.List(filter, page)
+	if err != nil {
+		log.Println("Error listing reports:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, newCursorPage(reports, more, page, func(report database.Report) store.Cursor {
+		return store.Cursor{CreatedAt: report.CreatedAt, ID: report.ID}
+	}))
+}
+
+// UpdateReport moves a report through the moderation queue, recording the change
+// and an optional note in the moderation log
+func (h *Handler) UpdateReport(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	body.Note = strings.TrimSpace(body.Note)
+	if len(body.Note) > utils.MaxReportTextLength {
+		response.WriteError(w, response.InvalidField("note", fmt.Sprintf("Note must be at most %d characters long", utils.MaxReportTextLength)))
+		return
+	}
+
+	report, err := h.store.Reports.Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Report not found"))
+			return
+		}
+		log.Println("Error loading report:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	from := report.Status
+	if !slices.Contains(reportTransitions[from], body.Status) {
+		response.WriteError(w, response.InvalidField("status", fmt.Sprintf("A %s report cannot become %q", from, body.Status)))
+		return
+	}
+
+	// reopened reports go back to the queue unassigned
+	report.ReviewerID = nil
+	if body.Status != database.ReportOpen {
+		reviewerID, err := uuid.FromString(principal.UserID)
+		if err != nil {
+			log.Println("Error parsing user ID:", err)
+			response.WriteError(w, response.Internal())
+			return
+		}
+		report.ReviewerID = &reviewerID
+	}
+	report.Status = body.Status
+	report.UpdatedAt = time.Now()
+	if err := h.store.Reports.SetStatus(&report, from); err != nil {
+		if err == store.ErrStatusChanged {
+			response.WriteError(w, response.Conflict("The report was updated by another moderator"))
+			return
+		}
+		log.Println("Error updating report:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	note := from + " -> " + report.Status
+	if body.Note != "" {
+		note += ": " + body.Note
+	}
+	h.audit(principal, database.ModerationAction{Action: database.ActionReviewReport, TargetType: database.TargetReport, TargetID: strconv.Itoa(report.ID), Note: note})
+	h.notifyModerators(EventReportUpdated, report)
+
+	response.WriteJSON(w, http.StatusOK, report)
+}
+
+/* -------------------- Notifications -------------------- */
+
+// GetNotifications gets a page of the current user's notifications, only the unread
+// ones with ?unread=true
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pageParams(r)
+	if err != nil {
+		response.WriteError(w, err)
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, more, err := h.store.Notifications.List(principal.UserID, unreadOnly, page)
+	if err != nil {
+		log.Println("Error fetching notifications:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusOK, newCursorPage(notifications, more, page, func(n database.Notification) store.Cursor {
+		return store.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+	}))
+}
+
+// GetUnreadNotificationCount tells the current user how many notifications they have not read
+func (h *Handler) GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	unread, err := h.store.Notifications.UnreadCount(principal.UserID)
+	if err != nil {
+		log.Println("Error counting unread notifications:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, NotificationsReadPayload{Unread: unread})
+}
+
+// MarkNotificationRead marks one of the current user's notifications read
+func (h *Handler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Notifications.MarkRead(principal.UserID, id, time.Now()); err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("Notification not found"))
+			return
+		}
+		log.Println("Error marking notification read:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.notificationsRead(w, principal, id)
+}
+
+// MarkAllNotificationsRead marks every notification of the current user read
+func (h *Handler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.store.Notifications.MarkAllRead(principal.UserID, time.Now()); err != nil {
+		log.Println("Error marking notifications read:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.notificationsRead(w, principal, 0)
+}
+
+// notificationsRead answers a mark-read request with the new unread count and
+// sends it to the user's clients so that every open tab stays in sync.
+func (h *Handler) notificationsRead(w http.ResponseWriter, principal *Principal, id int) {
+	unread, err := h.store.Notifications.UnreadCount(principal.UserID)
+	if err != nil {
+		log.Println("Error counting unread notifications:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	payload := NotificationsReadPayload{ID: id, Unread: unread}
+	h.wsHub.SendEvent(principal.UserID, EventNotificationsRead, payload)
+	response.WriteJSON(w, http.StatusOK, payload)
+}
+
+// GetNotificationPreferences tells the current user which kinds of notification they receive
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.store.Notifications.Preferences(principal.UserID)
+	if err != nil {
+		log.Println("Error loading notification preferences:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences turns kinds of notification on or off for the
+// current user; kinds left out of the request keep their setting
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var preferences map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := utils.ValidateNotificationPreferences(preferences); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+
+	if err := h.store.Notifications.SetPreferences(principal.UserID, preferences); err != nil {
+		log.Println("Error saving notification preferences:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	h.GetNotificationPreferences(w, r)
+}
+
+/* -------------------- Attachments -------------------- */
+
+// UploadAttachment stores a file sent as the "file" field of a multipart form. The
+// attachment stays unattached, visible to its uploader alone, until its ID is given
+// with a new post or private message
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	// leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, utils.MaxAttachmentSize+64<<10)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteError(w, response.TooLarge(fmt.Sprintf("Files cannot be larger than %d MB", utils.MaxAttachmentSize>>20)))
+			return
+		}
+		response.WriteError(w, response.InvalidField("file", "A file is required"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, utils.MaxAttachmentSize+1))
+	if err != nil {
+		log.Println("Error reading upload:", err)
+		response.WriteError(w, response.BadRequest("The file could not be read"))
+		return
+	}
+	if len(data) > utils.MaxAttachmentSize {
+		response.WriteError(w, response.TooLarge(fmt.Sprintf("Files cannot be larger than %d MB", utils.MaxAttachmentSize>>20)))
+		return
+	}
+	if len(data) == 0 {
+		response.WriteError(w, response.InvalidField("file", "The file is empty"))
+		return
+	}
+	mimeType, allowed := utils.AttachmentType(data)
+	if !allowed {
+		response.WriteError(w, response.UnsupportedType("Only PNG, JPEG and GIF images, PDF and ZIP files and plain text can be attached"))
+		return
+	}
+
+	used, err := h.store.Attachments.UsedBytes(principal.UserID)
+	if err != nil {
+		log.Println("Error reading upload quota:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if used+int64(len(data)) > utils.AttachmentQuota {
+		response.WriteError(w, response.TooLarge(fmt.Sprintf("You cannot upload more than %d MB of files", utils.AttachmentQuota>>20)))
+		return
+	}
+
+	attachment := database.Attachment{
+		Filename:  utils.CleanFilename(header.Filename),
+		MimeType:  mimeType,
+		Size:      int64(len(data)),
+		CreatedAt: time.Now(),
+	}
+	attachment.UploaderID, err = uuid.FromString(principal.UserID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	if err := h.storeAttachment(&attachment, data); err != nil {
+		var fields utils.FieldErrors
+		if errors.As(err, &fields) {
+			response.WriteError(w, validationError(err))
+			return
+		}
+		log.Println("Error storing attachment:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+
+	response.WriteJSON(w, http.StatusCreated, attachment)
+}
+
+// GetAttachment serves the file of an attachment the current user can see
+func (h *Handler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	h.serveAttachment(w, r, false)
+}
+
+// GetAttachmentThumbnail serves the thumbnail of an image attachment the current user can see
+func (h *Handler) GetAttachmentThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveAttachment(w, r, true)
+}
+
+/* -------------------- Profiles -------------------- */
+
+// GetProfile returns the profile of user {id}, without the fields they hide from others
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.store.Users.GetProfile(r.PathValue("id"))
+	if err != nil {
+		if err == store.ErrNotFound {
+			response.WriteError(w, response.NotFound("User not found"))
+			return
+		}
+		log.Println("Error loading profile:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	profile.Status = string(h.wsHub.Presence().Status(profile.ID.String()))
+
+	response.WriteJSON(w, http.StatusOK, visibleProfile(profile, principal.UserID))
+}
+
+// GetAccount returns the details and privacy settings of the current user
+func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+	h.writeAccount(w, principal.UserID)
+}
+
+// UpdateAccount lets the current user change their details, profile and privacy
+// settings; fields left out of the request keep their value, and an avatar_id of 0
+// removes the avatar
+func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Username    *string         `json:"username"`
+		Email       *string         `json:"email"`
+		FirstName   *string         `json:"first_name"`
+		LastName    *string         `json:"last_name"`
+		Age         *int            `json:"age"`
+		Gender      *string         `json:"gender"`
+		DisplayName *string         `json:"display_name"`
+		Bio         *string         `json:"bio"`
+		AvatarID    *int            `json:"avatar_id"`
+		Privacy     map[string]bool `json:"privacy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.WriteError(w, response.BadRequest("Invalid request payload"))
+		return
+	}
+
+	user, err := h.store.Users.GetAccount(principal.UserID)
+	if err != nil {
+		log.Println("Error loading account:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	current := user
+	set := func(field, value *string) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	set(&user.Username, body.Username)
+	set(&user.Email, body.Email)
+	set(&user.FirstName, body.FirstName)
+	set(&user.LastName, body.LastName)
+	set(&user.Gender, body.Gender)
+	set(&user.DisplayName, body.DisplayName)
+	set(&user.Bio, body.Bio)
+	if body.Age != nil {
+		user.Age = *body.Age
+	}
+
+	utils.NormalizeUser(&user)
+	if err := utils.ValidateProfile(user, current); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+	if err := utils.ValidatePrivacy(body.Privacy); err != nil {
+		response.WriteError(w, validationError(err))
+		return
+	}
+
+	if body.AvatarID != nil {
+		user.AvatarID = nil
+		if *body.AvatarID != 0 {
+			if err := h.checkAvatar(principal.UserID, *body.AvatarID); err != nil {
+				log.Println("Error checking avatar:", err)
+				response.WriteError(w, err)
+				return
+			}
+			user.AvatarID = body.AvatarID
+		}
+	}
+
+	if err := h.store.Users.UpdateProfile(user); err != nil {
+		switch err {
+		case store.ErrUsernameTaken:
+			response.WriteError(w, response.ConflictField("username", "Username is already taken"))
+		case store.ErrEmailTaken:
+			response.WriteError(w, response.ConflictField("email", "Email is already registered"))
+		default:
+			log.Println("Error updating profile:", err)
+			response.WriteError(w, response.Internal())
+		}
+		return
+	}
+	if len(body.Privacy) > 0 {
+		if err := h.store.Users.SetPrivacy(principal.UserID, body.Privacy); err != nil {
+			log.Println("Error saving privacy settings:", err)
+			response.WriteError(w, response.Internal())
+			return
+		}
+	}
+	h.writeAccount(w, principal.UserID)
+}
@@ -0,0 +1,747 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/storage"
+	"real-time-forum/backend/store"
+	"real-time-forum/backend/utils"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/gorilla/websocket"
+)
+
+const testPassword = "Passw0rd!x"
+
+// testCategory is the category every test server starts with.
+const testCategory = "general"
+
+// testServer serves the API from an in-memory store.
+type testServer struct {
+	*httptest.Server
+	store *store.Store
+	hub   *Hub
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	st := store.NewMemoryStore()
+	if err := st.Categories.Create(&database.Category{Slug: testCategory, Name: "General"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := NewHub(st.Users, DefaultClientConfig())
+	go hub.StartHub()
+
+	srv := httptest.NewServer(NewRouter(st, hub, files))
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, store: st, hub: hub}
+}
+
+// testUser is an account of the test server with a client logged in as it.
+type testUser struct {
+	id       string
+	username string
+	client   *http.Client
+}
+
+// signUp creates an account straight in the store, so that its username can break
+// the current rules like accounts made before them, and logs in as it.
+func (s *testServer) signUp(t *testing.T, username string) *testUser {
+	t.Helper()
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := database.User{
+		ID:        uuid.Must(uuid.NewV4()),
+		Username:  username,
+		Email:     username + "@example.com",
+		FirstName: "Test",
+		LastName:  "User",
+		Age:       30,
+		Gender:    "other",
+	}
+	if err := s.store.Users.Create(user, hash); err != nil {
+		t.Fatal(err)
+	}
+	return s.logIn(t, user.ID.String(), username)
+}
+
+// logIn starts a new session of an account with a client of its own.
+func (s *testServer) logIn(t *testing.T, id, username string) *testUser {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new connection per request keeps the throttle, keyed by remote address, out of the way
+	transport := &http.Transport{DisableKeepAlives: true}
+	u := &testUser{id: id, username: username, client: &http.Client{Jar: jar, Transport: transport}}
+	credentials := map[string]string{"email_or_username": username, "password": testPassword}
+	if status, body := s.do(t, u, http.MethodPost, "/api/login", credentials); status != http.StatusOK {
+		t.Fatalf("login as %s: %d %s", username, status, body)
+	}
+	return u
+}
+
+// setRole makes u a member, moderator or admin; it applies to u's next request.
+func (s *testServer) setRole(t *testing.T, u *testUser, role string) {
+	t.Helper()
+	if err := s.store.Users.SetRole(u.id, role); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createPost posts as u under testCategory and returns the post's ID.
+func (s *testServer) createPost(t *testing.T, u *testUser, title string) int {
+	t.Helper()
+	post := map[string]any{"title": title, "content": "Content of " + title, "categories": []string{testCategory}}
+	status, body := s.do(t, u, http.MethodPost, "/api/create-post", post)
+	if status != http.StatusCreated {
+		t.Fatalf("creating post %q: %d %s", title, status, body)
+	}
+	var created database.Post
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatal(err)
+	}
+	return created.ID
+}
+
+// do sends a JSON request as u and returns the status and body of the response.
+func (s *testServer) do(t *testing.T, u *testUser, method, path string, body any) (int, string) {
+	t.Helper()
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+// dial opens a websocket as u and returns it once the hub has registered it.
+func (s *testServer) dial(t *testing.T, u *testUser) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Jar: u.client.Jar}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+
+	// the reply goes through the hub after the registration
+	ping(t, conn)
+	return conn
+}
+
+// ping sends a malformed event on conn and waits for the hub to answer it, returning
+// the events received in the meantime.
+func ping(t *testing.T, conn *websocket.Conn) []Event {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	_, before := readUntil(t, conn, EventError)
+	return before
+}
+
+// readUntil reads events from conn up to the first of type eventType, which it
+// returns along with the events before it.
+func readUntil(t *testing.T, conn *websocket.Conn, eventType string) (Event, []Event) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var before []Event
+	for {
+		var e Event
+		if err := conn.ReadJSON(&e); err != nil {
+			t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+		if e.Type == eventType {
+			return e, before
+		}
+		before = append(before, e)
+	}
+}
+
+// expectClose reads from conn until the server closes it and returns the close code.
+func expectClose(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("read error %v, want the connection closed", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visitor := &testUser{client: &http.Client{Jar: jar}}
+
+	if status, _ := s.do(t, visitor, http.MethodGet, "/api/check-auth", nil); status != http.StatusUnauthorized {
+		t.Errorf("check-auth before login: %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	account := map[string]any{
+		"username": "alice", "email": "Alice@Example.com", "password": testPassword,
+		"first_name": "Alice", "last_name": "User", "age": 30, "gender": "other",
+	}
+	if status, body := s.do(t, visitor, http.MethodPost, "/api/register", account); status != http.StatusCreated {
+		t.Fatalf("register: %d %s", status, body)
+	}
+	if status, body := s.do(t, visitor, http.MethodPost, "/api/register", account); status != http.StatusConflict {
+		t.Errorf("registering twice: %d %s, want %d", status, body, http.StatusConflict)
+	}
+
+	user, err := s.store.Users.GetByLogin("alice@example.com")
+	if err != nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if user.Password == testPassword {
+		t.Error("password stored in clear")
+	}
+
+	credentials := map[string]string{"email_or_username": "alice", "password": "wrong"}
+	if status, _ := s.do(t, visitor, http.MethodPost, "/api/login", credentials); status != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password: %d, want %d", status, http.StatusUnauthorized)
+	}
+	credentials["password"] = testPassword
+	if status, body := s.do(t, visitor, http.MethodPost, "/api/login", credentials); status != http.StatusOK {
+		t.Fatalf("login: %d %s", status, body)
+	}
+	if status, _ := s.do(t, visitor, http.MethodGet, "/api/check-auth", nil); status != http.StatusOK {
+		t.Errorf("check-auth after login: %d, want %d", status, http.StatusOK)
+	}
+}
+func TestSendMessage(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+
+	tests := []struct {
+		name       string
+		receiverID string
+		status     int
+	}{
+		{"unknown user", uuid.Must(uuid.NewV4()).String(), http.StatusNotFound},
+		{"invalid ID", "bob", http.StatusBadRequest},
+		{"self", alice.id, http.StatusBadRequest},
+		{"existing user", bob.id, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := s.do(t, alice, http.MethodPost, "/api/messages/"+tt.receiverID, map[string]string{"content": "hi"})
+			if status != tt.status {
+				t.Errorf("status = %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+
+	messages, _, err := s.store.Messages.ListConversation(alice.id, bob.id, store.Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("stored %d messages, want 1", len(messages))
+	}
+}
+
+func TestUpdateAccountKeepsLegacyUsername(t *testing.T) {
+	s := newTestServer(t)
+	// dots were allowed in usernames before they were restricted
+	user := s.signUp(t, "old.name")
+
+	status, body := s.do(t, user, http.MethodPatch, "/api/me", map[string]string{"display_name": "Old Name"})
+	if status != http.StatusOK {
+		t.Fatalf("updating the display name: %d %s", status, body)
+	}
+	var account Account
+	if err := json.Unmarshal([]byte(body), &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Username != "old.name" || account.DisplayName != "Old Name" {
+		t.Errorf("account = %q (%q), want %q (%q)", account.Username, account.DisplayName, "old.name", "Old Name")
+	}
+
+	status, body = s.do(t, user, http.MethodPatch, "/api/me", map[string]string{"username": "new.name"})
+	if status != http.StatusBadRequest {
+		t.Errorf("renaming to an invalid username: %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+}
+
+func TestUpdateAccountUsernameTaken(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+
+	status, body := s.do(t, bob, http.MethodPatch, "/api/me", map[string]string{"username": "Alice"})
+	if status != http.StatusConflict {
+		t.Errorf("status = %d, want %d: %s", status, http.StatusConflict, body)
+	}
+}
+
+// currentSession returns the ID of the session u is logged in with.
+func (s *testServer) currentSession(t *testing.T, u *testUser) (string, []database.Session) {
+	t.Helper()
+	status, body := s.do(t, u, http.MethodGet, "/api/sessions", nil)
+	if status != http.StatusOK {
+		t.Fatalf("listing sessions: %d %s", status, body)
+	}
+	var sessions []database.Session
+	if err := json.Unmarshal([]byte(body), &sessions); err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range sessions {
+		if session.Current {
+			return session.ID, sessions
+		}
+	}
+	t.Fatalf("no current session among %s", body)
+	return "", nil
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	s := newTestServer(t)
+	laptop := s.signUp(t, "alice")
+	phone := s.logIn(t, laptop.id, "alice")
+	bob := s.signUp(t, "bob")
+
+	laptopSession, sessions := s.currentSession(t, laptop)
+	phoneSession, _ := s.currentSession(t, phone)
+	if len(sessions) != 2 || laptopSession == phoneSession {
+		t.Fatalf("listed %d sessions with %q current, want 2 with one per client", len(sessions), laptopSession)
+	}
+	conn := s.dial(t, phone)
+
+	for _, tt := range []struct {
+		name string
+		u    *testUser
+		id   string
+	}{
+		{"another user's session", bob, phoneSession},
+		{"unknown session", laptop, uuid.Must(uuid.NewV4()).String()},
+	} {
+		if status, body := s.do(t, tt.u, http.MethodDelete, "/api/sessions/"+tt.id, nil); status != http.StatusNotFound {
+			t.Errorf("revoking %s: %d %s, want %d", tt.name, status, body, http.StatusNotFound)
+		}
+	}
+
+	if status, body := s.do(t, laptop, http.MethodDelete, "/api/sessions/"+phoneSession, nil); status != http.StatusOK {
+		t.Fatalf("revoking the phone session: %d %s", status, body)
+	}
+	if code := expectClose(t, conn); code != websocket.ClosePolicyViolation {
+		t.Errorf("socket of the revoked session closed with %d, want %d", code, websocket.ClosePolicyViolation)
+	}
+	if status, _ := s.do(t, phone, http.MethodGet, "/api/check-auth", nil); status != http.StatusUnauthorized {
+		t.Errorf("check-auth with the revoked session: %d, want %d", status, http.StatusUnauthorized)
+	}
+	if _, sessions := s.currentSession(t, laptop); len(sessions) != 1 {
+		t.Errorf("listed %d sessions after revoking one, want 1", len(sessions))
+	}
+}
+
+func TestSessionSlidingExpiry(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	id, _ := s.currentSession(t, alice)
+
+	// idle long enough to be refreshed, and about to expire
+	if err := s.store.Sessions.Touch(id, time.Now().Add(-time.Hour), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := alice.client.Get(s.URL + "/api/check-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("check-auth: %d", resp.StatusCode)
+	}
+
+	want := time.Now().Add(utils.SessionDuration - time.Minute)
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == utils.SessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Expires.Before(want) {
+		t.Errorf("session cookie %v, want it renewed for %v", cookie, utils.SessionDuration)
+	}
+	if _, sessions := s.currentSession(t, alice); sessions[0].ExpiresAt.Before(want) {
+		t.Errorf("session expires at %v, want it pushed back to %v", sessions[0].ExpiresAt, want)
+	}
+}
+
+func TestLogoutClosesOnlyItsSockets(t *testing.T) {
+	s := newTestServer(t)
+	laptop := s.signUp(t, "alice")
+	phone := s.logIn(t, laptop.id, "alice")
+	laptopConn, phoneConn := s.dial(t, laptop), s.dial(t, phone)
+
+	if status, body := s.do(t, laptop, http.MethodPost, "/api/logout", nil); status != http.StatusOK {
+		t.Fatalf("logout: %d %s", status, body)
+	}
+	if code := expectClose(t, laptopConn); code != websocket.ClosePolicyViolation {
+		t.Errorf("socket of the logged out session closed with %d, want %d", code, websocket.ClosePolicyViolation)
+	}
+
+	// the phone's socket still gets answers from the hub
+	ping(t, phoneConn)
+	if status, _ := s.do(t, phone, http.MethodGet, "/api/check-auth", nil); status != http.StatusOK {
+		t.Errorf("check-auth with the other session: %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestModerationRequiresModerator(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	postID := s.createPost(t, bob, "Bob's post")
+
+	tests := []struct {
+		name, method, path string
+		body               any
+	}{
+		{"ban", http.MethodPost, "/api/users/" + bob.id + "/ban", nil},
+		{"suspend", http.MethodPost, "/api/users/" + bob.id + "/suspend", map[string]int{"hours": 1}},
+		{"lift sanction", http.MethodDelete, "/api/users/" + bob.id + "/ban", nil},
+		{"lock", http.MethodPost, "/api/posts/" + strconv.Itoa(postID) + "/lock", nil},
+		{"reports", http.MethodGet, "/api/reports", nil},
+		{"moderation log", http.MethodGet, "/api/moderation-log", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := s.do(t, alice, tt.method, tt.path, tt.body); status != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", status, http.StatusForbidden, body)
+			}
+		})
+	}
+
+	if status, _ := s.do(t, bob, http.MethodGet, "/api/check-auth", nil); status != http.StatusOK {
+		t.Errorf("check-auth of the user a member tried to ban: %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestModeratorsOnlyActBelowTheirRole(t *testing.T) {
+	s := newTestServer(t)
+	mod := s.signUp(t, "mod")
+	peer := s.signUp(t, "peer")
+	admin := s.signUp(t, "admin")
+	member := s.signUp(t, "member")
+	s.setRole(t, mod, RoleModerator)
+	s.setRole(t, peer, RoleModerator)
+	s.setRole(t, admin, RoleAdmin)
+
+	tests := []struct {
+		name   string
+		actor  *testUser
+		target *testUser
+		status int
+	}{
+		{"moderator on a peer", mod, peer, http.StatusForbidden},
+		{"moderator on themselves", mod, mod, http.StatusForbidden},
+		{"moderator on an admin", mod, admin, http.StatusForbidden},
+		{"moderator on a member", mod, member, http.StatusOK},
+		{"admin on a moderator", admin, peer, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/users/" + tt.target.id + "/suspend"
+			if status, body := s.do(t, tt.actor, http.MethodPost, path, map[string]int{"hours": 1}); status != tt.status {
+				t.Errorf("status = %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+
+	if user, err := s.store.Users.Get(mod.id); err != nil || user.SuspendedUntil != nil {
+		t.Errorf("moderator suspended by themselves: %v, %v", user.SuspendedUntil, err)
+	}
+}
+
+func TestBanRevokesSessionsAndSockets(t *testing.T) {
+	s := newTestServer(t)
+	mod := s.signUp(t, "mod")
+	s.setRole(t, mod, RoleModerator)
+	laptop := s.signUp(t, "bob")
+	phone := s.logIn(t, laptop.id, "bob")
+	conns := []*websocket.Conn{s.dial(t, laptop), s.dial(t, phone)}
+
+	if status, body := s.do(t, mod, http.MethodPost, "/api/users/"+laptop.id+"/ban", nil); status != http.StatusOK {
+		t.Fatalf("ban: %d %s", status, body)
+	}
+
+	for i, conn := range conns {
+		if code := expectClose(t, conn); code != websocket.ClosePolicyViolation {
+			t.Errorf("socket %d closed with %d, want %d", i, code, websocket.ClosePolicyViolation)
+		}
+	}
+	for _, u := range []*testUser{laptop, phone} {
+		if status, _ := s.do(t, u, http.MethodGet, "/api/check-auth", nil); status != http.StatusUnauthorized {
+			t.Errorf("check-auth after the ban: %d, want %d", status, http.StatusUnauthorized)
+		}
+	}
+	credentials := map[string]string{"email_or_username": "bob", "password": testPassword}
+	if status, body := s.do(t, laptop, http.MethodPost, "/api/login", credentials); status != http.StatusForbidden {
+		t.Errorf("login after the ban: %d %s, want %d", status, body, http.StatusForbidden)
+	}
+
+	entries, _, err := s.store.ModerationLog.List(store.Page{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != database.ActionBanUser || entries[0].TargetID != laptop.id {
+		t.Errorf("moderation log = %+v, want the ban", entries)
+	}
+}
+
+func TestLockedThreadRejectsMemberComments(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	mod := s.signUp(t, "mod")
+	s.setRole(t, mod, RoleModerator)
+	postID := s.createPost(t, alice, "Heated thread")
+
+	path := "/api/posts/" + strconv.Itoa(postID) + "/lock"
+	if status, body := s.do(t, mod, http.MethodPost, path, nil); status != http.StatusOK {
+		t.Fatalf("lock: %d %s", status, body)
+	}
+
+	comment := map[string]any{"post_id": postID, "content": "Last word"}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/create-comment", comment); status != http.StatusForbidden {
+		t.Errorf("member comment on a locked thread: %d %s, want %d", status, body, http.StatusForbidden)
+	}
+	status, body := s.do(t, mod, http.MethodPost, "/api/create-comment", comment)
+	if status != http.StatusCreated {
+		t.Fatalf("moderator comment on a locked thread: %d %s", status, body)
+	}
+	var modComment database.Comment
+	if err := json.Unmarshal([]byte(body), &modComment); err != nil {
+		t.Fatal(err)
+	}
+	replies := "/api/comments/" + strconv.Itoa(modComment.ID) + "/replies"
+	if status, body := s.do(t, alice, http.MethodPost, replies, map[string]string{"content": "Reply"}); status != http.StatusForbidden {
+		t.Errorf("member reply on a locked thread: %d %s, want %d", status, body, http.StatusForbidden)
+	}
+
+	if status, body := s.do(t, mod, http.MethodDelete, path, nil); status != http.StatusOK {
+		t.Fatalf("unlock: %d %s", status, body)
+	}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/create-comment", comment); status != http.StatusCreated {
+		t.Errorf("member comment once unlocked: %d %s, want %d", status, body, http.StatusCreated)
+	}
+}
+
+func TestReportLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	mod := s.signUp(t, "mod")
+	s.setRole(t, mod, RoleModerator)
+	modConn, bobConn := s.dial(t, mod), s.dial(t, bob)
+	postID := s.createPost(t, alice, "Buy now")
+
+	report := map[string]any{"target_type": database.TargetPost, "target_id": postID, "reason": database.ReasonSpam}
+	if status, body := s.do(t, alice, http.MethodPost, "/api/reports", report); status != http.StatusBadRequest {
+		t.Errorf("reporting one's own post: %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+	status, body := s.do(t, bob, http.MethodPost, "/api/reports", report)
+	if status != http.StatusCreated {
+		t.Fatalf("report: %d %s", status, body)
+	}
+	var created database.Report
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := s.do(t, bob, http.MethodPost, "/api/reports", report); status != http.StatusConflict {
+		t.Errorf("reporting twice: %d %s, want %d", status, body, http.StatusConflict)
+	}
+
+	event, _ := readUntil(t, modConn, EventReportCreated)
+	var notified database.Report
+	if err := json.Unmarshal(event.Payload, &notified); err != nil {
+		t.Fatal(err)
+	}
+	if notified.ID != created.ID || notified.Status != database.ReportOpen {
+		t.Errorf("moderator notified of report %d (%s), want %d (%s)", notified.ID, notified.Status, created.ID, database.ReportOpen)
+	}
+	for _, e := range ping(t, bobConn) {
+		if e.Type == EventReportCreated {
+			t.Error("member notified of the report")
+		}
+	}
+
+	path := "/api/reports/" + strconv.Itoa(created.ID)
+	tests := []struct {
+		status string
+		code   int
+	}{
+		{database.ReportReviewing, http.StatusOK},
+		{database.ReportActioned, http.StatusOK},
+		{database.ReportDismissed, http.StatusBadRequest},
+		{database.ReportOpen, http.StatusOK},
+		{database.ReportDismissed, http.StatusOK},
+		{database.ReportReviewing, http.StatusBadRequest},
+		{"closed", http.StatusBadRequest},
+	}
+	want := database.ReportOpen
+	for _, tt := range tests {
+		code, body := s.do(t, mod, http.MethodPatch, path, map[string]string{"status": tt.status})
+		if code != tt.code {
+			t.Errorf("%s report to %s: %d %s, want %d", want, tt.status, code, body, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		want = tt.status
+		event, _ := readUntil(t, modConn, EventReportUpdated)
+		if err := json.Unmarshal(event.Payload, &notified); err != nil {
+			t.Fatal(err)
+		}
+		if notified.Status != want {
+			t.Errorf("moderator notified of a %s report, want %s", notified.Status, want)
+		}
+	}
+
+	stored, err := s.store.Reports.Get(created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != database.ReportDismissed || stored.ReviewerID == nil || stored.ReviewerID.String() != mod.id {
+		t.Errorf("stored report %s reviewed by %v, want dismissed by the moderator", stored.Status, stored.ReviewerID)
+	}
+}
+
+func TestCreatePostCategories(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	if err := s.store.Categories.Create(&database.Category{Slug: "help", Name: "Help"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		categories []string
+		status     int
+	}{
+		{"unknown category", []string{"nope"}, http.StatusBadRequest},
+		{"unknown among known ones", []string{testCategory, "nope"}, http.StatusBadRequest},
+		{"none", nil, http.StatusBadRequest},
+		{"known, in another case", []string{"Help"}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := map[string]any{"title": "Title", "content": "Content", "categories": tt.categories}
+			if status, body := s.do(t, alice, http.MethodPost, "/api/create-post", post); status != tt.status {
+				t.Errorf("status = %d, want %d: %s", status, tt.status, body)
+			}
+		})
+	}
+	generalID := s.createPost(t, alice, "General post")
+
+	status, body := s.do(t, alice, http.MethodGet, "/api/get-posts?category="+testCategory, nil)
+	if status != http.StatusOK {
+		t.Fatalf("listing posts: %d %s", status, body)
+	}
+	var listed CursorPage[database.Post]
+	if err := json.Unmarshal([]byte(body), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Items) != 1 || listed.Items[0].ID != generalID {
+		t.Errorf("posts in %s = %+v, want only post %d", testCategory, listed.Items, generalID)
+	}
+}
+
+func TestNotificationPreferencesAndRepeats(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	carol := s.signUp(t, "carol")
+	postID := s.createPost(t, alice, "Alice's post")
+
+	inbox := func() []database.Notification {
+		t.Helper()
+		status, body := s.do(t, alice, http.MethodGet, "/api/notifications", nil)
+		if status != http.StatusOK {
+			t.Fatalf("listing notifications: %d %s", status, body)
+		}
+		var page CursorPage[database.Notification]
+		if err := json.Unmarshal([]byte(body), &page); err != nil {
+			t.Fatal(err)
+		}
+		return page.Items
+	}
+	like := map[string]any{"target_type": database.TargetPost, "target_id": postID, "reaction": database.ReactionLike}
+
+	// like, unlike and like again
+	for range 3 {
+		if status, body := s.do(t, bob, http.MethodPost, "/api/reactions", like); status != http.StatusOK {
+			t.Fatalf("reacting: %d %s", status, body)
+		}
+	}
+	if n := inbox(); len(n) != 1 || n[0].Type != database.NotifyReaction {
+		t.Fatalf("notifications after repeated likes = %+v, want one reaction", n)
+	}
+
+	preferences := map[string]bool{database.NotifyReaction: false}
+	if status, body := s.do(t, alice, http.MethodPut, "/api/notifications/preferences", preferences); status != http.StatusOK {
+		t.Fatalf("saving preferences: %d %s", status, body)
+	}
+	if status, body := s.do(t, alice, http.MethodPut, "/api/notifications/preferences", map[string]bool{"digest": false}); status != http.StatusBadRequest {
+		t.Errorf("saving an unknown kind: %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+
+	if status, body := s.do(t, carol, http.MethodPost, "/api/reactions", like); status != http.StatusOK {
+		t.Fatalf("reacting: %d %s", status, body)
+	}
+	comment := map[string]any{"post_id": postID, "content": "Nice post"}
+	if status, body := s.do(t, carol, http.MethodPost, "/api/create-comment", comment); status != http.StatusCreated {
+		t.Fatalf("commenting: %d %s", status, body)
+	}
+
+	n := inbox()
+	if len(n) != 2 || n[0].Type != database.NotifyComment || n[0].ActorID.String() != carol.id {
+		t.Errorf("notifications = %+v, want carol's comment and no reaction of hers", n)
+	}
+}
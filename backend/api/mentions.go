@@ -0,0 +1,78 @@
+package api
+
+import (
+	"log"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/utils"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// resolveMentions returns the @username references in content that name a user,
+// leaving the others as plain text. When participants are given, as for a private
+// message, only they can be mentioned. Mentions are not worth failing the write
+// they come with, so a lookup error is logged and leaves none.
+func (h *Handler) resolveMentions(content string, participants ...uuid.UUID) []database.Mention {
+	found := utils.FindMentions(content)
+	if len(found) == 0 {
+		return found
+	}
+	usernames := make([]string, len(found))
+	for i, mention := range found {
+		usernames[i] = mention.Username
+	}
+	users, err := h.store.Users.ListByUsernames(usernames)
+	if err != nil {
+		log.Println("Error resolving mentions:", err)
+		return []database.Mention{}
+	}
+
+	mentions := []database.Mention{}
+	for _, mention := range found {
+		for _, user := range users {
+			if strings.EqualFold(user.Username, mention.Username) && canMention(user.ID, participants) {
+				mention.UserID, mention.Username = user.ID, user.Username
+				mentions = append(mentions, mention)
+				break
+			}
+		}
+	}
+	return mentions
+}
+
+func canMention(userID uuid.UUID, participants []uuid.UUID) bool {
+	if len(participants) == 0 {
+		return true
+	}
+	for _, participant := range participants {
+		if participant == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// saveMentions stores the mentions of a post, comment or message and notifies the
+// users it mentions that previous, its mentions before an edit, did not. n carries
+// the actor and what the notifications point at.
+func (h *Handler) saveMentions(targetType string, targetID int, mentions, previous []database.Mention, n database.Notification) {
+	if err := h.store.Mentions.Replace(targetType, targetID, mentions); err != nil {
+		log.Println("Error storing mentions:", err)
+		return
+	}
+
+	notified := make(map[uuid.UUID]bool)
+	for _, mention := range previous {
+		notified[mention.UserID] = true
+	}
+	n.Type = database.NotifyMention
+	for _, mention := range mentions {
+		if notified[mention.UserID] {
+			continue
+		}
+		notified[mention.UserID] = true
+		n.UserID = mention.UserID
+		h.notify(n)
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/store"
 	"real-time-forum/backend/utils"
 	"time"
 )
 
 type Middleware struct {
-	db *database.Database
+	sessions store.SessionStore
 }
 
 func (m *Middleware) LogMiddleware(next http.Handler) http.Handler {
@@ -27,7 +28,7 @@ func (m *Middleware) LogMiddleware(next http.Handler) http.Handler {
 func (m *Middleware) CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization") // Specify allowed headers
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -36,21 +37,64 @@ func (m *Middleware) CorsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AuthMiddleware loads the session behind the request cookie and attaches its principal to the request context.
 func (m *Middleware) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := utils.GetCookie(r, "session_token")
+		token, err := utils.GetCookie(r, utils.SessionCookie)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			response.WriteError(w, response.Unauthorized("Unauthorized"))
 			return
 		}
 
-		userID, err := utils.GetUserID(m.db, token)
+		session, err := utils.GetSession(m.sessions, token)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			response.WriteError(w, response.Unauthorized("Unauthorized"))
 			return
-		}		
+		}
 
-		r.Header.Set("user_id", userID)
-		next.ServeHTTP(w, r)
+		// sliding expiration: keep active sessions alive
+		refreshed, err := utils.RefreshSession(m.sessions, &session)
+		if err != nil {
+			log.Println("Error refreshing session:", err)
+		} else if refreshed {
+			utils.SetSessionCookie(w, token, session.ExpiresAt)
+		}
+
+		principal := &Principal{
+			UserID:    session.UserID.String(),
+			Username:  session.Username,
+			Roles:     rolesUpTo(session.Role),
+			SessionID: session.ID,
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
 	})
 }
+
+// RequireRole answers 403 unless the request's principal holds the role. It must run inside AuthMiddleware.
+func (m *Middleware) RequireRole(role string) func(http.Handler) http.Handler {
+	return m.require(func(p *Principal) bool { return p.HasRole(role) })
+}
+
+// RequirePermission answers 403 unless one of the principal's roles grants the permission.
+// It must run inside AuthMiddleware.
+func (m *Middleware) RequirePermission(permission Permission) func(http.Handler) http.Handler {
+	return m.require(func(p *Principal) bool { return p.Can(permission) })
+}
+
+// require builds a middleware letting through the requests whose principal is allowed.
+func (m *Middleware) require(allowed func(*Principal) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := requirePrincipal(w, r)
+			if !ok {
+				return
+			}
+			if !allowed(principal) {
+				response.WriteError(w, response.Forbidden("You do not have permission to do this"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
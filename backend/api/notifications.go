@@ -0,0 +1,95 @@
+package api
+
+import (
+	"log"
+	"real-time-forum/backend/database"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// notify delivers a notification unless the recipient caused it or turned its kind
+// off: it is stored in their inbox and pushed to every client they have connected.
+func (h *Handler) notify(n database.Notification) {
+	if n.UserID == n.ActorID {
+		return
+	}
+	recipientID := n.UserID.String()
+
+	preferences, err := h.store.Notifications.Preferences(recipientID)
+	if err != nil {
+		log.Println("Error loading notification preferences:", err)
+		return
+	}
+	if !preferences[n.Type] {
+		return
+	}
+
+	n.CreatedAt = time.Now()
+	created, err := h.store.Notifications.Create(&n)
+	if err != nil {
+		log.Println("Error storing notification:", err)
+		return
+	}
+	if !created {
+		return
+	}
+
+	unread, err := h.store.Notifications.UnreadCount(recipientID)
+	if err != nil {
+		log.Println("Error counting unread notifications:", err)
+		return
+	}
+	h.wsHub.SendEvent(recipientID, EventNotification, NotificationPayload{Notification: n, Unread: unread})
+}
+
+// notifyComment tells the author of a post about a new comment on it and, for a
+// reply, the author of the comment it answers, who is only told once when both.
+func (h *Handler) notifyComment(comment database.Comment, post database.Post, parent *database.Comment) {
+	n := database.Notification{ActorID: comment.UserID, PostID: &comment.PostID, CommentID: &comment.ID}
+	if parent != nil {
+		reply := n
+		reply.UserID, reply.Type = parent.UserID, database.NotifyReply
+		h.notify(reply)
+		if parent.UserID == post.UserID {
+			return
+		}
+	}
+	n.UserID, n.Type = post.UserID, database.NotifyComment
+	h.notify(n)
+}
+
+// notifyReaction tells the author of a post or comment that actorID reacted to it.
+func (h *Handler) notifyReaction(actorID string, targetType string, targetID int) {
+	actor, err := uuid.FromString(actorID)
+	if err != nil {
+		log.Println("Error parsing user ID:", err)
+		return
+	}
+	n := database.Notification{ActorID: actor, Type: database.NotifyReaction}
+
+	switch targetType {
+	case database.TargetPost:
+		post, err := h.store.Posts.Get(actorID, targetID)
+		if err != nil {
+			log.Println("Error loading post:", err)
+			return
+		}
+		n.UserID, n.PostID = post.UserID, &post.ID
+	case database.TargetComment:
+		comment, err := h.store.Comments.Get(actorID, targetID)
+		if err != nil {
+			log.Println("Error loading comment:", err)
+			return
+		}
+		n.UserID, n.PostID, n.CommentID = comment.UserID, &comment.PostID, &comment.ID
+	}
+	h.notify(n)
+}
+
+// notifyMessage tells the receiver of a message about it. The notification points
+// at the conversation rather than the message, so that a burst of messages from
+// one sender leaves a single unread notification.
+func (h *Handler) notifyMessage(message database.Message) {
+	h.notify(database.Notification{UserID: message.ReceiverID, ActorID: message.SenderID, Type: database.NotifyMessage})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/store"
+	"strconv"
+)
+
+// Page sizes of paginated listings; larger requested limits are capped.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 50
+)
+
+// CursorPage is the envelope of paginated listings, whose items are newest first.
+// Next is the cursor to pass as ?after= for older items and Prev the one to pass
+// as ?before= for newer items; each is omitted when there is nothing that way.
+type CursorPage[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// pageParams reads the ?limit=, ?after= and ?before= parameters of a listing,
+// rejecting malformed cursors with a validation error.
+func pageParams(r *http.Request) (store.Page, error) {
+	query := r.URL.Query()
+	page := store.Page{Limit: defaultPageSize}
+
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		page.Limit = min(limit, maxPageSize)
+	}
+
+	after, before := query.Get("after"), query.Get("before")
+	if after != "" && before != "" {
+		return page, response.InvalidField("before", "after and before cannot be combined")
+	}
+	if after != "" {
+		cursor, err := store.DecodeCursor(after)
+		if err != nil {
+			return page, response.InvalidField("after", "Invalid pagination cursor")
+		}
+		page.After = &cursor
+	}
+	if before != "" {
+		cursor, err := store.DecodeCursor(before)
+		if err != nil {
+			return page, response.InvalidField("before", "Invalid pagination cursor")
+		}
+		page.Before = &cursor
+	}
+	return page, nil
+}
+
+// newCursorPage wraps the items of a page with the cursors of the pages around it;
+// more reports whether the store found items past the page in its direction.
+func newCursorPage[T any](items []T, more bool, page store.Page, cursor func(T) store.Cursor) CursorPage[T] {
+	if items == nil {
+		items = []T{}
+	}
+	result := CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return result
+	}
+
+	first, last := cursor(items[0]).Encode(), cursor(items[len(items)-1]).Encode()
+	if page.Before != nil {
+		// walking towards newer items, which leaves the cursor's item and older ones behind
+		result.Next = last
+		if more {
+			result.Prev = first
+		}
+		return result
+	}
+	if more {
+		result.Next = last
+	}
+	if page.After != nil {
+		result.Prev = first
+	}
+	return result
+}
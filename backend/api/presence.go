@@ -0,0 +1,137 @@
+package api
+
+import (
+	"log"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/store"
+	"sync"
+	"time"
+)
+
+// PresenceStatus is the availability of a user as seen by other users.
+type PresenceStatus string
+
+const (
+	StatusOnline  PresenceStatus = "online"
+	StatusAway    PresenceStatus = "away"
+	StatusOffline PresenceStatus = "offline"
+)
+
+// awayAfter is how long a connected user can stay inactive before being marked away.
+const awayAfter = 5 * time.Minute
+
+// presenceSweepInterval is how often the hub looks for idle users.
+const presenceSweepInterval = 30 * time.Second
+
+// Presence tracks the connection count and activity of every connected user.
+// It is updated by the hub goroutine and safe to query from handlers.
+type Presence struct {
+	mu    sync.RWMutex
+	store store.UserStore
+	users map[string]*presenceEntry
+}
+
+type presenceEntry struct {
+	connections int
+	status      PresenceStatus
+	lastActive  time.Time
+}
+
+func newPresence(users store.UserStore) *Presence {
+	return &Presence{store: users, users: make(map[string]*presenceEntry)}
+}
+
+// Status returns the current status of a user.
+func (p *Presence) Status(userID string) PresenceStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if entry, ok := p.users[userID]; ok {
+		return entry.status
+	}
+	return StatusOffline
+}
+
+// connect records a new connection and reports whether the user just came online.
+func (p *Presence) connect(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.users[userID]
+	if !ok {
+		entry = &presenceEntry{status: StatusOnline}
+		p.users[userID] = entry
+	}
+	entry.connections++
+	entry.lastActive = time.Now()
+	return !ok
+}
+
+// disconnect records a closed connection and reports whether it was the user's
+// last one, in which case the user goes offline.
+func (p *Presence) disconnect(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.users[userID]
+	if !ok {
+		return false
+	}
+	entry.connections--
+	if entry.connections > 0 {
+		return false
+	}
+	delete(p.users, userID)
+	return true
+}
+
+// recordLastSeen stores the time a user went offline and returns the offline
+// status to announce, leaving the time out when the user hides it from others.
+// It queries the database, so it must not run on the hub goroutine.
+func (p *Presence) recordLastSeen(userID string, lastSeen time.Time) PresencePayload {
+	change := PresencePayload{UserID: userID, Status: StatusOffline}
+	if err := p.store.SetLastSeen(userID, lastSeen); err != nil {
+		log.Println("Error recording last seen time:", err)
+	}
+	privacy, err := p.store.Privacy(userID)
+	if err != nil {
+		log.Println("Error loading privacy settings:", err)
+		return change
+	}
+	if privacy[database.ProfileLastSeen] {
+		change.LastSeenAt = &lastSeen
+	}
+	return change
+}
+
+// touch records activity from a user and reports whether they came back from away.
+func (p *Presence) touch(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.users[userID]
+	if !ok {
+		return false
+	}
+	entry.lastActive = time.Now()
+	if entry.status == StatusAway {
+		entry.status = StatusOnline
+		return true
+	}
+	return false
+}
+
+// expireIdle marks users inactive for longer than awayAfter as away and returns them.
+func (p *Presence) expireIdle(now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var away []string
+	for userID, entry := range p.users {
+		if entry.status == StatusOnline && now.Sub(entry.lastActive) > awayAfter {
+			entry.status = StatusAway
+			away = append(away, userID)
+		}
+	}
+	return away
+}
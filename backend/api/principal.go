@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+)
+
+// Roles a principal can have. Every registered user is a member, and each role
+// holds the ones below it: an admin is also a moderator and a member.
+const (
+	RoleMember    = database.RoleMember
+	RoleModerator = database.RoleModerator
+	RoleAdmin     = database.RoleAdmin
+)
+
+// roleOrder lists the roles from least to most privileged.
+var roleOrder = []string{RoleMember, RoleModerator, RoleAdmin}
+
+// Permission names an action reserved to some roles.
+type Permission string
+
+const (
+	PermDeleteAnyContent Permission = "delete_any_content"
+	PermLockThread       Permission = "lock_thread"
+	PermSanctionUser     Permission = "sanction_user"
+	PermViewRevisions    Permission = "view_revisions"
+	PermReviewReports    Permission = "review_reports"
+	PermViewAuditLog     Permission = "view_audit_log"
+)
+
+// rolePermissions lists the permissions each role adds to those of the roles below it.
+var rolePermissions = map[string][]Permission{
+	RoleModerator: {PermDeleteAnyContent, PermLockThread, PermSanctionUser, PermViewRevisions, PermReviewReports},
+	RoleAdmin:     {PermViewAuditLog},
+}
+
+// rank returns the position of a role in roleOrder, or -1 for unknown roles.
+func rank(role string) int {
+	for i, r := range roleOrder {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	return rank(role) >= 0
+}
+
+// rolesUpTo returns role along with every role below it, the roles a user with role holds.
+func rolesUpTo(role string) []string {
+	if !IsRole(role) {
+		return []string{RoleMember}
+	}
+	return append([]string{}, roleOrder[:rank(role)+1]...)
+}
+
+// Principal is the authenticated user a request is made on behalf of.
+type Principal struct {
+	UserID    string
+	Username  string
+	Roles     []string
+	SessionID string
+}
+
+// principalKey is the context key under which the Principal is stored.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal attached to ctx by AuthMiddleware.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// HasRole reports whether the principal has the given role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether one of the principal's roles grants the permission.
+func (p *Principal) Can(permission Permission) bool {
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Outranks reports whether the principal holds a role above role, as it must to
+// moderate or promote a user who has it.
+func (p *Principal) Outranks(role string) bool {
+	for _, r := range p.Roles {
+		if rank(r) > rank(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// requirePrincipal returns the request's principal, answering 401 when there is none.
+func requirePrincipal(w http.ResponseWriter, r *http.Request) (*Principal, bool) {
+	p, ok := PrincipalFrom(r.Context())
+	if !ok {
+		response.WriteError(w, response.Unauthorized("Unauthorized"))
+	}
+	return p, ok
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/store"
+	"real-time-forum/backend/utils"
+)
+
+// Account is the current user's own view of their details, along with which of
+// their profile fields others can see.
+type Account struct {
+	database.User
+	Privacy map[string]bool `json:"privacy"`
+}
+
+// writeAccount responds with the account of a user.
+func (h *Handler) writeAccount(w http.ResponseWriter, userID string) {
+	user, err := h.store.Users.GetAccount(userID)
+	if err != nil {
+		log.Println("Error loading account:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	privacy, err := h.store.Users.Privacy(userID)
+	if err != nil {
+		log.Println("Error loading privacy settings:", err)
+		response.WriteError(w, response.Internal())
+		return
+	}
+	response.WriteJSON(w, http.StatusOK, Account{User: user, Privacy: privacy})
+}
+
+// visibleProfile leaves out of a profile the fields its user hides from the viewer.
+// Users see the whole of their own profile, along with their privacy settings.
+func visibleProfile(profile database.Profile, viewerID string) database.Profile {
+	if profile.ID.String() == viewerID {
+		return profile
+	}
+	privacy := profile.Privacy
+	profile.Privacy = nil
+	if !privacy[database.ProfileDisplayName] {
+		profile.DisplayName = ""
+	}
+	if !privacy[database.ProfileBio] {
+		profile.Bio = ""
+	}
+	if !privacy[database.ProfileAvatar] {
+		profile.AvatarID = nil
+	}
+	if !privacy[database.ProfileJoined] {
+		profile.JoinedAt = nil
+	}
+	if !privacy[database.ProfileActivity] {
+		profile.PostCount, profile.CommentCount = nil, nil
+	}
+	if !privacy[database.ProfileLastSeen] {
+		profile.LastSeenAt = nil
+	}
+	return profile
+}
+
+// checkAvatar rejects as the avatar of a user anything but an image they uploaded
+// that is attached to nothing.
+func (h *Handler) checkAvatar(userID string, id int) error {
+	attachment, err := h.store.Attachments.Get(id)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+	if err == store.ErrNotFound || attachment.UploaderID.String() != userID || attachment.PostID != nil || attachment.MessageID != nil {
+		return response.InvalidField("avatar_id", "Unknown image")
+	}
+	if !utils.IsImage(attachment.MimeType) {
+		return response.InvalidField("avatar_id", "Avatars must be images")
+	}
+	return nil
+}
+
+// isVisibleAvatar reports whether an attachment is the avatar of its uploader and
+// shown to the viewer.
+func (h *Handler) isVisibleAvatar(viewerID string, attachment database.Attachment) (bool, error) {
+	profile, err := h.store.Users.GetProfile(attachment.UploaderID.String())
+	if err != nil {
+		return false, err
+	}
+	profile = visibleProfile(profile, viewerID)
+	return profile.AvatarID != nil && *profile.AvatarID == attachment.ID, nil
+}
@@ -2,16 +2,23 @@ package api
 
 import (
 	"net/http"
-	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
+	"real-time-forum/backend/storage"
+	"real-time-forum/backend/store"
 	"time"
 )
 
-func NewRouter(db *database.Database, wsHub *Hub) *http.ServeMux {
+func NewRouter(st *store.Store, wsHub *Hub, files storage.Storage) *http.ServeMux {
 	r := http.NewServeMux()
-	h := Handler{db: db, wsHub: wsHub}
-	mw := Middleware{db: db}
+	h := Handler{store: st, wsHub: wsHub, files: files}
+	mw := Middleware{sessions: st.Sessions}
 	th := NewThrottle(3 * time.Second)
 
+	wsHub.On(EventSendMessage, h.HandleSendMessageEvent)
+	wsHub.On(EventTypingStart, h.HandleTypingEvent)
+	wsHub.On(EventTypingStop, h.HandleTypingEvent)
+	wsHub.On(EventMarkRead, h.HandleMarkReadEvent)
+
 	wrap := func(h http.Handler) http.Handler {
 		return mw.LogMiddleware(mw.CorsMiddleware(h))
 	}
@@ -21,13 +28,47 @@ func NewRouter(db *database.Database, wsHub *Hub) *http.ServeMux {
 	}))))
 
 	r.Handle("/api/register", wrap(http.HandlerFunc(h.RegisterUser)))
+	r.Handle("/api/check-username", wrap(http.HandlerFunc(h.CheckUsername)))
+	r.Handle("/api/check-email", wrap(http.HandlerFunc(h.CheckEmail)))
 	r.Handle("/api/login", wrap(http.HandlerFunc(h.LoginUser)))
 	r.Handle("/api/logout", wrap(mw.AuthMiddleware(http.HandlerFunc(h.LogoutUser))))
+	r.Handle("/api/sessions", wrap(mw.AuthMiddleware(http.HandlerFunc(h.GetSessions))))
+	r.Handle("/api/sessions/{id}", wrap(mw.AuthMiddleware(http.HandlerFunc(h.RevokeSession))))
 
 	r.Handle("/api/get-posts", wrap(mw.AuthMiddleware(http.HandlerFunc(h.GetPosts))))
+	r.Handle("/api/categories", wrap(mw.AuthMiddleware(http.HandlerFunc(h.GetCategories))))
 	r.Handle("/api/get-comments", wrap(mw.AuthMiddleware(http.HandlerFunc(h.GetComments))))
 	r.Handle("/api/create-post", wrap(th.Throttle(mw.AuthMiddleware(http.HandlerFunc(h.CreatePost)))))
 	r.Handle("/api/create-comment", wrap(th.Throttle(mw.AuthMiddleware(http.HandlerFunc(h.CreateComment)))))
+	r.Handle("/api/posts/{id}", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPatch:  h.UpdatePost,
+		http.MethodDelete: h.DeletePost,
+	}))))
+	r.Handle("/api/posts/{id}/revisions", wrap(mw.AuthMiddleware(mw.RequirePermission(PermViewRevisions)(http.HandlerFunc(h.GetPostRevisions)))))
+	r.Handle("/api/posts/{id}/lock", wrap(mw.AuthMiddleware(mw.RequirePermission(PermLockThread)(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost:   h.LockPost,
+		http.MethodDelete: h.UnlockPost,
+	})))))
+	r.Handle("/api/comments/{id}", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPatch:  h.UpdateComment,
+		http.MethodDelete: h.DeleteComment,
+	}))))
+	r.Handle("/api/comments/{id}/replies", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:  h.GetReplies,
+		http.MethodPost: th.Throttle(http.HandlerFunc(h.CreateReply)).ServeHTTP,
+	}))))
+	r.Handle("/api/comments/{id}/revisions", wrap(mw.AuthMiddleware(mw.RequirePermission(PermViewRevisions)(http.HandlerFunc(h.GetCommentRevisions)))))
+	r.Handle("/api/attachments", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: h.UploadAttachment,
+	}))))
+	r.Handle("/api/attachments/{id}", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetAttachment,
+	}))))
+	r.Handle("/api/attachments/{id}/thumbnail", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetAttachmentThumbnail,
+	}))))
+	r.Handle("/api/search", wrap(mw.AuthMiddleware(http.HandlerFunc(h.Search))))
+	r.Handle("/api/reactions", wrap(mw.AuthMiddleware(http.HandlerFunc(h.React))))
 
 	r.Handle("/api/get-users", wrap(mw.AuthMiddleware(http.HandlerFunc(h.GetUsers))))
 	r.Handle("/api/messages/{id}", wrap(mw.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -37,12 +78,71 @@ func NewRouter(db *database.Database, wsHub *Hub) *http.ServeMux {
 			th.Throttle(http.HandlerFunc(h.SendMessage)).ServeHTTP(w, r)
 		}
 	}))))
+	r.Handle("/api/messages/{id}/read", wrap(mw.AuthMiddleware(http.HandlerFunc(h.MarkMessagesRead))))
+	r.Handle("/api/messages/{id}/{messageID}", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPatch:  h.UpdateMessage,
+		http.MethodDelete: h.DeleteMessage,
+	}))))
+
+	r.Handle("/api/me", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet:   h.GetAccount,
+		http.MethodPatch: h.UpdateAccount,
+	}))))
+	r.Handle("/api/users/{id}", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetProfile,
+	}))))
+	r.Handle("/api/users/{id}/ban", wrap(mw.AuthMiddleware(mw.RequirePermission(PermSanctionUser)(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost:   h.BanUser,
+		http.MethodDelete: h.LiftSanction,
+	})))))
+	r.Handle("/api/users/{id}/suspend", wrap(mw.AuthMiddleware(mw.RequirePermission(PermSanctionUser)(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost:   h.SuspendUser,
+		http.MethodDelete: h.LiftSanction,
+	})))))
+	r.Handle("/api/users/{id}/role", wrap(mw.AuthMiddleware(mw.RequireRole(RoleAdmin)(byMethod(map[string]http.HandlerFunc{
+		http.MethodPut: h.SetUserRole,
+	})))))
 
-	go wsHub.StartHub()
-	r.Handle("/api/ws", wrap(mw.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.wsHub.HandleWebSocket(w, r, db)
+	r.Handle("/api/reports", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: h.CreateReport,
+		http.MethodGet:  mw.RequirePermission(PermReviewReports)(http.HandlerFunc(h.GetReports)).ServeHTTP,
 	}))))
+	r.Handle("/api/reports/{id}", wrap(mw.AuthMiddleware(mw.RequirePermission(PermReviewReports)(byMethod(map[string]http.HandlerFunc{
+		http.MethodPatch: h.UpdateReport,
+	})))))
+	r.Handle("/api/moderation-log", wrap(mw.AuthMiddleware(mw.RequirePermission(PermViewAuditLog)(http.HandlerFunc(h.GetModerationLog)))))
+
+	r.Handle("/api/notifications", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetNotifications,
+	}))))
+	r.Handle("/api/notifications/unread-count", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetUnreadNotificationCount,
+	}))))
+	r.Handle("/api/notifications/read-all", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: h.MarkAllNotificationsRead,
+	}))))
+	r.Handle("/api/notifications/preferences", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: h.GetNotificationPreferences,
+		http.MethodPut: h.UpdateNotificationPreferences,
+	}))))
+	r.Handle("/api/notifications/{id}/read", wrap(mw.AuthMiddleware(byMethod(map[string]http.HandlerFunc{
+		http.MethodPost: h.MarkNotificationRead,
+	}))))
+
+	r.Handle("/api/ws", wrap(mw.AuthMiddleware(http.HandlerFunc(h.wsHub.HandleWebSocket))))
 
 	r.Handle("/", http.FileServer(http.Dir("../frontend")))
 	return r
 }
+
+// byMethod dispatches a request to the handler registered for its method.
+func byMethod(handlers map[string]http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			response.WriteError(w, response.MethodNotAllowed())
+			return
+		}
+		handler(w, r)
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"real-time-forum/backend/response"
 	"sync"
 	"time"
 )
@@ -22,7 +23,7 @@ func (t *Throttle) Throttle(next http.Handler) http.Handler {
 
 		if lastTime, ok := t.lastTime.Load(ip); ok {
 			if now.Sub(lastTime.(time.Time)) < t.delay {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				response.WriteError(w, response.RateLimited("Too many requests, please wait a moment"))
 				return
 			}
 		}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// typingTimeout is how long a typing indicator stays active without a new typing_start.
+const typingTimeout = 5 * time.Second
+
+// typingKey identifies a user typing to a conversation partner.
+type typingKey struct {
+	senderID   string
+	receiverID string
+}
+
+// typingTracker expires typing indicators whose typing_stop never arrives.
+type typingTracker struct {
+	mu     sync.Mutex
+	timers map[typingKey]*time.Timer
+}
+
+func newTypingTracker() *typingTracker {
+	return &typingTracker{timers: make(map[typingKey]*time.Timer)}
+}
+
+// startTyping notifies the receiver that the sender is typing, or extends an active indicator.
+// Events are sent after mu is released so a busy hub never stalls other typing updates.
+func (h *Hub) startTyping(senderID, receiverID string) {
+	key := typingKey{senderID: senderID, receiverID: receiverID}
+
+	h.typing.mu.Lock()
+	if t, ok := h.typing.timers[key]; ok && t.Stop() {
+		t.Reset(typingTimeout)
+		h.typing.mu.Unlock()
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(typingTimeout, func() {
+		h.expireTyping(key, t)
+	})
+	h.typing.timers[key] = t
+	h.typing.mu.Unlock()
+
+	h.SendEvent(receiverID, EventTypingStart, TypingPayload{UserID: senderID})
+}
+
+// stopTyping clears an active indicator and notifies the receiver.
+func (h *Hub) stopTyping(senderID, receiverID string) {
+	key := typingKey{senderID: senderID, receiverID: receiverID}
+
+	h.typing.mu.Lock()
+	t, ok := h.typing.timers[key]
+	if ok {
+		t.Stop()
+		delete(h.typing.timers, key)
+	}
+	h.typing.mu.Unlock()
+
+	if ok {
+		h.SendEvent(receiverID, EventTypingStop, TypingPayload{UserID: senderID})
+	}
+}
+
+// expireTyping clears an indicator when its timer fires, unless it was replaced meanwhile.
+func (h *Hub) expireTyping(key typingKey, t *time.Timer) {
+	h.typing.mu.Lock()
+	current := h.typing.timers[key] == t
+	if current {
+		delete(h.typing.timers, key)
+	}
+	h.typing.mu.Unlock()
+
+	if current {
+		h.SendEvent(key.receiverID, EventTypingStop, TypingPayload{UserID: key.senderID})
+	}
+}
@@ -3,8 +3,8 @@ package api
 import (
 	"log"
 	"net/http"
-	"real-time-forum/backend/database"
-	"real-time-forum/backend/utils"
+	"real-time-forum/backend/store"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -15,87 +15,254 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-type Client struct {
-	conn *websocket.Conn
-	id   string
-	send chan []byte
-}
-
+// Hub keeps track of the connected clients, indexed by user ID so a user
+// with several open tabs receives events on each of them.
 type Hub struct {
-	clients    map[*Client]bool
-	broadcast  chan []byte
+	clients    map[string]map[*Client]bool
+	broadcast  chan frame
+	direct     chan directMessage
 	register   chan *Client
 	unregister chan *Client
+	revoke     chan string
+	disconnect chan string
+	offline    chan PresencePayload
+	shutdown   chan chan struct{}
+	handlers   map[string]EventHandler
+	typing     *typingTracker
+	presence   *Presence
+	config     ClientConfig
+
+	// presenceChanges collects status changes made while handling a hub event,
+	// they are broadcast once the event is done.
+	presenceChanges []PresencePayload
 }
 
-func NewHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte),
+// directMessage is a frame addressed to every connection of a single user,
+// or to a single connection when client is set.
+type directMessage struct {
+	userID string
+	client *Client
+	frame  frame
+}
+
+func NewHub(users store.UserStore, config ClientConfig) *Hub {
+	h := &Hub{
+		broadcast:  make(chan frame),
+		direct:     make(chan directMessage),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
+		revoke:     make(chan string),
+		disconnect: make(chan string),
+		offline:    make(chan PresencePayload),
+		shutdown:   make(chan chan struct{}),
+		clients:    make(map[string]map[*Client]bool),
+		handlers:   make(map[string]EventHandler),
+		typing:     newTypingTracker(),
+		presence:   newPresence(users),
+		config:     config,
 	}
+	h.On(EventHeartbeat, h.handleHeartbeat)
+	return h
+}
+
+// Presence returns the presence tracker owned by the hub.
+func (h *Hub) Presence() *Presence {
+	return h.presence
+}
+
+// On registers the handler for an inbound event type. It must be called before the hub serves connections.
+func (h *Hub) On(eventType string, handler EventHandler) {
+	h.handlers[eventType] = handler
 }
 
 func (h *Hub) StartHub() {
+	sweep := time.NewTicker(presenceSweepInterval)
+	defer sweep.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
-			h.clients[client] = true
+			if h.clients[client.id] == nil {
+				h.clients[client.id] = make(map[*Client]bool)
+			}
+			h.clients[client.id][client] = true
 			log.Println("Client registered:", client.id)
-			h.broadcast <- []byte(`{"type": "user_connected"}`)
+			if h.presence.connect(client.id) {
+				h.presenceChanges = append(h.presenceChanges, PresencePayload{UserID: client.id, Status: StatusOnline})
+			}
 
 		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				_ = client.conn.Close()
+			if h.removeClient(client) {
 				log.Println("Client unregistered:", client.id)
-				h.broadcast <- []byte(`{"type": "user_disconnected"}`)
 			}
 
-		case message := <-h.broadcast:
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-					_ = client.conn.Close()
-					log.Println("Client forcefully disconnected due to blocked send channel:", client.id)
+		case sessionID := <-h.revoke:
+			h.closeSession(sessionID)
+
+		case userID := <-h.disconnect:
+			h.closeUser(userID)
+
+		case done := <-h.shutdown:
+			h.closeAll()
+			close(done)
+
+		case change := <-h.offline:
+			// The user may have reconnected while last_seen_at was being stored.
+			if len(h.clients[change.UserID]) == 0 {
+				h.presenceChanges = append(h.presenceChanges, change)
+			}
+
+		case now := <-sweep.C:
+			for _, userID := range h.presence.expireIdle(now) {
+				h.presenceChanges = append(h.presenceChanges, PresencePayload{UserID: userID, Status: StatusAway})
+			}
+
+		case f := <-h.broadcast:
+			h.fanout(f)
+
+		case dm := <-h.direct:
+			if dm.client != nil {
+				if h.clients[dm.client.id][dm.client] {
+					h.deliver(dm.client, dm.frame)
 				}
+				break
+			}
+			for client := range h.clients[dm.userID] {
+				h.deliver(client, dm.frame)
 			}
 		}
+
+		h.flushPresence()
 	}
 }
 
-func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, db *database.Database) {
-	ws, err := upgrader.Upgrade(w, r, nil)
+// flushPresence broadcasts the status changes collected while handling a hub event.
+func (h *Hub) flushPresence() {
+	for len(h.presenceChanges) > 0 {
+		changes := h.presenceChanges
+		h.presenceChanges = nil
+		for _, change := range changes {
+			h.fanoutEvent(EventPresence, change)
+		}
+	}
+}
+
+// SendToUser queues an already encoded payload for every connection of the given user only.
+func (h *Hub) SendToUser(userID string, payload []byte) {
+	h.direct <- directMessage{userID: userID, frame: frame{data: payload}}
+}
+
+// SendEvent encodes an event and queues it for every connection of the given user.
+func (h *Hub) SendEvent(userID, eventType string, payload any) {
+	if f, ok := encodeFrame(eventType, payload); ok {
+		h.direct <- directMessage{userID: userID, frame: f}
+	}
+}
+
+// BroadcastEvent encodes an event and queues it for every connected client.
+func (h *Hub) BroadcastEvent(eventType string, payload any) {
+	if f, ok := encodeFrame(eventType, payload); ok {
+		h.broadcast <- f
+	}
+}
+
+// reply encodes an event and queues it for a single connection.
+func (h *Hub) reply(c *Client, eventType string, payload any) {
+	if f, ok := encodeFrame(eventType, payload); ok {
+		h.direct <- directMessage{client: c, frame: f}
+	}
+}
+
+// fanoutEvent encodes an event and delivers it to every client from the hub goroutine.
+func (h *Hub) fanoutEvent(eventType string, payload any) {
+	if f, ok := encodeFrame(eventType, payload); ok {
+		h.fanout(f)
+	}
+}
+
+// encodeFrame encodes an event for delivery, logging encoding failures.
+func encodeFrame(eventType string, payload any) (frame, bool) {
+	data, err := newEvent(eventType, payload)
 	if err != nil {
-		log.Println("Error upgrading to WebSocket:", err)
-		http.Error(w, "Error upgrading to WebSocket", http.StatusInternalServerError)
+		log.Println("Error encoding event:", err)
+		return frame{}, false
+	}
+	f := frame{eventType: eventType, data: data}
+	if p, ok := payload.(PresencePayload); ok {
+		f.subject = p.UserID
+	}
+	return f, true
+}
+
+// fanout delivers a frame to every connected client.
+func (h *Hub) fanout(f frame) {
+	for _, conns := range h.clients {
+		for client := range conns {
+			h.deliver(client, f)
+		}
+	}
+}
+
+// deliver queues a frame on a client, disconnecting the client when it is too
+// slow to keep up even after dropping its non-critical events.
+func (h *Hub) deliver(client *Client, f frame) {
+	if client.enqueue(f) {
 		return
 	}
+	client.close(websocket.CloseTryAgainLater, "send buffer overflow")
+	h.removeClient(client)
+	log.Println("Client forcefully disconnected due to full send buffer:", client.id)
+}
 
-	token, err := utils.GetCookie(r, "session_token")
-	if err != nil {
-		log.Println("Error getting session token:", err)
-		http.Error(w, "Error getting session token", http.StatusInternalServerError)
+// removeClient closes and forgets a client, reporting whether it was registered.
+func (h *Hub) removeClient(client *Client) bool {
+	conns, ok := h.clients[client.id]
+	if !ok || !conns[client] {
+		return false
+	}
+	delete(conns, client)
+	if len(conns) == 0 {
+		delete(h.clients, client.id)
+	}
+	client.close(0, "")
+
+	if h.presence.disconnect(client.id) {
+		go h.goOffline(client.id, time.Now())
+	}
+	return true
+}
+
+// goOffline stores when a user went offline away from the hub goroutine and hands
+// the resulting status change back to the hub to broadcast.
+func (h *Hub) goOffline(userID string, lastSeen time.Time) {
+	h.offline <- h.presence.recordLastSeen(userID, lastSeen)
+}
+
+// handleHeartbeat keeps a user online while their client reports activity.
+func (h *Hub) handleHeartbeat(c *Client, e Event) error {
+	var payload HeartbeatPayload
+	if err := decodePayload(e, &payload); err != nil {
+		return err
+	}
+	if payload.Active && h.presence.touch(c.id) {
+		h.BroadcastEvent(EventPresence, PresencePayload{UserID: c.id, Status: StatusOnline})
+	}
+	return nil
+}
+
+func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	principal, ok := requirePrincipal(w, r)
+	if !ok {
 		return
 	}
 
-	userID, err := utils.GetUserID(db, token)
+	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("Error getting user ID:", err)
-		http.Error(w, "Error getting user ID", http.StatusInternalServerError)
+		log.Println("Error upgrading to WebSocket:", err)
 		return
 	}
 
-	client := &Client{
-		conn: ws,
-		id:   userID,
-		send: make(chan []byte, 256),
-	}
+	client := newClient(ws, principal.UserID, principal.SessionID, h.config)
 
 	h.register <- client
 	go client.writePump()
@@ -103,48 +270,73 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, db *databa
 		h.unregister <- client
 	}()
 
-	for {
-		_, message, err := ws.ReadMessage()
-		if err != nil {
-			log.Println("Error reading message:", err)
-			break
-		}
-		h.broadcast <- []byte(`{"type": "message", "content": "` + string(message) + `"}`)
-	}
+	client.readPump(func(message []byte) {
+		h.dispatch(client, message)
+	})
 }
 
-func (c *Client) writePump() {
-	for msg := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-			log.Println("WebSocket write error for client:", c.id, err)
-			break
+// dispatch decodes an inbound frame and runs the handler registered for its type,
+// answering with an error frame when the event is rejected.
+func (h *Hub) dispatch(c *Client, data []byte) {
+	e, err := decodeEvent(data)
+	if err == nil {
+		handler, ok := h.handlers[e.Type]
+		if !ok {
+			err = &ProtocolError{Code: ErrCodeUnknownEvent, Message: "Unknown event type: " + e.Type}
+		} else {
+			err = handler(c, e)
 		}
 	}
-	c.conn.Close()
+	if err != nil {
+		log.Println("Error handling event from client:", c.id, err)
+		h.reply(c, EventError, errorPayload(err, e.ID))
+	}
 }
 
+// Shutdown disconnects every client and returns once the hub has closed them.
 func (h *Hub) Shutdown() {
-	for client := range h.clients {
-		_ = client.conn.Close()
-		h.unregister <- client
-	}
+	done := make(chan struct{})
+	h.shutdown <- done
+	<-done
 	log.Println("WebSocket hub shutdown completed.")
 }
 
-func (h *Hub) logout(userID string) {
-	for client := range h.clients {
-		if client.id == userID {
-			_ = client.conn.Close()
-			h.unregister <- client
-			h.broadcast <- []byte(`{"type": "user_disconnected"}`)
+// CloseSession disconnects every client opened with the given session.
+func (h *Hub) CloseSession(sessionID string) {
+	h.revoke <- sessionID
+}
+
+// DisconnectUser closes every connection of a user, whose sessions were revoked.
+func (h *Hub) DisconnectUser(userID string) {
+	h.disconnect <- userID
+}
+
+// closeUser runs on the hub goroutine on behalf of DisconnectUser.
+func (h *Hub) closeUser(userID string) {
+	for client := range h.clients[userID] {
+		client.close(websocket.ClosePolicyViolation, "account suspended")
+		h.removeClient(client)
+	}
+}
+
+// closeAll runs on the hub goroutine on behalf of Shutdown.
+func (h *Hub) closeAll() {
+	for _, conns := range h.clients {
+		for client := range conns {
+			client.close(websocket.CloseGoingAway, "server shutting down")
+			h.removeClient(client)
 		}
 	}
 }
 
-func (h *Hub) login(userID string) {
-	for client := range h.clients {
-		if client.id == userID {
-			h.broadcast <- []byte(`{"type": "user_connected"}`)
+// closeSession runs on the hub goroutine on behalf of CloseSession.
+func (h *Hub) closeSession(sessionID string) {
+	for _, conns := range h.clients {
+		for client := range conns {
+			if client.sessionID == sessionID {
+				client.close(websocket.ClosePolicyViolation, "session revoked")
+				h.removeClient(client)
+			}
 		}
 	}
 }
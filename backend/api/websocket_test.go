@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestShutdownClosesClients(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp(t, "alice")
+	bob := s.signUp(t, "bob")
+	conns := []*websocket.Conn{s.dial(t, alice), s.dial(t, alice), s.dial(t, bob)}
+
+	s.hub.Shutdown()
+
+	for i, conn := range conns {
+		if code := expectClose(t, conn); code != websocket.CloseGoingAway {
+			t.Errorf("connection %d closed with %d, want %d", i, code, websocket.CloseGoingAway)
+		}
+	}
+	if status := s.hub.Presence().Status(alice.id); status != StatusOffline {
+		t.Errorf("status after shutdown = %s, want %s", status, StatusOffline)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,8 +14,9 @@ type Database struct {
 	DB *sql.DB
 }
 
-// NewDatabase initializes a new database connection and executes the schema.
-func NewDatabase(dbpath string, schemaPath string) *Database {
+// NewDatabase initializes a new database connection. The schema is brought up
+// to date separately with MigrateUp.
+func NewDatabase(dbpath string) *Database {
 	db, err := sql.Open("sqlite3", dbpath)
 	if err != nil {
 		log.Fatal("\033[31mError:\033[0m" + " Database connection failed - " + err.Error())
@@ -29,13 +29,10 @@ func NewDatabase(dbpath string, schemaPath string) *Database {
 		log.Fatal("\033[31mError:\033[0m" + " Database connection failed - " + err.Error())
 	}
 
-	schema, err := readSchema(schemaPath)
-	if err != nil {
-		log.Fatal("\033[31mError:\033[0m" + " Failed to read schema - " + err.Error())
-	}
-
-	if _, err := db.ExecContext(ctx, schema); err != nil {
-		log.Fatal("\033[31mError:\033[0m" + " Schema execution failed - " + err.Error())
+	// search relies on FTS5, which go-sqlite3 only compiles in with the sqlite_fts5 build tag
+	var fts5 bool
+	if err := db.QueryRowContext(ctx, `SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil || !fts5 {
+		log.Fatal("\033[31mError:\033[0m" + " SQLite lacks FTS5 support - build with -tags sqlite_fts5, as make build and make run do")
 	}
 
 	log.Println("\033[32mSuccess:\033[0m" + " Database connection successful")
@@ -43,15 +40,6 @@ func NewDatabase(dbpath string, schemaPath string) *Database {
 	return &Database{DB: db}
 }
 
-// readSchema reads the SQL schema from a file.
-func readSchema(schemaPath string) (string, error) {
-	data, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return "", err
-	}
-	return string(data), nil
-}
-
 // Close closes the database connection.
 func (db *Database) Close() error {
 	if err := db.DB.Close(); err != nil {
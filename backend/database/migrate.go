@@ -0,0 +1,306 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the numbered migrations, each as NNNN_name.up.sql and NNNN_name.down.sql.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationChecks refuse data a migration cannot convert, by version. Each check
+// runs in the migration's transaction, before its up script.
+var migrationChecks = map[int]func(tx *sql.Tx) error{
+	10: checkCaseConflicts,
+}
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the content of the up migration, to detect edits after it was applied.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationStatus is a migration along with when it was applied, if it was.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrations returns the embedded migrations, ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+		}
+		prefix, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version", name)
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d: %s and %s share a version", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ensureMigrationTable creates the table recording applied migrations.
+func (db *Database) ensureMigrationTable() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`
+	_, err := db.DB.Exec(query)
+	return err
+}
+
+// applied returns the applied migrations by version, after checking that each one
+// is still embedded unchanged.
+func (db *Database) applied(migrations []Migration) (map[int]appliedMigration, error) {
+	if err := db.ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.DB.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for version, a := range applied {
+		m, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is applied but unknown to this build", version)
+		}
+		if m.Checksum() != a.checksum {
+			return nil, fmt.Errorf("migration %d (%s) was edited after it was applied", m.Version, m.Name)
+		}
+	}
+	return applied, nil
+}
+
+// MigrationStatus lists every migration and whether it has been applied.
+func (db *Database) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.applied(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i].Migration = m
+		if a, ok := applied[m.Version]; ok {
+			appliedAt := a.appliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// MigrateUp applies every pending migration in order and returns those it applied.
+// Each migration runs in its own transaction.
+func (db *Database) MigrateUp() ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.applied(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := db.inTx(func(tx *sql.Tx) error {
+			if check, ok := migrationChecks[m.Version]; ok {
+				if err := check(tx); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.Exec(m.Up); err != nil {
+				return err
+			}
+			query := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+			_, err := tx.Exec(query, m.Version, m.Name, m.Checksum(), time.Now())
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the n most recently applied migrations and returns those it reverted.
+// Each migration runs in its own transaction.
+func (db *Database) MigrateDown(n int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.applied(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < n; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %d (%s) cannot be reverted", m.Version, m.Name)
+		}
+		err := db.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(m.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// inTx runs fn in a transaction, committing only if it succeeds.
+func (db *Database) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkCaseConflicts lists the accounts whose usernames or emails only differ in
+// case, which the case-insensitive unique indexes of migration 10 cannot hold.
+// They have to be renamed or merged by hand before the migration can run.
+func checkCaseConflicts(tx *sql.Tx) error {
+	var conflicts []string
+	for _, c := range []struct{ column, key string }{
+		{"username", "lower(username)"},
+		{"email", "lower(trim(email))"},
+	} {
+		query := `
+			SELECT group_concat(id || ' (' || ` + c.column + ` || ')', ', ')
+			FROM (SELECT id, ` + c.column + `, ` + c.key + ` AS k FROM user ORDER BY rowid)
+			GROUP BY k
+			HAVING count(*) > 1
+			ORDER BY k
+		`
+		rows, err := tx.Query(query)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var users string
+			if err := rows.Scan(&users); err != nil {
+				rows.Close()
+				return err
+			}
+			conflicts = append(conflicts, c.column+" "+users)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("accounts differ only in case, rename or merge them first:\n  %s", strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	db := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func migrateUp(t *testing.T, db *Database) []Migration {
+	t.Helper()
+	applied, err := db.MigrateUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return applied
+}
+
+func versions(migrations []Migration) []int {
+	v := make([]int, len(migrations))
+	for i, m := range migrations {
+		v[i] = m.Version
+	}
+	return v
+}
+
+func TestMigrateUpAppliesPendingOnce(t *testing.T) {
+	db := newTestDatabase(t)
+	all, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if applied := migrateUp(t, db); len(applied) != len(all) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(all))
+	}
+	if applied := migrateUp(t, db); len(applied) != 0 {
+		t.Errorf("second run applied %v, want none", versions(applied))
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	db := newTestDatabase(t)
+	all := migrateUp(t, db)
+	last := all[len(all)-1].Version
+
+	reverted, err := db.MigrateDown(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(reverted); len(got) != 2 || got[0] != last || got[1] != last-1 {
+		t.Fatalf("reverted %v, want [%d %d]", got, last, last-1)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if pending := s.AppliedAt == nil; pending != (s.Version >= last-1) {
+			t.Errorf("migration %d pending = %v after reverting two", s.Version, pending)
+		}
+	}
+
+	if got := versions(migrateUp(t, db)); len(got) != 2 || got[0] != last-1 || got[1] != last {
+		t.Errorf("reapplied %v, want [%d %d]", got, last-1, last)
+	}
+}
+
+func TestMigrateDownPastFirst(t *testing.T) {
+	db := newTestDatabase(t)
+	all := migrateUp(t, db)
+
+	reverted, err := db.MigrateDown(len(all) + 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(all) {
+		t.Errorf("reverted %d migrations, want %d", len(reverted), len(all))
+	}
+}
+
+func TestMigrationChecksumMismatch(t *testing.T) {
+	db := newTestDatabase(t)
+	migrateUp(t, db)
+
+	if _, err := db.DB.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 3`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.MigrationStatus(); err == nil || !strings.Contains(err.Error(), "migration 3") {
+		t.Errorf("MigrationStatus error = %v, want migration 3 reported as edited", err)
+	}
+	if _, err := db.MigrateUp(); err == nil {
+		t.Error("MigrateUp succeeded with an edited migration")
+	}
+	if _, err := db.MigrateDown(1); err == nil {
+		t.Error("MigrateDown succeeded with an edited migration")
+	}
+}
+
+func TestMigrationUnknownVersion(t *testing.T) {
+	db := newTestDatabase(t)
+	migrateUp(t, db)
+
+	query := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (9999, 'future', '', CURRENT_TIMESTAMP)`
+	if _, err := db.DB.Exec(query); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.MigrateUp(); err == nil || !strings.Contains(err.Error(), "9999") {
+		t.Errorf("MigrateUp error = %v, want migration 9999 reported as unknown", err)
+	}
+}
+
+func TestMigrationCaseConflicts(t *testing.T) {
+	db := newTestDatabase(t)
+	// back to before the case-insensitive unique indexes
+	since := 0
+	for _, m := range migrateUp(t, db) {
+		if m.Version >= 10 {
+			since++
+		}
+	}
+	if _, err := db.MigrateDown(since); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `INSERT INTO user (id, username, email, password, first_name, last_name, age, gender) VALUES (?, ?, ?, '', 'Test', 'User', 30, 'other')`
+	for _, u := range [][]string{
+		{"id-1", "Bob", "bob@example.com"},
+		{"id-2", "bob", "BOB@example.com"},
+		{"id-3", "carol", "carol@example.com"},
+	} {
+		if _, err := db.DB.Exec(query, u[0], u[1], u[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	applied, err := db.MigrateUp()
+	if len(applied) != 0 {
+		t.Errorf("applied %v, want migration 10 to stop the run", versions(applied))
+	}
+	if err == nil {
+		t.Fatal("MigrateUp succeeded with case-variant accounts")
+	}
+	for _, want := range []string{"username id-1 (Bob), id-2 (bob)", "email id-1 (bob@example.com), id-2 (BOB@example.com)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "carol") {
+		t.Errorf("error %q reports an account without conflict", err)
+	}
+
+	if _, err := db.DB.Exec(`UPDATE user SET username = 'bobby', email = 'bobby@example.com' WHERE id = 'id-2'`); err != nil {
+		t.Fatal(err)
+	}
+	migrateUp(t, db)
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS message;
+DROP TABLE IF EXISTS comment;
+DROP TABLE IF EXISTS post;
+DROP TABLE IF EXISTS user;
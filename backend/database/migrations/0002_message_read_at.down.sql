@@ -0,0 +1 @@
+ALTER TABLE message DROP COLUMN read_at;
@@ -0,0 +1 @@
+ALTER TABLE user ADD COLUMN last_seen_at TIMESTAMP;
@@ -0,0 +1,14 @@
+-- Session Table --
+CREATE TABLE session (
+    id TEXT PRIMARY KEY UNIQUE NOT NULL,
+    user_id TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_session_user ON session(user_id);
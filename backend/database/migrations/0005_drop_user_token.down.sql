@@ -0,0 +1,18 @@
+CREATE TABLE user_old (
+    id TEXT PRIMARY KEY UNIQUE NOT NULL,
+    username TEXT UNIQUE NOT NULL,
+    email TEXT UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    age INTEGER CHECK(age > 0) NOT NULL,
+    token BLOB UNIQUE,
+    gender TEXT CHECK(gender IN ('male', 'female', 'other')) NOT NULL,
+    last_seen_at TIMESTAMP
+);
+
+INSERT INTO user_old (id, username, email, password, first_name, last_name, age, gender, last_seen_at)
+SELECT id, username, email, password, first_name, last_name, age, gender, last_seen_at FROM user;
+
+DROP TABLE user;
+ALTER TABLE user_old RENAME TO user;
@@ -0,0 +1,18 @@
+-- the token column is UNIQUE, which SQLite cannot drop in place, so the table is rebuilt
+CREATE TABLE user_new (
+    id TEXT PRIMARY KEY UNIQUE NOT NULL,
+    username TEXT UNIQUE NOT NULL,
+    email TEXT UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    age INTEGER CHECK(age > 0) NOT NULL,
+    gender TEXT CHECK(gender IN ('male', 'female', 'other')) NOT NULL,
+    last_seen_at TIMESTAMP
+);
+
+INSERT INTO user_new (id, username, email, password, first_name, last_name, age, gender, last_seen_at)
+SELECT id, username, email, password, first_name, last_name, age, gender, last_seen_at FROM user;
+
+DROP TABLE user;
+ALTER TABLE user_new RENAME TO user;
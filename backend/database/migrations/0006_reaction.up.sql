@@ -0,0 +1,12 @@
+-- Reaction Table --
+CREATE TABLE reaction (
+    user_id TEXT NOT NULL,
+    target_type TEXT CHECK(target_type IN ('post', 'comment')) NOT NULL,
+    target_id INTEGER NOT NULL,
+    kind TEXT CHECK(kind IN ('like', 'dislike')) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY(user_id, target_type, target_id),
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_reaction_target ON reaction(target_type, target_id);
@@ -0,0 +1,12 @@
+ALTER TABLE post ADD COLUMN category TEXT;
+
+UPDATE post SET category = (
+    SELECT group_concat(c.name, ', ')
+    FROM post_category pc
+    JOIN category c ON c.id = pc.category_id
+    WHERE pc.post_id = post.id
+);
+
+DROP INDEX IF EXISTS idx_post_category_category;
+DROP TABLE IF EXISTS post_category;
+DROP TABLE IF EXISTS category;
@@ -0,0 +1,36 @@
+-- Category Table --
+CREATE TABLE category (
+    id INTEGER PRIMARY KEY UNIQUE NOT NULL,
+    slug TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+-- Post Category Table --
+CREATE TABLE post_category (
+    post_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    PRIMARY KEY(post_id, category_id),
+    FOREIGN KEY(post_id) REFERENCES post(id),
+    FOREIGN KEY(category_id) REFERENCES category(id)
+);
+
+CREATE INDEX idx_post_category_category ON post_category(category_id);
+
+INSERT INTO category (slug, name, description, position) VALUES
+    ('general', 'General', 'Anything that does not fit elsewhere', 1),
+    ('programming', 'Programming', 'Code, languages and tools', 2),
+    ('help', 'Help', 'Questions and answers', 3),
+    ('feedback', 'Feedback', 'Suggestions about the forum', 4),
+    ('off-topic', 'Off-topic', 'Everything else', 5);
+
+-- free text categories are kept only when they name an existing category
+INSERT INTO post_category (post_id, category_id)
+SELECT p.id, COALESCE(
+    (SELECT c.id FROM category c WHERE c.slug = lower(trim(p.category))),
+    (SELECT c.id FROM category c WHERE c.slug = 'general')
+)
+FROM post p;
+
+ALTER TABLE post DROP COLUMN category;
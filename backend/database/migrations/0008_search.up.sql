@@ -0,0 +1,44 @@
+-- Full text indexes, kept in sync with their tables by triggers --
+CREATE VIRTUAL TABLE post_fts USING fts5(
+    title,
+    content,
+    content='post',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+CREATE VIRTUAL TABLE comment_fts USING fts5(
+    content,
+    content='comment',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+INSERT INTO post_fts(post_fts) VALUES ('rebuild');
+INSERT INTO comment_fts(comment_fts) VALUES ('rebuild');
+
+CREATE TRIGGER post_fts_insert AFTER INSERT ON post BEGIN
+    INSERT INTO post_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER post_fts_delete AFTER DELETE ON post BEGIN
+    INSERT INTO post_fts(post_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER post_fts_update AFTER UPDATE OF title, content ON post BEGIN
+    INSERT INTO post_fts(post_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO post_fts(rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER comment_fts_insert AFTER INSERT ON comment BEGIN
+    INSERT INTO comment_fts(rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER comment_fts_delete AFTER DELETE ON comment BEGIN
+    INSERT INTO comment_fts(comment_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER comment_fts_update AFTER UPDATE OF content ON comment BEGIN
+    INSERT INTO comment_fts(comment_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO comment_fts(rowid, content) VALUES (new.id, new.content);
+END;
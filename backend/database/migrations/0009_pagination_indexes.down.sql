@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_message_conversation;
+DROP INDEX IF EXISTS idx_comment_post_created;
+DROP INDEX IF EXISTS idx_post_created;
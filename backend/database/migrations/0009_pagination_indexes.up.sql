@@ -0,0 +1,4 @@
+-- Indexes backing keyset pagination on (created_at, id) --
+CREATE INDEX idx_post_created ON post(created_at, id);
+CREATE INDEX idx_comment_post_created ON comment(post_id, created_at, id);
+CREATE INDEX idx_message_conversation ON message(sender_id, receiver_id, created_at, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_email_nocase;
+DROP INDEX IF EXISTS idx_user_username_nocase;
@@ -0,0 +1,4 @@
+-- Usernames and emails are unique regardless of case; emails are stored lowercased --
+UPDATE user SET email = lower(trim(email));
+CREATE UNIQUE INDEX idx_user_username_nocase ON user(username COLLATE NOCASE);
+CREATE UNIQUE INDEX idx_user_email_nocase ON user(email COLLATE NOCASE);
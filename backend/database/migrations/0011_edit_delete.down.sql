@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_revision_target;
+DROP TABLE IF EXISTS revision;
+
+-- Soft deleted rows cannot be told apart once the columns are gone --
+DELETE FROM reaction WHERE (target_type = 'post' AND target_id IN (SELECT id FROM post WHERE deleted_at IS NOT NULL))
+    OR (target_type = 'comment' AND target_id IN (SELECT id FROM comment WHERE deleted_at IS NOT NULL));
+DELETE FROM post_category WHERE post_id IN (SELECT id FROM post WHERE deleted_at IS NOT NULL);
+DELETE FROM comment WHERE deleted_at IS NOT NULL;
+DELETE FROM post WHERE deleted_at IS NOT NULL;
+DELETE FROM message WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE message DROP COLUMN deleted_at;
+ALTER TABLE message DROP COLUMN edited_at;
+ALTER TABLE comment DROP COLUMN deleted_at;
+ALTER TABLE comment DROP COLUMN edited_at;
+ALTER TABLE post DROP COLUMN deleted_at;
+ALTER TABLE post DROP COLUMN edited_at;
@@ -0,0 +1,21 @@
+-- Edit markers and soft deletes --
+ALTER TABLE post ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE post ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE comment ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE comment ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE message ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE message ADD COLUMN deleted_at TIMESTAMP;
+
+-- Revision Table: the version of a post or comment replaced by each edit --
+CREATE TABLE revision (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target_type TEXT CHECK(target_type IN ('post', 'comment')) NOT NULL,
+    target_id INTEGER NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    editor_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(editor_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_revision_target ON revision(target_type, target_id, created_at);
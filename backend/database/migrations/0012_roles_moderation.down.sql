@@ -0,0 +1,4 @@
+ALTER TABLE post DROP COLUMN locked_at;
+ALTER TABLE user DROP COLUMN suspended_until;
+ALTER TABLE user DROP COLUMN banned_at;
+ALTER TABLE user DROP COLUMN role;
@@ -0,0 +1,9 @@
+-- Roles: every user is a member until promoted --
+ALTER TABLE user ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK(role IN ('member', 'moderator', 'admin'));
+
+-- Sanctions: a ban has no end, a suspension lasts until suspended_until --
+ALTER TABLE user ADD COLUMN banned_at TIMESTAMP;
+ALTER TABLE user ADD COLUMN suspended_until TIMESTAMP;
+
+-- Locked threads take no new comments --
+ALTER TABLE post ADD COLUMN locked_at TIMESTAMP;
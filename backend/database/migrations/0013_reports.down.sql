@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_moderation_log_created;
+DROP TABLE IF EXISTS moderation_log;
+DROP INDEX IF EXISTS idx_report_queue;
+DROP INDEX IF EXISTS idx_report_pending;
+DROP TABLE IF EXISTS report;
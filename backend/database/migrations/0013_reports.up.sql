@@ -0,0 +1,34 @@
+-- Report Table: posts, comments and messages flagged by members for moderators --
+CREATE TABLE report (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id TEXT NOT NULL,
+    target_type TEXT CHECK(target_type IN ('post', 'comment', 'message')) NOT NULL,
+    target_id INTEGER NOT NULL,
+    reason TEXT CHECK(reason IN ('spam', 'harassment', 'hate', 'inappropriate', 'other')) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    status TEXT CHECK(status IN ('open', 'reviewing', 'actioned', 'dismissed')) NOT NULL DEFAULT 'open',
+    reviewer_id TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(reporter_id) REFERENCES user(id),
+    FOREIGN KEY(reviewer_id) REFERENCES user(id)
+);
+
+-- A member has at most one pending report per target --
+CREATE UNIQUE INDEX idx_report_pending ON report(reporter_id, target_type, target_id) WHERE status IN ('open', 'reviewing');
+CREATE INDEX idx_report_queue ON report(status, created_at, id);
+
+-- Moderation Log Table: the audit trail of moderator actions --
+CREATE TABLE moderation_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    moderator_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY(moderator_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_moderation_log_created ON moderation_log(created_at, id);
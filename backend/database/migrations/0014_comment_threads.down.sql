@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comment_parent_created;
+
+ALTER TABLE comment DROP COLUMN depth;
+ALTER TABLE comment DROP COLUMN parent_id;
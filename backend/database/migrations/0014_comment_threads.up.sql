@@ -0,0 +1,5 @@
+-- Threaded replies: the comment a reply answers and how deep it is nested --
+ALTER TABLE comment ADD COLUMN parent_id INTEGER REFERENCES comment(id);
+ALTER TABLE comment ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_comment_parent_created ON comment(parent_id, created_at, id);
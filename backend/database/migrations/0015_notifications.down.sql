@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS notification_preference;
+DROP INDEX IF EXISTS idx_notification_unread;
+DROP INDEX IF EXISTS idx_notification_inbox;
+DROP TABLE IF EXISTS notification;
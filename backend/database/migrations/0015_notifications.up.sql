@@ -0,0 +1,26 @@
+-- Notification Table: the inbox of activity concerning a user --
+CREATE TABLE notification (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    type TEXT CHECK(type IN ('comment', 'reply', 'reaction', 'mention', 'message')) NOT NULL,
+    post_id INTEGER,
+    comment_id INTEGER,
+    message_id INTEGER,
+    created_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES user(id),
+    FOREIGN KEY(actor_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_notification_inbox ON notification(user_id, created_at, id);
+CREATE INDEX idx_notification_unread ON notification(user_id) WHERE read_at IS NULL;
+
+-- Notification Preference Table: the types a user opted out of, or back into --
+CREATE TABLE notification_preference (
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_mention_user;
+DROP TABLE IF EXISTS mention;
@@ -0,0 +1,12 @@
+-- Mention Table: the users referenced with @username in posts, comments and messages --
+CREATE TABLE mention (
+    target_type TEXT CHECK(target_type IN ('post', 'comment', 'message')) NOT NULL,
+    target_id INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    span_start INTEGER NOT NULL,
+    span_end INTEGER NOT NULL,
+    PRIMARY KEY (target_type, target_id, span_start),
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
+
+CREATE INDEX idx_mention_user ON mention(user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE comment DROP COLUMN content_html;
+ALTER TABLE post DROP COLUMN content_html;
@@ -0,0 +1,4 @@
+-- Rendered HTML of post and comment bodies, stored alongside their Markdown source --
+-- Rows written before this migration are rendered at startup --
+ALTER TABLE post ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comment ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_attachment_message;
+DROP INDEX IF EXISTS idx_attachment_post;
+DROP INDEX IF EXISTS idx_attachment_uploader;
+DROP TABLE IF EXISTS attachment;
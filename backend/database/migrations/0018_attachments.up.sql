@@ -0,0 +1,24 @@
+-- Attachment Table: files uploaded to posts and private messages --
+-- An attachment belongs to its uploader alone until it is attached to a post or message --
+CREATE TABLE attachment (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uploader_id TEXT NOT NULL,
+    post_id INTEGER,
+    message_id INTEGER,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    filename TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP NOT NULL,
+    CHECK(post_id IS NULL OR message_id IS NULL),
+    FOREIGN KEY(uploader_id) REFERENCES user(id),
+    FOREIGN KEY(post_id) REFERENCES post(id),
+    FOREIGN KEY(message_id) REFERENCES message(id)
+);
+
+CREATE INDEX idx_attachment_uploader ON attachment(uploader_id);
+CREATE INDEX idx_attachment_post ON attachment(post_id) WHERE post_id IS NOT NULL;
+CREATE INDEX idx_attachment_message ON attachment(message_id) WHERE message_id IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS profile_privacy;
+ALTER TABLE user DROP COLUMN created_at;
+ALTER TABLE user DROP COLUMN avatar_id;
+ALTER TABLE user DROP COLUMN bio;
+ALTER TABLE user DROP COLUMN display_name;
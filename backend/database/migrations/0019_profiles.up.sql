@@ -0,0 +1,25 @@
+-- Profiles: what users tell about themselves besides their username --
+-- avatar_id is an image attachment of the user --
+ALTER TABLE user ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE user ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+ALTER TABLE user ADD COLUMN avatar_id INTEGER;
+
+-- Join dates: users registered before this migration joined with their first recorded activity, if any --
+ALTER TABLE user ADD COLUMN created_at TIMESTAMP;
+UPDATE user SET created_at = (
+    SELECT MIN(at) FROM (
+        SELECT MIN(created_at) AS at FROM session WHERE user_id = user.id
+        UNION ALL SELECT MIN(created_at) FROM post WHERE user_id = user.id
+        UNION ALL SELECT MIN(created_at) FROM comment WHERE user_id = user.id
+        UNION ALL SELECT MIN(created_at) FROM message WHERE sender_id = user.id
+    )
+);
+
+-- Profile Privacy Table: the profile fields a user hid from others, or showed again --
+CREATE TABLE profile_privacy (
+    user_id TEXT NOT NULL,
+    field TEXT NOT NULL,
+    visible BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, field),
+    FOREIGN KEY(user_id) REFERENCES user(id)
+);
@@ -0,0 +1,16 @@
+-- Keyset pagination compares julianday(created_at) since timestamps carry their zone offset, --
+-- so the indexes backing it are rebuilt on that expression --
+DROP INDEX IF EXISTS idx_post_created;
+DROP INDEX IF EXISTS idx_comment_post_created;
+DROP INDEX IF EXISTS idx_comment_parent_created;
+DROP INDEX IF EXISTS idx_message_conversation;
+DROP INDEX IF EXISTS idx_report_queue;
+DROP INDEX IF EXISTS idx_moderation_log_created;
+DROP INDEX IF EXISTS idx_notification_inbox;
+CREATE INDEX idx_post_created ON post(julianday(created_at), id);
+CREATE INDEX idx_comment_post_created ON comment(post_id, julianday(created_at), id);
+CREATE INDEX idx_comment_parent_created ON comment(parent_id, julianday(created_at), id);
+CREATE INDEX idx_message_conversation ON message(sender_id, receiver_id, julianday(created_at), id);
+CREATE INDEX idx_report_queue ON report(status, julianday(created_at), id);
+CREATE INDEX idx_moderation_log_created ON moderation_log(julianday(created_at), id);
+CREATE INDEX idx_notification_inbox ON notification(user_id, julianday(created_at), id);
@@ -8,43 +8,303 @@ import (
 
 // User represents a user in the system.
 type User struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	Username  string    `db:"username" json:"username"`
-	Email     string    `db:"email" json:"email"`
-	Password  string    `db:"password" json:"password"`
-	FirstName string    `db:"first_name" json:"first_name"`
-	LastName  string    `db:"last_name" json:"last_name"`
-	Age       int       `db:"age" json:"age"`
-	Token     []byte    `db:"token" json:"token"`
-	Gender    string    `db:"gender" json:"gender"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	Online    bool      `json:"online"`
+	ID         uuid.UUID  `db:"id" json:"id"`
+	Username   string     `db:"username" json:"username"`
+	Email      string     `db:"email" json:"email"`
+	Password   string     `db:"password" json:"password"`
+	FirstName  string     `db:"first_name" json:"first_name"`
+	LastName   string     `db:"last_name" json:"last_name"`
+	Age        int        `db:"age" json:"age"`
+	Gender     string     `db:"gender" json:"gender"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt *time.Time `db:"last_seen_at" json:"last_seen_at"`
+	Online     bool       `json:"online"`
+	Status     string     `json:"status"`
+	Unread     int        `json:"unread"`
+
+	DisplayName string `db:"display_name" json:"display_name"`
+	Bio         string `db:"bio" json:"bio"`
+	AvatarID    *int   `db:"avatar_id" json:"avatar_id"`
+
+	Role           string     `db:"role" json:"role,omitempty"`
+	BannedAt       *time.Time `db:"banned_at" json:"banned_at,omitempty"`
+	SuspendedUntil *time.Time `db:"suspended_until" json:"suspended_until,omitempty"`
+}
+
+// Roles a user can have, from least to most privileged.
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Profile is what other users can learn about a user. Fields the user hides from
+// the viewer are left out, JoinedAt is unknown for some users registered before
+// join dates were kept, and Privacy is only shown to the user themselves.
+type Profile struct {
+	ID           uuid.UUID       `json:"id"`
+	Username     string          `json:"username"`
+	DisplayName  string          `json:"display_name,omitempty"`
+	Bio          string          `json:"bio,omitempty"`
+	AvatarID     *int            `json:"avatar_id,omitempty"`
+	JoinedAt     *time.Time      `json:"joined_at,omitempty"`
+	PostCount    *int            `json:"post_count,omitempty"`
+	CommentCount *int            `json:"comment_count,omitempty"`
+	LastSeenAt   *time.Time      `json:"last_seen_at,omitempty"`
+	Status       string          `json:"status"`
+	Privacy      map[string]bool `json:"privacy,omitempty"`
+}
+
+// Profile fields a user can hide from other users. ProfileActivity covers the
+// post and comment counts.
+const (
+	ProfileDisplayName = "display_name"
+	ProfileBio         = "bio"
+	ProfileAvatar      = "avatar"
+	ProfileJoined      = "joined"
+	ProfileActivity    = "activity"
+	ProfileLastSeen    = "last_seen"
+)
+
+// ProfileFields lists every profile field a user can hide.
+var ProfileFields = []string{ProfileDisplayName, ProfileBio, ProfileAvatar, ProfileJoined, ProfileActivity, ProfileLastSeen}
+
+// Sanctioned reports whether the user is banned or suspended past now.
+func (u User) Sanctioned(now time.Time) bool {
+	return u.BannedAt != nil || (u.SuspendedUntil != nil && u.SuspendedUntil.After(now))
+}
+
+// Session represents a logged in device of a user.
+type Session struct {
+	ID         string    `db:"id" json:"id"`
+	UserID     uuid.UUID `db:"user_id" json:"user_id"`
+	Username   string    `db:"username" json:"-"`
+	Role       string    `db:"role" json:"-"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	LastUsedAt time.Time `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+	UserAgent  string    `db:"user_agent" json:"user_agent"`
+	IP         string    `db:"ip" json:"ip"`
+	Current    bool      `json:"current"`
 }
 
 // Post represents a post created by a user.
 type Post struct {
-	ID        int       `db:"id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	Content   string    `db:"content" json:"content"`
-	Category  string    `db:"category" json:"category"`
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID          int          `db:"id" json:"id"`
+	Title       string       `db:"title" json:"title"`
+	Content     string       `db:"content" json:"content"`
+	ContentHTML string       `db:"content_html" json:"content_html"`
+	Categories  []string     `json:"categories"`
+	UserID      uuid.UUID    `db:"user_id" json:"user_id"`
+	CreatedAt   time.Time    `db:"created_at" json:"created_at"`
+	EditedAt    *time.Time   `db:"edited_at" json:"edited_at"`
+	LockedAt    *time.Time   `db:"locked_at" json:"locked_at"`
+	Mentions    []Mention    `json:"mentions"`
+	Attachments []Attachment `json:"attachments"`
+	Likes       int          `json:"likes"`
+	Dislikes    int          `json:"dislikes"`
+	MyReaction  string       `json:"my_reaction"`
+}
+
+// Category groups posts by topic. Posts refer to categories by slug.
+type Category struct {
+	ID          int    `db:"id" json:"id"`
+	Slug        string `db:"slug" json:"slug"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	Position    int    `db:"position" json:"position"`
+	PostCount   int    `json:"post_count"`
 }
 
-// Comment represents a comment made on a post.
+// Comment represents a comment made on a post, or a reply to another comment of
+// the post when ParentID is set. A deleted comment that still has replies is kept
+// in its thread as a placeholder with Deleted set and its content and author cleared.
 type Comment struct {
-	ID        int       `db:"id" json:"id"`
-	Content   string    `db:"content" json:"content"`
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
-	PostID    int       `db:"post_id" json:"post_id"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID          int        `db:"id" json:"id"`
+	Content     string     `db:"content" json:"content"`
+	ContentHTML string     `db:"content_html" json:"content_html"`
+	UserID      uuid.UUID  `db:"user_id" json:"user_id"`
+	PostID      int        `db:"post_id" json:"post_id"`
+	ParentID    *int       `db:"parent_id" json:"parent_id"`
+	Depth       int        `db:"depth" json:"depth"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	EditedAt    *time.Time `db:"edited_at" json:"edited_at"`
+	Deleted     bool       `json:"deleted,omitempty"`
+	ReplyCount  int        `json:"reply_count"`
+	Mentions    []Mention  `json:"mentions"`
+	Likes       int        `json:"likes"`
+	Dislikes    int        `json:"dislikes"`
+	MyReaction  string     `json:"my_reaction"`
 }
 
 // Message represents a message sent between users.
 type Message struct {
+	ID          int          `db:"id" json:"id"`
+	SenderID    uuid.UUID    `db:"sender_id" json:"sender_id"`
+	ReceiverID  uuid.UUID    `db:"receiver_id" json:"receiver_id"`
+	Content     string       `db:"content" json:"content"`
+	CreatedAt   time.Time    `db:"created_at" json:"created_at"`
+	EditedAt    *time.Time   `db:"edited_at" json:"edited_at"`
+	ReadAt      *time.Time   `db:"read_at" json:"read_at"`
+	Mentions    []Mention    `json:"mentions"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Mention is a reference to a user with @username in the content of a post, comment
+// or message. Start and End delimit the reference in UTF-16 code units, the way
+// JavaScript indexes strings, so that clients can link it.
+type Mention struct {
+	UserID   uuid.UUID `db:"user_id" json:"user_id"`
+	Username string    `json:"username"`
+	Start    int       `db:"span_start" json:"start"`
+	End      int       `db:"span_end" json:"end"`
+}
+
+// Attachment is a file uploaded to a post or private message. It belongs to its
+// uploader alone until attached to one. Images carry their dimensions and a
+// thumbnail; the files themselves are kept in storage under their keys.
+type Attachment struct {
+	ID           int       `db:"id" json:"id"`
+	UploaderID   uuid.UUID `db:"uploader_id" json:"uploader_id"`
+	PostID       *int      `db:"post_id" json:"post_id"`
+	MessageID    *int      `db:"message_id" json:"message_id"`
+	StorageKey   string    `db:"storage_key" json:"-"`
+	ThumbnailKey *string   `db:"thumbnail_key" json:"-"`
+	Filename     string    `db:"filename" json:"filename"`
+	MimeType     string    `db:"mime_type" json:"mime_type"`
+	Size         int64     `db:"size" json:"size"`
+	Width        *int      `db:"width" json:"width"`
+	Height       *int      `db:"height" json:"height"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Revision is a version of a post or comment as it was before an edit replaced it.
+// Title is only set for posts.
+type Revision struct {
 	ID         int       `db:"id" json:"id"`
-	SenderID   uuid.UUID `db:"sender_id" json:"sender_id"`
-	ReceiverID uuid.UUID `db:"receiver_id" json:"receiver_id"`
+	TargetType string    `db:"target_type" json:"target_type"`
+	TargetID   int       `db:"target_id" json:"target_id"`
+	Title      string    `db:"title" json:"title,omitempty"`
 	Content    string    `db:"content" json:"content"`
+	EditorID   uuid.UUID `db:"editor_id" json:"editor_id"`
 	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 }
+
+// Report flags a post, comment or message for moderators. Content is a snapshot of
+// the target when it was reported, kept even if it is later edited or deleted.
+type Report struct {
+	ID         int        `db:"id" json:"id"`
+	ReporterID uuid.UUID  `db:"reporter_id" json:"reporter_id"`
+	TargetType string     `db:"target_type" json:"target_type"`
+	TargetID   int        `db:"target_id" json:"target_id"`
+	Reason     string     `db:"reason" json:"reason"`
+	Details    string     `db:"details" json:"details"`
+	Content    string     `db:"content" json:"content"`
+	Status     string     `db:"status" json:"status"`
+	ReviewerID *uuid.UUID `db:"reviewer_id" json:"reviewer_id"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Reasons a report can be filed for.
+const (
+	ReasonSpam          = "spam"
+	ReasonHarassment    = "harassment"
+	ReasonHate          = "hate"
+	ReasonInappropriate = "inappropriate"
+	ReasonOther         = "other"
+)
+
+// States of a report in the moderation queue.
+const (
+	ReportOpen      = "open"
+	ReportReviewing = "reviewing"
+	ReportActioned  = "actioned"
+	ReportDismissed = "dismissed"
+)
+
+// ModerationAction is an entry of the audit trail of moderator actions. TargetID
+// holds a user ID when the target is a user.
+type ModerationAction struct {
+	ID          int       `db:"id" json:"id"`
+	ModeratorID uuid.UUID `db:"moderator_id" json:"moderator_id"`
+	Action      string    `db:"action" json:"action"`
+	TargetType  string    `db:"target_type" json:"target_type"`
+	TargetID    string    `db:"target_id" json:"target_id"`
+	Note        string    `db:"note" json:"note,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Actions recorded in the moderation log.
+const (
+	ActionDeletePost    = "delete_post"
+	ActionDeleteComment = "delete_comment"
+	ActionLockPost      = "lock_post"
+	ActionUnlockPost    = "unlock_post"
+	ActionBanUser       = "ban_user"
+	ActionSuspendUser   = "suspend_user"
+	ActionLiftSanction  = "lift_sanction"
+	ActionSetRole       = "set_role"
+	ActionReviewReport  = "review_report"
+)
+
+// Notification tells a user about activity of another, the actor, concerning them.
+// PostID, CommentID and MessageID point at what the notification is about; a
+// comment notification carries both the post and the comment.
+type Notification struct {
+	ID        int        `db:"id" json:"id"`
+	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
+	ActorID   uuid.UUID  `db:"actor_id" json:"actor_id"`
+	Type      string     `db:"type" json:"type"`
+	PostID    *int       `db:"post_id" json:"post_id"`
+	CommentID *int       `db:"comment_id" json:"comment_id"`
+	MessageID *int       `db:"message_id" json:"message_id"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ReadAt    *time.Time `db:"read_at" json:"read_at"`
+}
+
+// Kinds of notification, each of which a user can turn off.
+const (
+	NotifyComment  = "comment"
+	NotifyReply    = "reply"
+	NotifyReaction = "reaction"
+	NotifyMention  = "mention"
+	NotifyMessage  = "message"
+)
+
+// NotificationTypes lists every kind of notification.
+var NotificationTypes = []string{NotifyComment, NotifyReply, NotifyReaction, NotifyMention, NotifyMessage}
+
+// Reaction kinds a user can leave on a post or comment.
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+)
+
+// Kinds of content a reaction can target. Reports can also target messages, and
+// moderation log entries users and reports.
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+	TargetMessage = "message"
+	TargetUser    = "user"
+	TargetReport  = "report"
+)
+
+// ReactionCounts aggregates the reactions left on a post or comment.
+type ReactionCounts struct {
+	Likes    int `json:"likes"`
+	Dislikes int `json:"dislikes"`
+}
+
+// PostResult is a post matching a search, with an HTML excerpt highlighting the matched terms.
+type PostResult struct {
+	Post
+	Snippet string `json:"snippet"`
+}
+
+// CommentResult is a comment matching a search, with an HTML excerpt highlighting the matched terms.
+type CommentResult struct {
+	Comment
+	Snippet string `json:"snippet"`
+}
@@ -8,6 +8,8 @@ import (
 	"os/signal"
 	"real-time-forum/backend/api"
 	"real-time-forum/backend/database"
+	"real-time-forum/backend/storage"
+	"real-time-forum/backend/store"
 	"real-time-forum/backend/utils"
 	"time"
 )
@@ -19,8 +21,7 @@ func main() {
 	}
 
 	dbPath := "database/database.db"
-	schemaPath := "database/schema.sql"
-	db := database.NewDatabase(dbPath, schemaPath)
+	db := database.NewDatabase(dbPath)
 	defer func() {
 		if err := db.Close(); err != nil {
 			log.Println("\033[31mError:\033[0m" + " Database shutdown failed - " + err.Error())
@@ -29,12 +30,44 @@ func main() {
 		}
 	}()
 
-	wsHub := api.NewHub()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(db, os.Args[2:])
+		return
+	}
+
+	applied, err := db.MigrateUp()
+	if err != nil {
+		log.Fatal("\033[31mError:\033[0m" + " Migration failed - " + err.Error())
+	}
+	for _, m := range applied {
+		log.Printf("\033[32mSuccess:\033[0m Applied migration %04d_%s", m.Version, m.Name)
+	}
+
+	st := store.NewSQLiteStore(db)
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		runSetRole(st.Users, os.Args[2:])
+		return
+	}
+
+	rendered, err := utils.RenderMissingContent(st.Contents)
+	if err != nil {
+		log.Fatal("\033[31mError:\033[0m" + " Rendering content failed - " + err.Error())
+	}
+	if rendered > 0 {
+		log.Printf("\033[32mSuccess:\033[0m Rendered %d posts and comments", rendered)
+	}
+
+	files, err := storage.NewDisk("uploads")
+	if err != nil {
+		log.Fatal("\033[31mError:\033[0m" + " Opening uploads failed - " + err.Error())
+	}
+
+	wsHub := api.NewHub(st.Users, api.DefaultClientConfig())
 	go wsHub.StartHub()
 
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: api.NewRouter(db, wsHub),
+		Handler: api.NewRouter(st, wsHub, files),
 	}
 
 	go func() {
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"real-time-forum/backend/database"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate status | up | down N"
+
+// runMigrate runs the migrate subcommand: status lists the migrations, up applies
+// the pending ones and down N reverts the last N applied.
+func runMigrate(db *database.Database, args []string) {
+	if len(args) == 0 {
+		log.Fatal("\033[31mError:\033[0m " + migrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := db.MigrationStatus()
+		if err != nil {
+			log.Fatal("\033[31mError:\033[0m" + " Migration status failed - " + err.Error())
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", s.Version, s.Name, state)
+		}
+
+	case "up":
+		applied, err := db.MigrateUp()
+		for _, m := range applied {
+			log.Printf("\033[32mSuccess:\033[0m Applied migration %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("\033[31mError:\033[0m" + " Migration failed - " + err.Error())
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+
+	case "down":
+		if len(args) != 2 {
+			log.Fatal("\033[31mError:\033[0m " + migrateUsage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			log.Fatal("\033[31mError:\033[0m" + " down expects a positive number of migrations")
+		}
+		reverted, err := db.MigrateDown(n)
+		for _, m := range reverted {
+			log.Printf("\033[32mSuccess:\033[0m Reverted migration %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal("\033[31mError:\033[0m" + " Migration failed - " + err.Error())
+		}
+
+	default:
+		log.Fatal("\033[31mError:\033[0m " + migrateUsage)
+	}
+}
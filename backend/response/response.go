@@ -0,0 +1,144 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Machine-readable codes carried by every error response.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "payload_too_large"
+	CodeUnsupportedType  = "unsupported_media_type"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+)
+
+// Error is the body of every error response. Fields, set on validation
+// failures, maps each rejected input field to the reason it was rejected.
+type Error struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New returns an error response with the given status, code and message.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest rejects a request whose body or parameters cannot be read.
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Invalid rejects input that was read but failed validation.
+func Invalid(message string, fields map[string]string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+// InvalidField rejects input with a single invalid field.
+func InvalidField(field, message string) *Error {
+	return Invalid(message, map[string]string{field: message})
+}
+
+// Unauthorized rejects a request lacking valid credentials.
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden rejects an authenticated request the user is not allowed to make.
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound reports that the requested record does not exist.
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// MethodNotAllowed rejects a method the endpoint does not serve.
+func MethodNotAllowed() *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
+
+// Conflict rejects a change clashing with existing data, such as a taken username.
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// ConflictField rejects a change because the value of a field is already in use.
+func ConflictField(field, message string) *Error {
+	e := Conflict(message)
+	e.Fields = map[string]string{field: message}
+	return e
+}
+
+// TooLarge rejects an upload exceeding a size limit or the quota of the user.
+func TooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+// UnsupportedType rejects an upload of a kind of file that is not accepted.
+func UnsupportedType(message string) *Error {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupportedType, message)
+}
+
+// RateLimited rejects a request made too soon after the previous one.
+func RateLimited(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+// Internal hides the cause of a failure from the client, which should be logged instead.
+func Internal() *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// FieldMessages joins the messages of invalid fields, ordered by field name.
+func FieldMessages(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fields[name]
+	}
+	return strings.Join(messages, "; ")
+}
+
+// WriteJSON writes v as a JSON body with the given status.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// WriteError writes err as an error body. Errors other than *Error are
+// reported as internal errors without exposing their message.
+func WriteError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Internal()
+	}
+	WriteJSON(w, e.Status, e)
+}
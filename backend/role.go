@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"real-time-forum/backend/api"
+	"real-time-forum/backend/store"
+)
+
+const setRoleUsage = "usage: set-role USERNAME member | moderator | admin"
+
+// runSetRole runs the set-role subcommand, which changes the role of a user from
+// the command line; it is how the first admin is appointed.
+func runSetRole(users store.UserStore, args []string) {
+	if len(args) != 2 || !api.IsRole(args[1]) {
+		log.Fatal("\033[31mError:\033[0m " + setRoleUsage)
+	}
+
+	user, err := users.GetByLogin(args[0])
+	if err != nil {
+		log.Fatal("\033[31mError:\033[0m" + " User lookup failed - " + err.Error())
+	}
+	if err := users.SetRole(user.ID.String(), args[1]); err != nil {
+		log.Fatal("\033[31mError:\033[0m" + " Setting role failed - " + err.Error())
+	}
+	log.Printf("\033[32mSuccess:\033[0m %s is now %s", user.Username, args[1])
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Disk stores files in a directory on the local disk, fanned out into
+// subdirectories named after the first two characters of their key.
+type Disk struct {
+	root string
+}
+
+// NewDisk returns a Storage keeping its files under root, which is created when missing.
+func NewDisk(root string) (*Disk, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &Disk{root: root}, nil
+}
+
+func (d *Disk) path(key string) (string, error) {
+	if len(key) != 64 || filepath.Base(key) != key {
+		return "", ErrNotFound
+	}
+	return filepath.Join(d.root, key[:2], key), nil
+}
+
+func (d *Disk) Put(data []byte) (string, error) {
+	key := Key(data)
+	path, err := d.path(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so that a reader never sees a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (d *Disk) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("file not found")
+
+// Storage keeps uploaded files under content-addressed keys, so that the same
+// file uploaded twice is stored once.
+type Storage interface {
+	// Put stores data under its key and returns the key. Storing data already
+	// present is a no-op.
+	Put(data []byte) (string, error)
+	// Open returns the file stored under key, or ErrNotFound.
+	Open(key string) (io.ReadSeekCloser, error)
+}
+
+// Key returns the content address of data.
+func Key(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryCategories(t *testing.T) {
+	testCategories(t, NewMemoryStore())
+}
+
+// testCategories lists posts by category and checks that deleting a post takes it,
+// and its comments, out of its categories.
+func testCategories(t *testing.T, st *Store) {
+	t.Helper()
+	alice := createTestUser(t, st, "alice")
+	viewer := alice.ID.String()
+	for _, slug := range []string{"test-a", "test-b"} {
+		if err := st.Categories.Create(&database.Category{Slug: slug, Name: slug}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	posts := []database.Post{
+		{Title: "Only a", Categories: []string{"test-a"}},
+		{Title: "Only b", Categories: []string{"test-b"}},
+		{Title: "Both", Categories: []string{"test-a", "test-b"}},
+	}
+	for i := range posts {
+		posts[i].UserID, posts[i].Content, posts[i].CreatedAt = alice.ID, "Content", base.Add(time.Duration(i)*time.Minute)
+		if err := st.Posts.Create(&posts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comment := database.Comment{UserID: alice.ID, PostID: posts[2].ID, Content: "Comment", CreatedAt: base}
+	if err := st.Comments.Create(&comment); err != nil {
+		t.Fatal(err)
+	}
+
+	checkListed := func(categories []string, want ...int) {
+		t.Helper()
+		listed, _, err := st.Posts.List(viewer, categories, Page{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, p := range listed {
+			got = append(got, p.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("posts in %v = %v, want %v", categories, got, want)
+		}
+	}
+	checkCounts := func(a, b int) {
+		t.Helper()
+		categories, err := st.Categories.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, c := range categories {
+			counts[c.Slug] = c.PostCount
+		}
+		if counts["test-a"] != a || counts["test-b"] != b {
+			t.Errorf("post counts = %d and %d, want %d and %d", counts["test-a"], counts["test-b"], a, b)
+		}
+	}
+
+	checkListed([]string{"test-a"}, posts[2].ID, posts[0].ID)
+	checkListed([]string{"test-b"}, posts[2].ID, posts[1].ID)
+	checkListed([]string{"test-a", "test-b"}, posts[2].ID, posts[1].ID, posts[0].ID)
+	checkCounts(2, 2)
+
+	if err := st.Posts.Delete(posts[2].ID, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	checkListed([]string{"test-a"}, posts[0].ID)
+	checkListed([]string{"test-b"}, posts[1].ID)
+	checkCounts(1, 1)
+	if _, err := st.Comments.Get(viewer, comment.ID); err != ErrNotFound {
+		t.Errorf("comment of the deleted post: error %v, want ErrNotFound", err)
+	}
+}
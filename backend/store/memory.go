@@ -0,0 +1,1415 @@
+package store
+
+import (
+	"errors"
+	"real-time-forum/backend/database"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// memory holds the records of the in-memory store. Every repository shares it so
+// that lookups spanning several tables, like unread counts, see the same data.
+type memory struct {
+	mu         sync.Mutex
+	users      []database.User
+	sessions   map[string]memorySession
+	posts      []database.Post
+	categories []database.Category
+	comments   []database.Comment
+	messages   []database.Message
+	reactions  map[reactionKey]string
+	revisions  []database.Revision
+	reports    []database.Report
+	actions    []database.ModerationAction
+	inbox      []database.Notification
+	files      []database.Attachment
+	// preferences holds the notification kinds each user set, by user ID
+	preferences map[string]map[string]bool
+	// privacy holds the profile fields each user showed or hid, by user ID
+	privacy map[string]map[string]bool
+	// mentions holds the users mentioned in each post, comment and message
+	mentions map[targetKey][]database.Mention
+	// deleted holds the soft deleted posts, comments and messages
+	deleted map[targetKey]bool
+}
+
+// targetKey identifies a post, comment or message.
+type targetKey struct {
+	targetType string
+	id         int
+}
+
+type memorySession struct {
+	session   database.Session
+	tokenHash string
+}
+
+type reactionKey struct {
+	userID     string
+	targetType string
+	targetID   int
+}
+
+// NewMemoryStore returns repositories that keep everything in memory, for tests.
+func NewMemoryStore() *Store {
+	m := &memory{
+		sessions:    make(map[string]memorySession),
+		reactions:   make(map[reactionKey]string),
+		mentions:    make(map[targetKey][]database.Mention),
+		deleted:     make(map[targetKey]bool),
+		preferences: make(map[string]map[string]bool),
+		privacy:     make(map[string]map[string]bool),
+	}
+	return &Store{
+		Users:         &memoryUsers{m},
+		Sessions:      &memorySessions{m},
+		Posts:         &memoryPosts{m},
+		Categories:    &memoryCategories{m},
+		Comments:      &memoryComments{m},
+		Messages:      &memoryMessages{m},
+		Reactions:     &memoryReactions{m},
+		Revisions:     &memoryRevisions{m},
+		Mentions:      &memoryMentions{m},
+		Contents:      &memoryContents{m},
+		Attachments:   &memoryAttachments{m},
+		Reports:       &memoryReports{m},
+		ModerationLog: &memoryModerationLog{m},
+		Notifications: &memoryNotifications{m},
+		Search:        &memorySearch{m},
+	}
+}
+
+// offsetWindow returns the [offset, offset+limit) window of n items as slice bounds.
+func offsetWindow(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if limit < 0 || end > n {
+		end = n
+	}
+	return offset, end
+}
+
+type memoryUsers struct{ *memory }
+
+func (s *memoryUsers) Create(user database.User, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		switch {
+		case u.ID == user.ID:
+			return errors.New("UNIQUE constraint failed: user.id")
+		case strings.EqualFold(u.Username, user.Username):
+			return ErrUsernameTaken
+		case strings.EqualFold(u.Email, user.Email):
+			return ErrEmailTaken
+		}
+	}
+	user.Password = passwordHash
+	user.CreatedAt = time.Now()
+	user.Role = database.RoleMember
+	s.users = append(s.users, user)
+	return nil
+}
+
+func (s *memoryUsers) GetByLogin(emailOrUsername string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, emailOrUsername) || strings.EqualFold(u.Username, emailOrUsername) {
+			return u, nil
+		}
+	}
+	return database.User{}, ErrNotFound
+}
+
+func (s *memoryUsers) Get(userID string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID.String() == userID {
+			return database.User{
+				ID: u.ID, Username: u.Username, LastSeenAt: u.LastSeenAt,
+				Role: u.Role, BannedAt: u.BannedAt, SuspendedUntil: u.SuspendedUntil,
+			}, nil
+		}
+	}
+	return database.User{}, ErrNotFound
+}
+
+func (s *memoryUsers) UsernameExists(username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Username, username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryUsers) EmailExists(email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryUsers) ListContacts(userID string) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latest := make(map[string]time.Time)
+	unread := make(map[string]int)
+	for _, m := range s.messages {
+		if s.deleted[targetKey{database.TargetMessage, m.ID}] {
+			continue
+		}
+		other := ""
+		switch userID {
+		case m.SenderID.String():
+			other = m.ReceiverID.String()
+		case m.ReceiverID.String():
+			other = m.SenderID.String()
+			if m.ReadAt == nil {
+				unread[other]++
+			}
+		default:
+			continue
+		}
+		if m.CreatedAt.After(latest[other]) {
+			latest[other] = m.CreatedAt
+		}
+	}
+
+	users := []database.User{}
+	for _, u := range s.users {
+		id := u.ID.String()
+		if id == userID {
+			continue
+		}
+		lastSeen := u.LastSeenAt
+		if !s.visible(id, database.ProfileLastSeen) {
+			lastSeen = nil
+		}
+		users = append(users, database.User{ID: u.ID, Username: u.Username, LastSeenAt: lastSeen, Unread: unread[id]})
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		ti, tj := latest[users[i].ID.String()], latest[users[j].ID.String()]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+func (s *memoryUsers) SetLastSeen(userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID.String() == userID {
+			s.users[i].LastSeenAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memoryUsers) SetRole(userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID.String() == userID {
+			s.users[i].Role = role
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryUsers) ListIDsByRole(roles []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+	for _, u := range s.users {
+		if hasAny(roles, []string{u.Role}) {
+			ids = append(ids, u.ID.String())
+		}
+	}
+	return ids, nil
+}
+
+func (s *memoryUsers) ListByUsernames(usernames []string) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []database.User{}
+	for _, u := range s.users {
+		for _, username := range usernames {
+			if strings.EqualFold(u.Username, username) {
+				users = append(users, database.User{ID: u.ID, Username: u.Username})
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+func (s *memoryUsers) SetSanction(userID string, bannedAt, suspendedUntil *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID.String() == userID {
+			s.users[i].BannedAt = bannedAt
+			s.users[i].SuspendedUntil = suspendedUntil
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryUsers) GetAccount(userID string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID.String() == userID {
+			u.Password = ""
+			return u, nil
+		}
+	}
+	return database.User{}, ErrNotFound
+}
+
+func (s *memoryUsers) GetProfile(userID string) (database.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID.String() != userID {
+			continue
+		}
+		var posts, comments int
+		for _, p := range s.posts {
+			if p.UserID == u.ID && !s.deleted[targetKey{database.TargetPost, p.ID}] {
+				posts++
+			}
+		}
+		for _, c := range s.comments {
+			if c.UserID == u.ID && !s.deleted[targetKey{database.TargetComment, c.ID}] {
+				comments++
+			}
+		}
+		joinedAt := u.CreatedAt
+		return database.Profile{
+			ID: u.ID, Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarID: u.AvatarID,
+			JoinedAt: &joinedAt, PostCount: &posts, CommentCount: &comments, LastSeenAt: u.LastSeenAt,
+			Privacy: s.privacyOf(userID),
+		}, nil
+	}
+	return database.Profile{}, ErrNotFound
+}
+
+func (s *memoryUsers) UpdateProfile(user database.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := -1
+	for i, u := range s.users {
+		switch {
+		case u.ID == user.ID:
+			index = i
+		case strings.EqualFold(u.Username, user.Username):
+			return ErrUsernameTaken
+		case strings.EqualFold(u.Email, user.Email):
+			return ErrEmailTaken
+		}
+	}
+	if index < 0 {
+		return ErrNotFound
+	}
+	u := &s.users[index]
+	u.Username, u.Email, u.FirstName, u.LastName, u.Age, u.Gender = user.Username, user.Email, user.FirstName, user.LastName, user.Age, user.Gender
+	u.DisplayName, u.Bio, u.AvatarID = user.DisplayName, user.Bio, user.AvatarID
+	return nil
+}
+
+func (s *memoryUsers) Privacy(userID string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.privacyOf(userID), nil
+}
+
+func (s *memoryUsers) SetPrivacy(userID string, privacy map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.privacy[userID] == nil {
+		s.privacy[userID] = make(map[string]bool)
+	}
+	for field, visible := range privacy {
+		s.privacy[userID][field] = visible
+	}
+	return nil
+}
+
+// privacyOf returns whether each profile field of a user is visible to others.
+// The caller must hold mu.
+func (m *memory) privacyOf(userID string) map[string]bool {
+	privacy := make(map[string]bool, len(database.ProfileFields))
+	for _, field := range database.ProfileFields {
+		privacy[field] = true
+	}
+	for field, visible := range m.privacy[userID] {
+		privacy[field] = visible
+	}
+	return privacy
+}
+
+// visible reports whether a profile field of a user is visible to others. The
+// caller must hold mu.
+func (m *memory) visible(userID, field string) bool {
+	visible, set := m.privacy[userID][field]
+	return visible || !set
+}
+
+// isAvatar reports whether an attachment is the avatar of a user. The caller must hold mu.
+func (m *memory) isAvatar(id int) bool {
+	for _, u := range m.users {
+		if u.AvatarID != nil && *u.AvatarID == id {
+			return true
+		}
+	}
+	return false
+}
+
+type memorySessions struct{ *memory }
+
+func (s *memorySessions) Create(session database.Session, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = memorySession{session: session, tokenHash: tokenHash}
+	return nil
+}
+
+func (s *memorySessions) GetByTokenHash(tokenHash string, now time.Time) (database.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.sessions {
+		if stored.tokenHash != tokenHash || !stored.session.ExpiresAt.After(now) {
+			continue
+		}
+		session := stored.session
+		for _, u := range s.users {
+			if u.ID == session.UserID {
+				session.Username = u.Username
+				session.Role = u.Role
+				return session, nil
+			}
+		}
+	}
+	return database.Session{}, ErrNotFound
+}
+
+func (s *memorySessions) Touch(id string, lastUsedAt, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.sessions[id]; ok {
+		stored.session.LastUsedAt = lastUsedAt
+		stored.session.ExpiresAt = expiresAt
+		s.sessions[id] = stored
+	}
+	return nil
+}
+
+func (s *memorySessions) ListByUser(userID string, now time.Time) ([]database.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := []database.Session{}
+	for _, stored := range s.sessions {
+		if stored.session.UserID.String() == userID && stored.session.ExpiresAt.After(now) {
+			session := stored.session
+			session.Username = ""
+			session.Role = ""
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (s *memorySessions) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[id]
+	if !ok || stored.session.UserID.String() != userID {
+		return ErrNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memorySessions) DeleteExpired(userID string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stored := range s.sessions {
+		if stored.session.UserID.String() == userID && !stored.session.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *memorySessions) DeleteByUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stored := range s.sessions {
+		if stored.session.UserID.String() == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+type memoryPosts struct{ *memory }
+
+func (s *memoryPosts) List(viewerID string, categories []string, page Page) ([]database.Post, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := []database.Post{}
+	for _, p := range s.posts {
+		if s.deleted[targetKey{database.TargetPost, p.ID}] || len(categories) > 0 && !hasAny(p.Categories, categories) {
+			continue
+		}
+		p.Categories = append([]string{}, p.Categories...)
+		p.Mentions = s.mentionsIn(database.TargetPost, p.ID)
+		p.Attachments = s.attachmentsOn(database.TargetPost, p.ID)
+		p.Likes, p.Dislikes, p.MyReaction = s.reactionsOn(database.TargetPost, p.ID, viewerID)
+		posts = append(posts, p)
+	}
+	cursor := func(p database.Post) Cursor { return Cursor{CreatedAt: p.CreatedAt, ID: p.ID} }
+	sort.Slice(posts, func(i, j int) bool {
+		return cursor(posts[i]).before(cursor(posts[j]))
+	})
+	posts, more := pageOf(posts, cursor, page)
+	return posts, more, nil
+}
+
+func (s *memoryPosts) Get(viewerID string, id int) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetPost, id) {
+		return database.Post{}, ErrNotFound
+	}
+	p := s.posts[id-1]
+	p.Categories = append([]string{}, p.Categories...)
+	p.Mentions = s.mentionsIn(database.TargetPost, p.ID)
+	p.Attachments = s.attachmentsOn(database.TargetPost, p.ID)
+	p.Likes, p.Dislikes, p.MyReaction = s.reactionsOn(database.TargetPost, p.ID, viewerID)
+	return p, nil
+}
+
+func (s *memoryPosts) Create(post *database.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post.ID = len(s.posts) + 1
+	stored := *post
+	stored.Categories = s.knownCategories(post.Categories)
+	s.posts = append(s.posts, stored)
+	return nil
+}
+
+// knownCategories returns the slugs of the existing categories among slugs. The caller must hold mu.
+func (m *memory) knownCategories(slugs []string) []string {
+	var known []string
+	for _, c := range m.categories {
+		if hasAny(slugs, []string{c.Slug}) {
+			known = append(known, c.Slug)
+		}
+	}
+	return known
+}
+
+func (s *memoryPosts) Update(post *database.Post, editorID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetPost, post.ID) {
+		return ErrNotFound
+	}
+	stored := &s.posts[post.ID-1]
+	if err := s.addRevision(database.TargetPost, post.ID, stored.Title, stored.Content, editorID, post.EditedAt); err != nil {
+		return err
+	}
+	stored.Title = post.Title
+	stored.Content = post.Content
+	stored.ContentHTML = post.ContentHTML
+	stored.Categories = s.knownCategories(post.Categories)
+	stored.EditedAt = post.EditedAt
+	return nil
+}
+
+func (s *memoryPosts) Delete(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetPost, id) {
+		return ErrNotFound
+	}
+	s.deleted[targetKey{database.TargetPost, id}] = true
+	for _, c := range s.comments {
+		if c.PostID == id {
+			s.deleted[targetKey{database.TargetComment, c.ID}] = true
+		}
+	}
+	return nil
+}
+
+func (s *memoryPosts) SetLocked(id int, at *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetPost, id) {
+		return ErrNotFound
+	}
+	s.posts[id-1].LockedAt = at
+	return nil
+}
+
+// exists reports whether a post, comment or message with the ID was created and
+// not deleted. The caller must hold mu.
+func (m *memory) exists(targetType string, id int) bool {
+	var count int
+	switch targetType {
+	case database.TargetPost:
+		count = len(m.posts)
+	case database.TargetComment:
+		count = len(m.comments)
+	case database.TargetMessage:
+		count = len(m.messages)
+	}
+	return id > 0 && id <= count && !m.deleted[targetKey{targetType, id}]
+}
+
+// addRevision records the version of a post or comment an edit replaces. The caller must hold mu.
+func (m *memory) addRevision(targetType string, targetID int, title, content, editorID string, at *time.Time) error {
+	editor, err := uuid.FromString(editorID)
+	if err != nil {
+		return err
+	}
+	revision := database.Revision{
+		ID:         len(m.revisions) + 1,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Title:      title,
+		Content:    content,
+		EditorID:   editor,
+	}
+	if at != nil {
+		revision.CreatedAt = *at
+	}
+	m.revisions = append(m.revisions, revision)
+	return nil
+}
+
+// hasAny reports whether values and wanted share an element.
+func hasAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type memoryCategories struct{ *memory }
+
+func (s *memoryCategories) List() ([]database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := append([]database.Category{}, s.categories...)
+	for i := range categories {
+		c := &categories[i]
+		c.PostCount = 0
+		for _, p := range s.posts {
+			if !s.deleted[targetKey{database.TargetPost, p.ID}] && hasAny(p.Categories, []string{c.Slug}) {
+				c.PostCount++
+			}
+		}
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (s *memoryCategories) Create(category *database.Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.categories {
+		if c.Slug == category.Slug {
+			return errors.New("UNIQUE constraint failed: category.slug")
+		}
+	}
+	category.ID = len(s.categories) + 1
+	s.categories = append(s.categories, *category)
+	return nil
+}
+
+type memoryComments struct{ *memory }
+
+// answered returns the comments of a post with a reply below them that was not
+// deleted, as threadCTE does. The caller must hold mu.
+func (m *memory) answered(postID int) map[int]bool {
+	answered := make(map[int]bool)
+	for _, c := range m.comments {
+		if c.PostID != postID || m.deleted[targetKey{database.TargetComment, c.ID}] {
+			continue
+		}
+		for parent := c.ParentID; parent != nil && !answered[*parent]; parent = m.comments[*parent-1].ParentID {
+			answered[*parent] = true
+		}
+	}
+	return answered
+}
+
+// threadComment returns a listed comment with its reply count and reactions, or
+// its placeholder when deleted. The caller must hold mu.
+func (m *memory) threadComment(c database.Comment, viewerID string, answered map[int]bool) database.Comment {
+	for _, r := range m.comments {
+		if r.ParentID != nil && *r.ParentID == c.ID && (!m.deleted[targetKey{database.TargetComment, r.ID}] || answered[r.ID]) {
+			c.ReplyCount++
+		}
+	}
+	if m.deleted[targetKey{database.TargetComment, c.ID}] {
+		return placeholder(c)
+	}
+	c.Mentions = m.mentionsIn(database.TargetComment, c.ID)
+	c.Likes, c.Dislikes, c.MyReaction = m.reactionsOn(database.TargetComment, c.ID, viewerID)
+	return c
+}
+
+// listComments returns a page of the comments of postID that match, as sqliteComments does.
+// The caller must hold mu.
+func (m *memory) listComments(viewerID string, postID int, match func(database.Comment) bool, page Page) ([]database.Comment, bool) {
+	answered := m.answered(postID)
+	comments := []database.Comment{}
+	for _, c := range m.comments {
+		if c.PostID == postID && match(c) && (!m.deleted[targetKey{database.TargetComment, c.ID}] || answered[c.ID]) {
+			comments = append(comments, m.threadComment(c, viewerID, answered))
+		}
+	}
+	cursor := func(c database.Comment) Cursor { return Cursor{CreatedAt: c.CreatedAt, ID: c.ID} }
+	sort.Slice(comments, func(i, j int) bool {
+		return cursor(comments[i]).before(cursor(comments[j]))
+	})
+	return pageOf(comments, cursor, page)
+}
+
+func (s *memoryComments) ListByPost(viewerID string, postID int, page Page) ([]database.Comment, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments, more := s.listComments(viewerID, postID, func(c database.Comment) bool { return c.ParentID == nil }, page)
+	return comments, more, nil
+}
+
+func (s *memoryComments) ListReplies(viewerID string, parentID int, page Page) ([]database.Comment, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parentID <= 0 || parentID > len(s.comments) {
+		return nil, false, ErrNotFound
+	}
+	isReply := func(c database.Comment) bool { return c.ParentID != nil && *c.ParentID == parentID }
+	comments, more := s.listComments(viewerID, s.comments[parentID-1].PostID, isReply, page)
+	return comments, more, nil
+}
+
+func (s *memoryComments) Create(comment *database.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.ID = len(s.comments) + 1
+	s.comments = append(s.comments, *comment)
+	return nil
+}
+
+func (s *memoryComments) Get(viewerID string, id int) (database.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetComment, id) {
+		return database.Comment{}, ErrNotFound
+	}
+	c := s.comments[id-1]
+	return s.threadComment(c, viewerID, s.answered(c.PostID)), nil
+}
+
+func (s *memoryComments) Update(comment *database.Comment, editorID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetComment, comment.ID) {
+		return ErrNotFound
+	}
+	stored := &s.comments[comment.ID-1]
+	if err := s.addRevision(database.TargetComment, comment.ID, "", stored.Content, editorID, comment.EditedAt); err != nil {
+		return err
+	}
+	stored.Content = comment.Content
+	stored.ContentHTML = comment.ContentHTML
+	stored.EditedAt = comment.EditedAt
+	return nil
+}
+
+func (s *memoryComments) Delete(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetComment, id) {
+		return ErrNotFound
+	}
+	s.deleted[targetKey{database.TargetComment, id}] = true
+	return nil
+}
+
+// mentionsIn returns the mentions in a post, comment or message with the current
+// usernames of the mentioned users. The caller must hold mu.
+func (m *memory) mentionsIn(targetType string, id int) []database.Mention {
+	mentions := append([]database.Mention{}, m.mentions[targetKey{targetType, id}]...)
+	for i := range mentions {
+		for _, u := range m.users {
+			if u.ID == mentions[i].UserID {
+				mentions[i].Username = u.Username
+			}
+		}
+	}
+	return mentions
+}
+
+type memoryMentions struct{ *memory }
+
+func (s *memoryMentions) Replace(targetType string, targetID int, mentions []database.Mention) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mentions[targetKey{targetType, targetID}] = append([]database.Mention{}, mentions...)
+	return nil
+}
+
+type memoryContents struct{ *memory }
+
+func (s *memoryContents) Unrendered(targetType string, afterID, limit int) ([]Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sources := []Source{}
+	add := func(id int, content, html string) {
+		if id > afterID && html == "" && len(sources) < limit {
+			sources = append(sources, Source{ID: id, Content: content, Mentions: s.mentionsIn(targetType, id)})
+		}
+	}
+	switch targetType {
+	case database.TargetPost:
+		for _, p := range s.posts {
+			add(p.ID, p.Content, p.ContentHTML)
+		}
+	case database.TargetComment:
+		for _, c := range s.comments {
+			add(c.ID, c.Content, c.ContentHTML)
+		}
+	}
+	return sources, nil
+}
+
+func (s *memoryContents) SetHTML(targetType string, id int, html string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case targetType == database.TargetPost && id >= 1 && id <= len(s.posts):
+		s.posts[id-1].ContentHTML = html
+	case targetType == database.TargetComment && id >= 1 && id <= len(s.comments):
+		s.comments[id-1].ContentHTML = html
+	default:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// attachmentsOn returns the attachments of a post or message. The caller must hold mu.
+func (m *memory) attachmentsOn(targetType string, id int) []database.Attachment {
+	attachments := []database.Attachment{}
+	for _, a := range m.files {
+		if targetType == database.TargetPost && a.PostID != nil && *a.PostID == id ||
+			targetType == database.TargetMessage && a.MessageID != nil && *a.MessageID == id {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments
+}
+
+type memoryAttachments struct{ *memory }
+
+func (s *memoryAttachments) Create(a *database.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = len(s.files) + 1
+	s.files = append(s.files, *a)
+	return nil
+}
+
+func (s *memoryAttachments) Get(id int) (database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > len(s.files) {
+		return database.Attachment{}, ErrNotFound
+	}
+	return s.files[id-1], nil
+}
+
+func (s *memoryAttachments) Unattached(uploaderID string, ids []int) ([]database.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attachments := []database.Attachment{}
+	for _, a := range s.files {
+		if a.UploaderID.String() == uploaderID && a.PostID == nil && a.MessageID == nil && slices.Contains(ids, a.ID) && !s.isAvatar(a.ID) {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments, nil
+}
+
+func (s *memoryAttachments) Attach(ids []int, targetType string, targetID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.files {
+		a := &s.files[i]
+		if a.PostID != nil || a.MessageID != nil || !slices.Contains(ids, a.ID) {
+			continue
+		}
+		id := targetID
+		switch targetType {
+		case database.TargetPost:
+			a.PostID = &id
+		case database.TargetMessage:
+			a.MessageID = &id
+		}
+	}
+	return nil
+}
+
+func (s *memoryAttachments) UsedBytes(uploaderID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var used int64
+	for _, a := range s.files {
+		if a.UploaderID.String() == uploaderID {
+			used += a.Size
+		}
+	}
+	return used, nil
+}
+
+type memoryMessages struct{ *memory }
+
+func (s *memoryMessages) Create(message *database.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message.ID = len(s.messages) + 1
+	s.messages = append(s.messages, *message)
+	return nil
+}
+
+func (s *memoryMessages) ListConversation(userID, otherUserID string, page Page) ([]database.Message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []database.Message{}
+	for _, m := range s.messages {
+		sender, receiver := m.SenderID.String(), m.ReceiverID.String()
+		if s.deleted[targetKey{database.TargetMessage, m.ID}] {
+			continue
+		}
+		if (sender == userID && receiver == otherUserID) || (sender == otherUserID && receiver == userID) {
+			m.Mentions = s.mentionsIn(database.TargetMessage, m.ID)
+			m.Attachments = s.attachmentsOn(database.TargetMessage, m.ID)
+			messages = append(messages, m)
+		}
+	}
+	cursor := func(m database.Message) Cursor { return Cursor{CreatedAt: m.CreatedAt, ID: m.ID} }
+	sort.Slice(messages, func(i, j int) bool {
+		return cursor(messages[i]).before(cursor(messages[j]))
+	})
+	messages, more := pageOf(messages, cursor, page)
+	return messages, more, nil
+}
+
+func (s *memoryMessages) Get(id int) (database.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetMessage, id) {
+		return database.Message{}, ErrNotFound
+	}
+	m := s.messages[id-1]
+	m.Mentions = s.mentionsIn(database.TargetMessage, id)
+	m.Attachments = s.attachmentsOn(database.TargetMessage, id)
+	return m, nil
+}
+
+func (s *memoryMessages) Update(message *database.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetMessage, message.ID) {
+		return ErrNotFound
+	}
+	s.messages[message.ID-1].Content = message.Content
+	s.messages[message.ID-1].EditedAt = message.EditedAt
+	return nil
+}
+
+func (s *memoryMessages) Delete(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.exists(database.TargetMessage, id) {
+		return ErrNotFound
+	}
+	s.deleted[targetKey{database.TargetMessage, id}] = true
+	return nil
+}
+
+func (s *memoryMessages) MarkRead(readerID, senderID string, upTo int, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for i := range s.messages {
+		m := &s.messages[i]
+		if s.deleted[targetKey{database.TargetMessage, m.ID}] {
+			continue
+		}
+		if m.SenderID.String() == senderID && m.ReceiverID.String() == readerID && m.ID <= upTo && m.ReadAt == nil {
+			readAt := at
+			m.ReadAt = &readAt
+			count++
+		}
+	}
+	return count, nil
+}
+
+// reactionsOn counts the reactions on a target and finds the viewer's own. The caller must hold mu.
+func (m *memory) reactionsOn(targetType string, targetID int, viewerID string) (likes, dislikes int, mine string) {
+	for key, kind := range m.reactions {
+		if key.targetType != targetType || key.targetID != targetID {
+			continue
+		}
+		if kind == database.ReactionLike {
+			likes++
+		} else {
+			dislikes++
+		}
+		if key.userID == viewerID {
+			mine = kind
+		}
+	}
+	return likes, dislikes, mine
+}
+
+type memoryReactions struct{ *memory }
+
+func (s *memoryReactions) Toggle(userID, targetType string, targetID int, kind string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if (targetType != database.TargetPost && targetType != database.TargetComment) || !s.exists(targetType, targetID) {
+		return "", ErrNotFound
+	}
+
+	key := reactionKey{userID: userID, targetType: targetType, targetID: targetID}
+	if s.reactions[key] == kind {
+		delete(s.reactions, key)
+		return "", nil
+	}
+	s.reactions[key] = kind
+	return kind, nil
+}
+
+func (s *memoryReactions) Counts(targetType string, targetID int) (database.ReactionCounts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	likes, dislikes, _ := s.reactionsOn(targetType, targetID, "")
+	return database.ReactionCounts{Likes: likes, Dislikes: dislikes}, nil
+}
+
+type memoryRevisions struct{ *memory }
+
+func (s *memoryRevisions) List(targetType string, targetID int) ([]database.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisions := []database.Revision{}
+	for _, r := range s.revisions {
+		if r.TargetType == targetType && r.TargetID == targetID {
+			revisions = append(revisions, r)
+		}
+	}
+	return revisions, nil
+}
+
+type memoryReports struct{ *memory }
+
+func (s *memoryReports) Create(report *database.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.reports {
+		pending := r.Status == database.ReportOpen || r.Status == database.ReportReviewing
+		if pending && r.ReporterID == report.ReporterID && r.TargetType == report.TargetType && r.TargetID == report.TargetID {
+			return ErrAlreadyReported
+		}
+	}
+	report.ID = len(s.reports) + 1
+	s.reports = append(s.reports, *report)
+	return nil
+}
+
+func (s *memoryReports) Get(id int) (database.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > len(s.reports) {
+		return database.Report{}, ErrNotFound
+	}
+	return s.reports[id-1], nil
+}
+
+func (s *memoryReports) List(filter ReportFilter, page Page) ([]database.Report, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports := []database.Report{}
+	for _, r := range s.reports {
+		if (filter.Status == "" || r.Status == filter.Status) &&
+			(filter.TargetType == "" || r.TargetType == filter.TargetType) &&
+			(filter.Reason == "" || r.Reason == filter.Reason) {
+			reports = append(reports, r)
+		}
+	}
+	cursor := func(r database.Report) Cursor { return Cursor{CreatedAt: r.CreatedAt, ID: r.ID} }
+	sort.Slice(reports, func(i, j int) bool {
+		return cursor(reports[i]).before(cursor(reports[j]))
+	})
+	reports, more := pageOf(reports, cursor, page)
+	return reports, more, nil
+}
+
+func (s *memoryReports) SetStatus(report *database.Report, from string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if report.ID < 1 || report.ID > len(s.reports) || s.reports[report.ID-1].Status != from {
+		return ErrStatusChanged
+	}
+	stored := &s.reports[report.ID-1]
+	stored.Status = report.Status
+	stored.ReviewerID = report.ReviewerID
+	stored.UpdatedAt = report.UpdatedAt
+	return nil
+}
+
+type memoryModerationLog struct{ *memory }
+
+func (s *memoryModerationLog) Add(action *database.ModerationAction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	action.ID = len(s.actions) + 1
+	s.actions = append(s.actions, *action)
+	return nil
+}
+
+func (s *memoryModerationLog) List(page Page) ([]database.ModerationAction, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actions := append([]database.ModerationAction{}, s.actions...)
+	cursor := func(a database.ModerationAction) Cursor { return Cursor{CreatedAt: a.CreatedAt, ID: a.ID} }
+	sort.Slice(actions, func(i, j int) bool {
+		return cursor(actions[i]).before(cursor(actions[j]))
+	})
+	actions, more := pageOf(actions, cursor, page)
+	return actions, more, nil
+}
+
+type memoryNotifications struct{ *memory }
+
+// sameID reports whether two optional IDs are both unset or equal.
+func sameID(a, b *int) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func (s *memoryNotifications) Create(n *database.Notification) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.inbox {
+		if other.ReadAt == nil && other.UserID == n.UserID && other.ActorID == n.ActorID && other.Type == n.Type &&
+			sameID(other.PostID, n.PostID) && sameID(other.CommentID, n.CommentID) && sameID(other.MessageID, n.MessageID) {
+			return false, nil
+		}
+	}
+	n.ID = len(s.inbox) + 1
+	s.inbox = append(s.inbox, *n)
+	return true, nil
+}
+
+func (s *memoryNotifications) List(userID string, unreadOnly bool, page Page) ([]database.Notification, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications := []database.Notification{}
+	for _, n := range s.inbox {
+		if n.UserID.String() == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, n)
+		}
+	}
+	cursor := func(n database.Notification) Cursor { return Cursor{CreatedAt: n.CreatedAt, ID: n.ID} }
+	sort.Slice(notifications, func(i, j int) bool {
+		return cursor(notifications[i]).before(cursor(notifications[j]))
+	})
+	notifications, more := pageOf(notifications, cursor, page)
+	return notifications, more, nil
+}
+
+func (s *memoryNotifications) UnreadCount(userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, n := range s.inbox {
+		if n.UserID.String() == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryNotifications) MarkRead(userID string, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || id > len(s.inbox) || s.inbox[id-1].UserID.String() != userID {
+		return ErrNotFound
+	}
+	if n := &s.inbox[id-1]; n.ReadAt == nil {
+		readAt := at
+		n.ReadAt = &readAt
+	}
+	return nil
+}
+
+func (s *memoryNotifications) MarkAllRead(userID string, at time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for i := range s.inbox {
+		n := &s.inbox[i]
+		if n.UserID.String() == userID && n.ReadAt == nil {
+			readAt := at
+			n.ReadAt = &readAt
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryNotifications) Preferences(userID string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	preferences := make(map[string]bool, len(database.NotificationTypes))
+	for _, kind := range database.NotificationTypes {
+		preferences[kind] = true
+	}
+	for kind, enabled := range s.preferences[userID] {
+		preferences[kind] = enabled
+	}
+	return preferences, nil
+}
+
+func (s *memoryNotifications) SetPreferences(userID string, preferences map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.preferences[userID] == nil {
+		s.preferences[userID] = make(map[string]bool)
+	}
+	for kind, enabled := range preferences {
+		s.preferences[userID][kind] = enabled
+	}
+	return nil
+}
+
+type memorySearch struct{ *memory }
+
+// markTerms wraps the words of text starting with one of the terms in the start and
+// end markers and returns how many terms were found.
+func markTerms(text string, terms []string, start, end string) (string, int) {
+	found := make(map[string]bool)
+	words := strings.Fields(text)
+	for i, word := range words {
+		for _, term := range terms {
+			if strings.HasPrefix(strings.ToLower(word), strings.ToLower(term)) {
+				words[i] = start + word + end
+				found[term] = true
+				break
+			}
+		}
+	}
+	return strings.Join(words, " "), len(found)
+}
+
+// matchesFilters reports whether a post or comment by authorID on postID, created
+// at createdAt, passes the author, category and date filters of a query. The caller
+// must hold mu.
+func (m *memory) matchesFilters(q SearchQuery, authorID string, postID int, createdAt time.Time) bool {
+	if q.Since != nil && createdAt.Before(*q.Since) || q.Until != nil && !createdAt.Before(*q.Until) {
+		return false
+	}
+	if q.Author != "" {
+		matched := false
+		for _, u := range m.users {
+			if strings.EqualFold(u.Username, q.Author) && u.ID.String() == authorID {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(q.Categories) > 0 {
+		if postID < 1 || postID > len(m.posts) || !hasAny(m.posts[postID-1].Categories, q.Categories) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *memorySearch) Posts(q SearchQuery) ([]database.PostResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	markStart, markEnd := matchMarkers()
+	results := []database.PostResult{}
+	for _, p := range s.posts {
+		snippet, found := markTerms(p.Title+" "+p.Content, q.Terms, markStart, markEnd)
+		if found < len(q.Terms) || s.deleted[targetKey{database.TargetPost, p.ID}] || !s.matchesFilters(q, p.UserID.String(), p.ID, p.CreatedAt) {
+			continue
+		}
+		p.Categories = append([]string{}, p.Categories...)
+		p.Mentions = s.mentionsIn(database.TargetPost, p.ID)
+		p.Attachments = s.attachmentsOn(database.TargetPost, p.ID)
+		p.Likes, p.Dislikes, p.MyReaction = s.reactionsOn(database.TargetPost, p.ID, q.ViewerID)
+		results = append(results, database.PostResult{Post: p, Snippet: highlight(snippet, markStart, markEnd)})
+	}
+	start, end := offsetWindow(len(results), q.Limit, q.Offset)
+	return results[start:end], nil
+}
+
+func (s *memorySearch) Comments(q SearchQuery) ([]database.CommentResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	markStart, markEnd := matchMarkers()
+	results := []database.CommentResult{}
+	for _, c := range s.comments {
+		snippet, found := markTerms(c.Content, q.Terms, markStart, markEnd)
+		if found < len(q.Terms) || s.deleted[targetKey{database.TargetComment, c.ID}] || !s.matchesFilters(q, c.UserID.String(), c.PostID, c.CreatedAt) {
+			continue
+		}
+		c.Mentions = s.mentionsIn(database.TargetComment, c.ID)
+		c.Likes, c.Dislikes, c.MyReaction = s.reactionsOn(database.TargetComment, c.ID, q.ViewerID)
+		results = append(results, database.CommentResult{Comment: c, Snippet: highlight(snippet, markStart, markEnd)})
+	}
+	start, end := offsetWindow(len(results), q.Limit, q.Offset)
+	return results[start:end], nil
+}
+
+func (s *memorySearch) Users(q SearchQuery) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []database.User{}
+	for _, u := range s.users {
+		name := strings.ToLower(u.Username)
+		matched := true
+		for _, term := range q.Terms {
+			if !strings.Contains(name, strings.ToLower(term)) {
+				matched = false
+			}
+		}
+		if matched {
+			lastSeen := u.LastSeenAt
+			if !s.visible(u.ID.String(), database.ProfileLastSeen) {
+				lastSeen = nil
+			}
+			users = append(users, database.User{ID: u.ID, Username: u.Username, LastSeenAt: lastSeen})
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return len(users[i].Username) < len(users[j].Username)
+	})
+	start, end := offsetWindow(len(users), q.Limit, q.Offset)
+	return users[start:end], nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"testing"
+	"time"
+)
+
+func TestMemoryNotificationDedup(t *testing.T) {
+	testNotificationDedup(t, NewMemoryStore())
+}
+
+// testNotificationDedup stores notifications that repeat an unread one, a read one
+// and one about something else.
+func testNotificationDedup(t *testing.T, st *Store) {
+	t.Helper()
+	alice := createTestUser(t, st, "alice")
+	bob := createTestUser(t, st, "bob")
+	post := database.Post{UserID: alice.ID, Title: "Post", Content: "Content", CreatedAt: time.Now()}
+	if err := st.Posts.Create(&post); err != nil {
+		t.Fatal(err)
+	}
+	userID := alice.ID.String()
+
+	notify := func(kind string) bool {
+		t.Helper()
+		n := database.Notification{UserID: alice.ID, ActorID: bob.ID, Type: kind, PostID: &post.ID, CreatedAt: time.Now()}
+		created, err := st.Notifications.Create(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	checkUnread := func(want int) {
+		t.Helper()
+		if unread, err := st.Notifications.UnreadCount(userID); err != nil || unread != want {
+			t.Errorf("unread count = %d, %v, want %d", unread, err, want)
+		}
+	}
+
+	if !notify(database.NotifyReaction) {
+		t.Fatal("first notification not stored")
+	}
+	if notify(database.NotifyReaction) {
+		t.Error("repeat of an unread notification stored")
+	}
+	checkUnread(1)
+	if !notify(database.NotifyComment) {
+		t.Error("notification of another kind not stored")
+	}
+	checkUnread(2)
+
+	if _, err := st.Notifications.MarkAllRead(userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !notify(database.NotifyReaction) {
+		t.Error("repeat of a read notification not stored")
+	}
+	checkUnread(1)
+}
+
+func TestMemoryNotificationPreferences(t *testing.T) {
+	testNotificationPreferences(t, NewMemoryStore())
+}
+
+// testNotificationPreferences checks that kinds are enabled until turned off and that
+// saving some kinds leaves the others as they were.
+func testNotificationPreferences(t *testing.T, st *Store) {
+	t.Helper()
+	userID := createTestUser(t, st, "alice").ID.String()
+	check := func(want map[string]bool) {
+		t.Helper()
+		preferences, err := st.Notifications.Preferences(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for kind, enabled := range want {
+			if preferences[kind] != enabled {
+				t.Errorf("%s notifications enabled = %v, want %v", kind, preferences[kind], enabled)
+			}
+		}
+	}
+
+	check(map[string]bool{database.NotifyComment: true, database.NotifyReaction: true, database.NotifyMessage: true})
+
+	if err := st.Notifications.SetPreferences(userID, map[string]bool{database.NotifyReaction: false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Notifications.SetPreferences(userID, map[string]bool{database.NotifyMessage: false}); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]bool{database.NotifyComment: true, database.NotifyReaction: false, database.NotifyMessage: false})
+
+	if err := st.Notifications.SetPreferences(userID, map[string]bool{database.NotifyReaction: true}); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]bool{database.NotifyReaction: true, database.NotifyMessage: false})
+}
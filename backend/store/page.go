@@ -0,0 +1,90 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list ordered newest first by creation time, then ID.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+// Encode returns the opaque string form of the cursor handed to clients.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// before reports whether c sorts before other, newest first.
+func (c Cursor) before(other Cursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.After(other.CreatedAt)
+	}
+	return c.ID > other.ID
+}
+
+// Page selects up to Limit items of a newest first list: the items older than After,
+// the items newer than Before, or the newest items when neither is set.
+type Page struct {
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+}
+
+// finishPage trims the Limit+1 items fetched for a page to Limit and puts them
+// newest first, reporting whether there were more in the direction of travel.
+// Items of a Before page are fetched oldest first.
+func finishPage[T any](items []T, page Page) ([]T, bool) {
+	more := len(items) > page.Limit
+	if more {
+		items = items[:page.Limit]
+	}
+	if page.Before != nil {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	return items, more
+}
+
+// pageOf selects a page from items already sorted newest first, for the in-memory store.
+func pageOf[T any](items []T, cursor func(T) Cursor, page Page) ([]T, bool) {
+	selected := []T{}
+	if page.Before != nil {
+		for i := len(items) - 1; i >= 0 && len(selected) <= page.Limit; i-- {
+			if cursor(items[i]).before(*page.Before) {
+				selected = append(selected, items[i])
+			}
+		}
+		return finishPage(selected, page)
+	}
+	for _, item := range items {
+		if len(selected) > page.Limit {
+			break
+		}
+		if page.After == nil || page.After.before(cursor(item)) {
+			selected = append(selected, item)
+		}
+	}
+	return finishPage(selected, page)
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 123456789, time.UTC), ID: 42}
+	decoded, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID {
+		t.Errorf("decoded %+v, want %+v", decoded, c)
+	}
+
+	for _, s := range []string{"", "not base64!", Cursor{CreatedAt: c.CreatedAt}.Encode()} {
+		if _, err := DecodeCursor(s); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestMemoryKeysetTies(t *testing.T) {
+	testKeysetTies(t, NewMemoryStore())
+}
+
+// testKeysetTies pages through a conversation whose messages share creation times,
+// some of them written with other zone offsets, in both directions.
+func testKeysetTies(t *testing.T, st *Store) {
+	t.Helper()
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	east, west := time.FixedZone("east", 5*60*60), time.FixedZone("west", -8*60*60)
+	times := []time.Time{
+		base, base.In(east), base.Add(time.Second).In(west), base, base.Add(-time.Second),
+		base.In(west), base.Add(time.Second), base,
+	}
+
+	var messages []database.Message
+	for i, at := range times {
+		m := database.Message{SenderID: alice, ReceiverID: bob, Content: "message", CreatedAt: at}
+		if i%2 == 1 {
+			m.SenderID, m.ReceiverID = bob, alice
+		}
+		if err := st.Messages.Create(&m); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		a, b := messages[i], messages[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	want := make([]int, len(messages))
+	for i, m := range messages {
+		want[i] = m.ID
+	}
+
+	var older []int
+	page := Page{Limit: 3}
+	for {
+		items, more, err := st.Messages.ListConversation(alice.String(), bob.String(), page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range items {
+			older = append(older, m.ID)
+		}
+		if !more {
+			break
+		}
+		last := items[len(items)-1]
+		page.After = &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if !slices.Equal(older, want) {
+		t.Errorf("paging older got %v, want %v", older, want)
+	}
+
+	var newer []int
+	oldest := messages[len(messages)-1]
+	page = Page{Limit: 3, Before: &Cursor{CreatedAt: oldest.CreatedAt, ID: oldest.ID}}
+	for {
+		items, more, err := st.Messages.ListConversation(alice.String(), bob.String(), page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]int, len(items))
+		for i, m := range items {
+			ids[i] = m.ID
+		}
+		newer = append(ids, newer...)
+		if !more {
+			break
+		}
+		first := items[0]
+		page.Before = &Cursor{CreatedAt: first.CreatedAt, ID: first.ID}
+	}
+	if !slices.Equal(newer, want[:len(want)-1]) {
+		t.Errorf("paging newer got %v, want %v", newer, want[:len(want)-1])
+	}
+}
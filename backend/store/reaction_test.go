@@ -0,0 +1,79 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"testing"
+	"time"
+)
+
+func TestMemoryReactionToggle(t *testing.T) {
+	testReactionToggle(t, NewMemoryStore())
+}
+
+// testReactionToggle applies reactions in turn and checks each user's resulting
+// reaction and the counts left on the target.
+func testReactionToggle(t *testing.T, st *Store) {
+	t.Helper()
+	alice := createTestUser(t, st, "alice")
+	bob := createTestUser(t, st, "bob")
+	post := database.Post{UserID: alice.ID, Title: "Post", Content: "Content", CreatedAt: time.Now()}
+	if err := st.Posts.Create(&post); err != nil {
+		t.Fatal(err)
+	}
+	comment := database.Comment{UserID: bob.ID, PostID: post.ID, Content: "Comment", CreatedAt: time.Now()}
+	if err := st.Comments.Create(&comment); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		user     database.User
+		kind     string
+		want     string
+		likes    int
+		dislikes int
+	}{
+		{alice, database.ReactionLike, database.ReactionLike, 1, 0},
+		{bob, database.ReactionDislike, database.ReactionDislike, 1, 1},
+		{alice, database.ReactionLike, "", 0, 1},
+		{alice, database.ReactionDislike, database.ReactionDislike, 0, 2},
+		{alice, database.ReactionLike, database.ReactionLike, 1, 1},
+	}
+	for i, step := range steps {
+		got, err := st.Reactions.Toggle(step.user.ID.String(), database.TargetPost, post.ID, step.kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts, err := st.Reactions.Counts(database.TargetPost, post.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want || counts.Likes != step.likes || counts.Dislikes != step.dislikes {
+			t.Errorf("step %d: %s by %s = %q with %d likes and %d dislikes, want %q with %d and %d",
+				i, step.kind, step.user.Username, got, counts.Likes, counts.Dislikes, step.want, step.likes, step.dislikes)
+		}
+	}
+
+	stored, err := st.Posts.Get(alice.ID.String(), post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Likes != 1 || stored.Dislikes != 1 || stored.MyReaction != database.ReactionLike {
+		t.Errorf("post read by alice has %d likes, %d dislikes and her %q, want 1, 1 and %q",
+			stored.Likes, stored.Dislikes, stored.MyReaction, database.ReactionLike)
+	}
+
+	// reactions on a comment are counted apart from its post's
+	if _, err := st.Reactions.Toggle(alice.ID.String(), database.TargetComment, comment.ID, database.ReactionLike); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := st.Reactions.Counts(database.TargetComment, comment.ID); err != nil || counts.Likes != 1 || counts.Dislikes != 0 {
+		t.Errorf("comment counts = %+v, %v, want one like", counts, err)
+	}
+	if counts, err := st.Reactions.Counts(database.TargetPost, post.ID); err != nil || counts.Likes != 1 || counts.Dislikes != 1 {
+		t.Errorf("post counts after a comment reaction = %+v, %v, want them unchanged", counts, err)
+	}
+
+	if _, err := st.Reactions.Toggle(alice.ID.String(), database.TargetPost, post.ID+100, database.ReactionLike); err != ErrNotFound {
+		t.Errorf("reacting to a missing post: error %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"strings"
+)
+
+// matchMarkers returns the markers wrapping matched terms in raw snippets. They
+// end in a random tag drawn for every search, so that users cannot forge them by
+// typing the same characters in a post.
+func matchMarkers() (start, end string) {
+	tag := make([]byte, 8)
+	rand.Read(tag)
+	return "\uE000" + hex.EncodeToString(tag), "\uE001" + hex.EncodeToString(tag)
+}
+
+// highlight escapes a raw snippet for HTML and turns the match markers into <mark> tags.
+func highlight(snippet, start, end string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(start, "<mark>", end, "</mark>").Replace(snippet)
+}
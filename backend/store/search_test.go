@@ -0,0 +1,174 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryLastSeenPrivacy(t *testing.T) {
+	testLastSeenPrivacy(t, NewMemoryStore())
+}
+
+// testLastSeenPrivacy checks that user search and contact lists leave out the
+// last seen time of users who hide it.
+func testLastSeenPrivacy(t *testing.T, st *Store) {
+	t.Helper()
+	shown := createTestUser(t, st, "alice_shown")
+	hidden := createTestUser(t, st, "alice_hidden")
+	seen := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, u := range []database.User{shown, hidden} {
+		if err := st.Users.SetLastSeen(u.ID.String(), seen); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Users.SetPrivacy(hidden.ID.String(), map[string]bool{database.ProfileLastSeen: false}); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := st.Search.Users(SearchQuery{Terms: []string{"alice"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLastSeen(t, users, shown, hidden, seen)
+
+	viewer := createTestUser(t, st, "viewer")
+	contacts, err := st.Users.ListContacts(viewer.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLastSeen(t, contacts, shown, hidden, seen)
+}
+
+// checkLastSeen checks that users holds shown with its last seen time and hidden without.
+func checkLastSeen(t *testing.T, users []database.User, shown, hidden database.User, seen time.Time) {
+	t.Helper()
+	if len(users) != 2 {
+		t.Fatalf("found %d users, want 2", len(users))
+	}
+	for _, u := range users {
+		switch u.ID {
+		case shown.ID:
+			if u.LastSeenAt == nil || !u.LastSeenAt.Equal(seen) {
+				t.Errorf("last seen of %s = %v, want %v", u.Username, u.LastSeenAt, seen)
+			}
+		case hidden.ID:
+			if u.LastSeenAt != nil {
+				t.Errorf("last seen of %s = %v, want it hidden", u.Username, u.LastSeenAt)
+			}
+		}
+	}
+}
+
+func TestMemorySearchFilters(t *testing.T) {
+	testSearchFilters(t, NewMemoryStore())
+}
+
+// testSearchFilters narrows a search by author, whatever its case, by category and
+// by creation time.
+func testSearchFilters(t *testing.T, st *Store) {
+	t.Helper()
+	alice := createTestUser(t, st, "alice")
+	bob := createTestUser(t, st, "bob")
+	for _, slug := range []string{"test-go", "test-misc"} {
+		if err := st.Categories.Create(&database.Category{Slug: slug, Name: slug}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 12, 0, 0, 0, time.UTC) }
+	posts := []database.Post{
+		{UserID: alice.ID, Title: "Golang generics", Content: "type parameters", Categories: []string{"test-go"}, CreatedAt: day(1)},
+		{UserID: bob.ID, Title: "Golang channels", Content: "select loops", Categories: []string{"test-misc"}, CreatedAt: day(5)},
+		{UserID: alice.ID, Title: "Golang modules", Content: "go.mod files", Categories: []string{"test-misc"}, CreatedAt: day(10)},
+	}
+	for i := range posts {
+		if err := st.Posts.Create(&posts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comment := database.Comment{UserID: bob.ID, PostID: posts[0].ID, Content: "golang in a comment", CreatedAt: day(6)}
+	if err := st.Comments.Create(&comment); err != nil {
+		t.Fatal(err)
+	}
+
+	since, until := day(5), day(10)
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		posts    []int
+		comments []int
+	}{
+		{"no filter", SearchQuery{}, []int{posts[0].ID, posts[1].ID, posts[2].ID}, []int{comment.ID}},
+		{"author", SearchQuery{Author: "bob"}, []int{posts[1].ID}, []int{comment.ID}},
+		{"author in another case", SearchQuery{Author: "ALICE"}, []int{posts[0].ID, posts[2].ID}, nil},
+		{"unknown author", SearchQuery{Author: "carol"}, nil, nil},
+		{"category", SearchQuery{Categories: []string{"test-misc"}}, []int{posts[1].ID, posts[2].ID}, nil},
+		{"category of the commented post", SearchQuery{Categories: []string{"test-go"}}, []int{posts[0].ID}, []int{comment.ID}},
+		{"since", SearchQuery{Since: &since}, []int{posts[1].ID, posts[2].ID}, []int{comment.ID}},
+		{"until", SearchQuery{Until: &until}, []int{posts[0].ID, posts[1].ID}, []int{comment.ID}},
+		{"author and dates", SearchQuery{Author: "Alice", Since: &since, Until: &until}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			q.Terms, q.ViewerID, q.Limit = []string{"golang"}, alice.ID.String(), 10
+
+			postResults, err := st.Search.Posts(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotPosts []int
+			for _, p := range postResults {
+				gotPosts = append(gotPosts, p.ID)
+			}
+			commentResults, err := st.Search.Comments(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotComments []int
+			for _, c := range commentResults {
+				gotComments = append(gotComments, c.ID)
+			}
+			slices.Sort(gotPosts)
+			if !slices.Equal(gotPosts, tt.posts) || !slices.Equal(gotComments, tt.comments) {
+				t.Errorf("found posts %v and comments %v, want %v and %v", gotPosts, gotComments, tt.posts, tt.comments)
+			}
+		})
+	}
+}
+
+func TestMemorySearchSnippet(t *testing.T) {
+	testSearchSnippet(t, NewMemoryStore())
+}
+
+// testSearchSnippet checks that snippets mark the matched terms and nothing else,
+// even when the text holds markup or the private use characters of the markers.
+func testSearchSnippet(t *testing.T, st *Store) {
+	t.Helper()
+	alice := createTestUser(t, st, "alice")
+	post := database.Post{UserID: alice.ID, Title: "Snippet", Content: "Tricky <b>bold</b> \uE000fake\uE001 text", CreatedAt: time.Now()}
+	if err := st.Posts.Create(&post); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := st.Search.Posts(SearchQuery{Terms: []string{"trick"}, ViewerID: alice.ID.String(), Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("found %d posts, want 1", len(results))
+	}
+	snippet := results[0].Snippet
+	if !strings.Contains(snippet, "<mark>Tricky</mark>") {
+		t.Errorf("snippet %q does not mark the match", snippet)
+	}
+	if n := strings.Count(snippet, "<mark>"); n != 1 {
+		t.Errorf("snippet %q has %d marks, want 1", snippet, n)
+	}
+	if !strings.Contains(snippet, "&lt;b&gt;bold&lt;/b&gt;") {
+		t.Errorf("snippet %q does not escape the post's markup", snippet)
+	}
+}
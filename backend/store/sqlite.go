@@ -0,0 +1,61 @@
+package store
+
+import (
+	"database/sql"
+	"real-time-forum/backend/database"
+)
+
+// NewSQLiteStore returns the repositories backed by the SQLite database.
+func NewSQLiteStore(db *database.Database) *Store {
+	return &Store{
+		Users:         &sqliteUsers{db: db},
+		Sessions:      &sqliteSessions{db: db},
+		Posts:         &sqlitePosts{db: db},
+		Categories:    &sqliteCategories{db: db},
+		Comments:      &sqliteComments{db: db},
+		Messages:      &sqliteMessages{db: db},
+		Reactions:     &sqliteReactions{db: db},
+		Revisions:     &sqliteRevisions{db: db},
+		Mentions:      &sqliteMentions{db: db},
+		Contents:      &sqliteContents{db: db},
+		Attachments:   &sqliteAttachments{db: db},
+		Reports:       &sqliteReports{db: db},
+		ModerationLog: &sqliteModerationLog{db: db},
+		Notifications: &sqliteNotifications{db: db},
+		Search:        &sqliteSearch{db: db},
+	}
+}
+
+// keyset returns the condition selecting the rows of alias that belong to a page,
+// its arguments, and the ORDER BY clause to fetch them in, for use with finishPage.
+// Timestamps are stored as text with the zone offset of the server at the time,
+// so they are compared as julian days, which the pagination indexes are built on.
+// The leading bound on the day alone lets SQLite seek the index to the cursor.
+func keyset(alias string, page Page) (string, []any, string) {
+	createdAt := `julianday(` + alias + `.created_at)`
+	switch {
+	case page.After != nil:
+		c := page.After
+		return `(` + createdAt + ` <= julianday(?) AND (` + createdAt + ` < julianday(?) OR ` + alias + `.id < ?))`,
+			[]any{c.CreatedAt, c.CreatedAt, c.ID},
+			`ORDER BY ` + createdAt + ` DESC, ` + alias + `.id DESC`
+	case page.Before != nil:
+		c := page.Before
+		return `(` + createdAt + ` >= julianday(?) AND (` + createdAt + ` > julianday(?) OR ` + alias + `.id > ?))`,
+			[]any{c.CreatedAt, c.CreatedAt, c.ID},
+			`ORDER BY ` + createdAt + ` ASC, ` + alias + `.id ASC`
+	}
+	return `1`, nil, `ORDER BY ` + createdAt + ` DESC, ` + alias + `.id DESC`
+}
+
+// requireAffected returns ErrNotFound when a statement changed no row.
+func requireAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"real-time-forum/backend/database"
+	"strings"
+)
+
+type sqliteAttachments struct {
+	db *database.Database
+}
+
+const attachmentColumns = `id, uploader_id, post_id, message_id, storage_key, thumbnail_key, filename, mime_type, size, width, height, created_at`
+
+func scanAttachment(row interface{ Scan(...any) error }) (database.Attachment, error) {
+	var a database.Attachment
+	err := row.Scan(&a.ID, &a.UploaderID, &a.PostID, &a.MessageID, &a.StorageKey, &a.ThumbnailKey, &a.Filename, &a.MimeType, &a.Size, &a.Width, &a.Height, &a.CreatedAt)
+	return a, err
+}
+
+// idList returns the placeholders and arguments of an IN list of ids.
+func idList(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return `(?` + strings.Repeat(", ?", len(ids)-1) + `)`, args
+}
+
+// attachmentColumn returns the column of attachment linking it to a post or
+// message, as targetType says.
+func attachmentColumn(targetType string) (string, error) {
+	switch targetType {
+	case database.TargetPost:
+		return "post_id", nil
+	case database.TargetMessage:
+		return "message_id", nil
+	}
+	return "", fmt.Errorf("no attachments on %q", targetType)
+}
+
+// fillAttachments loads the attachments of the posts or messages with the given
+// IDs, as targetType says, into the matching slices of into.
+func fillAttachments(db *sql.DB, targetType string, ids []int, into []*[]database.Attachment) error {
+	byTarget := make(map[int]*[]database.Attachment, len(ids))
+	for i, id := range ids {
+		*into[i] = []database.Attachment{}
+		byTarget[id] = into[i]
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	column, err := attachmentColumn(targetType)
+	if err != nil {
+		return err
+	}
+	in, args := idList(ids)
+	rows, err := db.Query(`SELECT `+attachmentColumns+` FROM attachment WHERE `+column+` IN `+in+` ORDER BY id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return err
+		}
+		targetID := a.PostID
+		if targetType == database.TargetMessage {
+			targetID = a.MessageID
+		}
+		attachments := byTarget[*targetID]
+		*attachments = append(*attachments, a)
+	}
+	return rows.Err()
+}
+
+func (s *sqliteAttachments) Create(a *database.Attachment) error {
+	query := `INSERT INTO attachment (uploader_id, storage_key, thumbnail_key, filename, mime_type, size, width, height, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.DB.Exec(query, a.UploaderID, a.StorageKey, a.ThumbnailKey, a.Filename, a.MimeType, a.Size, a.Width, a.Height, a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = int(id)
+	return nil
+}
+
+func (s *sqliteAttachments) Get(id int) (database.Attachment, error) {
+	a, err := scanAttachment(s.db.DB.QueryRow(`SELECT `+attachmentColumns+` FROM attachment WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return a, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *sqliteAttachments) Unattached(uploaderID string, ids []int) ([]database.Attachment, error) {
+	attachments := []database.Attachment{}
+	if len(ids) == 0 {
+		return attachments, nil
+	}
+	in, args := idList(ids)
+	query := `SELECT ` + attachmentColumns + ` FROM attachment
+        WHERE uploader_id = ? AND post_id IS NULL AND message_id IS NULL AND id IN ` + in + `
+            AND id NOT IN (SELECT avatar_id FROM user WHERE avatar_id IS NOT NULL)
+        ORDER BY id`
+	rows, err := s.db.DB.Query(query, append([]any{uploaderID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (s *sqliteAttachments) Attach(ids []int, targetType string, targetID int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	column, err := attachmentColumn(targetType)
+	if err != nil {
+		return err
+	}
+	in, args := idList(ids)
+	query := `UPDATE attachment SET ` + column + ` = ? WHERE post_id IS NULL AND message_id IS NULL AND id IN ` + in
+	_, err = s.db.DB.Exec(query, append([]any{targetID}, args...)...)
+	return err
+}
+
+func (s *sqliteAttachments) UsedBytes(uploaderID string) (int64, error) {
+	var used int64
+	err := s.db.DB.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM attachment WHERE uploader_id = ?`, uploaderID).Scan(&used)
+	return used, err
+}
@@ -0,0 +1,47 @@
+package store
+
+import "real-time-forum/backend/database"
+
+type sqliteCategories struct {
+	db *database.Database
+}
+
+func (s *sqliteCategories) List() ([]database.Category, error) {
+	query := `
+        SELECT c.id, c.slug, c.name, c.description, c.position, COUNT(p.id)
+        FROM category c
+        LEFT JOIN post_category pc ON pc.category_id = c.id
+        LEFT JOIN post p ON p.id = pc.post_id AND p.deleted_at IS NULL
+        GROUP BY c.id
+        ORDER BY c.position, c.name
+    `
+	rows, err := s.db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []database.Category{}
+	for rows.Next() {
+		var category database.Category
+		if err := rows.Scan(&category.ID, &category.Slug, &category.Name, &category.Description, &category.Position, &category.PostCount); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+func (s *sqliteCategories) Create(category *database.Category) error {
+	query := `INSERT INTO category (slug, name, description, position) VALUES (?, ?, ?, ?)`
+	result, err := s.db.DB.Exec(query, category.Slug, category.Name, category.Description, category.Position)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	category.ID = int(id)
+	return nil
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"database/sql"
+	"real-time-forum/backend/database"
+	"time"
+)
+
+type sqliteComments struct {
+	db *database.Database
+}
+
+// threadCTE returns a WITH clause naming, as answered, the comments of the post postID
+// selects that have a reply below them that was not deleted. Deleted comments among
+// them stay in the thread as placeholders.
+func threadCTE(postID string) string {
+	return `
+        WITH RECURSIVE answered(id) AS (
+            SELECT parent_id FROM comment WHERE post_id = ` + postID + ` AND parent_id IS NOT NULL AND deleted_at IS NULL
+            UNION
+            SELECT c.parent_id FROM comment c JOIN answered a ON c.id = a.id WHERE c.parent_id IS NOT NULL
+        )`
+}
+
+// visibleComment is the condition a comment aliased alias of a thread selected with
+// threadCTE must meet to be listed, either as itself or as a placeholder.
+func visibleComment(alias string) string {
+	return `(` + alias + `.deleted_at IS NULL OR ` + alias + `.id IN answered)`
+}
+
+// commentColumns returns the select columns of a comment aliased c, as scanComment reads them.
+// They take the viewer ID as their only argument and need the answered table of threadCTE.
+func commentColumns() string {
+	return `c.id, c.content, c.content_html, c.user_id, c.post_id, c.parent_id, c.depth, c.created_at, c.edited_at, c.deleted_at IS NOT NULL,
+            (SELECT COUNT(*) FROM comment r WHERE r.parent_id = c.id AND ` + visibleComment("r") + `),
+            ` + mentionColumn(database.TargetComment, "c") + `,
+            ` + reactionColumns("comment", "c")
+}
+
+// scanComment reads a row selected with commentColumns, clearing what placeholders hide.
+func scanComment(row interface{ Scan(...any) error }) (database.Comment, error) {
+	var comment database.Comment
+	var mentions string
+	err := row.Scan(&comment.ID, &comment.Content, &comment.ContentHTML, &comment.UserID, &comment.PostID, &comment.ParentID, &comment.Depth, &comment.CreatedAt, &comment.EditedAt, &comment.Deleted, &comment.ReplyCount, &mentions, &comment.Likes, &comment.Dislikes, &comment.MyReaction)
+	comment.Mentions = splitMentions(mentions)
+	if comment.Deleted {
+		comment = placeholder(comment)
+	}
+	return comment, err
+}
+
+// placeholder clears the content, author, edit marker and reactions of a deleted comment
+// kept in its thread for the sake of its replies.
+func placeholder(comment database.Comment) database.Comment {
+	return database.Comment{
+		ID:         comment.ID,
+		PostID:     comment.PostID,
+		ParentID:   comment.ParentID,
+		Depth:      comment.Depth,
+		CreatedAt:  comment.CreatedAt,
+		Deleted:    true,
+		ReplyCount: comment.ReplyCount,
+	}
+}
+
+// listComments returns a page of the comments of postID meeting condition, which
+// takes args, and whether there are more past the page.
+func (s *sqliteComments) listComments(viewerID string, postID int, condition string, args []any, page Page) ([]database.Comment, bool, error) {
+	pageCondition, pageArgs, order := keyset("c", page)
+	args = append([]any{postID, viewerID}, args...)
+	args = append(args, pageArgs...)
+	args = append(args, page.Limit+1)
+
+	query := threadCTE("?") + `
+        SELECT ` + commentColumns() + `
+        FROM comment c
+        WHERE ` + condition + ` AND ` + visibleComment("c") + ` AND ` + pageCondition + `
+        ` + order + `
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	comments := []database.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	comments, more := finishPage(comments, page)
+	return comments, more, nil
+}
+
+func (s *sqliteComments) ListByPost(viewerID string, postID int, page Page) ([]database.Comment, bool, error) {
+	return s.listComments(viewerID, postID, `c.post_id = ? AND c.parent_id IS NULL`, []any{postID}, page)
+}
+
+func (s *sqliteComments) ListReplies(viewerID string, parentID int, page Page) ([]database.Comment, bool, error) {
+	var postID int
+	err := s.db.DB.QueryRow(`SELECT post_id FROM comment WHERE id = ?`, parentID).Scan(&postID)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return s.listComments(viewerID, postID, `c.parent_id = ?`, []any{parentID}, page)
+}
+
+func (s *sqliteComments) Get(viewerID string, id int) (database.Comment, error) {
+	query := threadCTE(`(SELECT post_id FROM comment WHERE id = ?)`) + `
+        SELECT ` + commentColumns() + ` FROM comment c WHERE c.id = ? AND c.deleted_at IS NULL`
+	comment, err := scanComment(s.db.DB.QueryRow(query, id, viewerID, id))
+	if err == sql.ErrNoRows {
+		return comment, ErrNotFound
+	}
+	return comment, err
+}
+
+func (s *sqliteComments) Create(comment *database.Comment) error {
+	query := `INSERT INTO comment (post_id, parent_id, depth, user_id, content, content_html, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.DB.Exec(query, comment.PostID, comment.ParentID, comment.Depth, comment.UserID, comment.Content, comment.ContentHTML, comment.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	comment.ID = int(id)
+	return nil
+}
+
+func (s *sqliteComments) Update(comment *database.Comment, editorID string) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var content string
+	err = tx.QueryRow(`SELECT content FROM comment WHERE id = ? AND deleted_at IS NULL`, comment.ID).Scan(&content)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO revision (target_type, target_id, content, editor_id, created_at) VALUES ('comment', ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, comment.ID, content, editorID, comment.EditedAt); err != nil {
+		return err
+	}
+	query = `UPDATE comment SET content = ?, content_html = ?, edited_at = ? WHERE id = ?`
+	if _, err := tx.Exec(query, comment.Content, comment.ContentHTML, comment.EditedAt, comment.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteComments) Delete(id int, at time.Time) error {
+	result, err := s.db.DB.Exec(`UPDATE comment SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
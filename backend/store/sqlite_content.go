@@ -0,0 +1,64 @@
+package store
+
+import (
+	"fmt"
+	"real-time-forum/backend/database"
+)
+
+type sqliteContents struct {
+	db *database.Database
+}
+
+// contentTable returns the table holding the posts or comments, as targetType says.
+func contentTable(targetType string) (string, error) {
+	switch targetType {
+	case database.TargetPost:
+		return "post", nil
+	case database.TargetComment:
+		return "comment", nil
+	}
+	return "", fmt.Errorf("no rendered content for %q", targetType)
+}
+
+func (s *sqliteContents) Unrendered(targetType string, afterID, limit int) ([]Source, error) {
+	table, err := contentTable(targetType)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        SELECT t.id, t.content, ` + mentionColumn(targetType, "t") + `
+        FROM ` + table + ` t
+        WHERE t.content_html = '' AND t.id > ?
+        ORDER BY t.id
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []Source{}
+	for rows.Next() {
+		var source Source
+		var mentions string
+		if err := rows.Scan(&source.ID, &source.Content, &mentions); err != nil {
+			return nil, err
+		}
+		source.Mentions = splitMentions(mentions)
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func (s *sqliteContents) SetHTML(targetType string, id int, html string) error {
+	table, err := contentTable(targetType)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.DB.Exec(`UPDATE `+table+` SET content_html = ? WHERE id = ?`, html, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type sqliteMentions struct {
+	db *database.Database
+}
+
+// mentionColumn returns the select column listing the mentions in the content of the
+// target aliased alias, as splitMentions reads them.
+func mentionColumn(targetType, alias string) string {
+	return `(SELECT COALESCE(group_concat(mn.user_id || ' ' || u.username || ' ' || mn.span_start || ' ' || mn.span_end), '')
+            FROM mention mn JOIN user u ON u.id = mn.user_id
+            WHERE mn.target_type = '` + targetType + `' AND mn.target_id = ` + alias + `.id)`
+}
+
+// splitMentions splits the result of mentionColumn, ordering the mentions as they appear.
+func splitMentions(column string) []database.Mention {
+	mentions := []database.Mention{}
+	if column == "" {
+		return mentions
+	}
+	for _, entry := range strings.Split(column, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) != 4 {
+			continue
+		}
+		userID, err := uuid.FromString(fields[0])
+		if err != nil {
+			continue
+		}
+		start, _ := strconv.Atoi(fields[2])
+		end, _ := strconv.Atoi(fields[3])
+		mentions = append(mentions, database.Mention{UserID: userID, Username: fields[1], Start: start, End: end})
+	}
+	sort.Slice(mentions, func(i, j int) bool { return mentions[i].Start < mentions[j].Start })
+	return mentions
+}
+
+func (s *sqliteMentions) Replace(targetType string, targetID int, mentions []database.Mention) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mention WHERE target_type = ? AND target_id = ?`, targetType, targetID); err != nil {
+		return err
+	}
+	query := `INSERT INTO mention (target_type, target_id, user_id, span_start, span_end) VALUES (?, ?, ?, ?, ?)`
+	for _, mention := range mentions {
+		if _, err := tx.Exec(query, targetType, targetID, mention.UserID.String(), mention.Start, mention.End); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"database/sql"
+	"real-time-forum/backend/database"
+	"time"
+)
+
+type sqliteMessages struct {
+	db *database.Database
+}
+
+func (s *sqliteMessages) Create(message *database.Message) error {
+	query := `INSERT INTO message (sender_id, receiver_id, content, created_at) VALUES (?, ?, ?, ?)`
+	result, err := s.db.DB.Exec(query, message.SenderID, message.ReceiverID, message.Content, message.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	message.ID = int(id)
+	return nil
+}
+
+func (s *sqliteMessages) ListConversation(userID, otherUserID string, page Page) ([]database.Message, bool, error) {
+	condition, args, order := keyset("m", page)
+	args = append([]any{userID, otherUserID, otherUserID, userID}, args...)
+	args = append(args, page.Limit+1)
+
+	query := `
+		SELECT m.id, m.sender_id, m.receiver_id, m.content, m.created_at, m.edited_at, m.read_at,
+			` + mentionColumn(database.TargetMessage, "m") + `
+		FROM message m
+		WHERE ((m.sender_id = ? AND m.receiver_id = ?) OR (m.sender_id = ? AND m.receiver_id = ?))
+			AND m.deleted_at IS NULL AND ` + condition + `
+		` + order + `
+		LIMIT ?
+	`
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	messages := []database.Message{}
+	for rows.Next() {
+		var message database.Message
+		var mentions string
+		if err := rows.Scan(&message.ID, &message.SenderID, &message.ReceiverID, &message.Content, &message.CreatedAt, &message.EditedAt, &message.ReadAt, &mentions); err != nil {
+			return nil, false, err
+		}
+		message.Mentions = splitMentions(mentions)
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	messages, more := finishPage(messages, page)
+
+	ids := make([]int, len(messages))
+	into := make([]*[]database.Attachment, len(messages))
+	for i := range messages {
+		ids[i], into[i] = messages[i].ID, &messages[i].Attachments
+	}
+	if err := fillAttachments(s.db.DB, database.TargetMessage, ids, into); err != nil {
+		return nil, false, err
+	}
+	return messages, more, nil
+}
+
+func (s *sqliteMessages) Get(id int) (database.Message, error) {
+	var message database.Message
+	var mentions string
+	query := `SELECT m.id, m.sender_id, m.receiver_id, m.content, m.created_at, m.edited_at, m.read_at, ` + mentionColumn(database.TargetMessage, "m") + ` FROM message m WHERE m.id = ? AND m.deleted_at IS NULL`
+	err := s.db.DB.QueryRow(query, id).Scan(&message.ID, &message.SenderID, &message.ReceiverID, &message.Content, &message.CreatedAt, &message.EditedAt, &message.ReadAt, &mentions)
+	if err == sql.ErrNoRows {
+		return message, ErrNotFound
+	}
+	if err != nil {
+		return message, err
+	}
+	message.Mentions = splitMentions(mentions)
+	err = fillAttachments(s.db.DB, database.TargetMessage, []int{id}, []*[]database.Attachment{&message.Attachments})
+	return message, err
+}
+
+func (s *sqliteMessages) Update(message *database.Message) error {
+	query := `UPDATE message SET content = ?, edited_at = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := s.db.DB.Exec(query, message.Content, message.EditedAt, message.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteMessages) Delete(id int, at time.Time) error {
+	result, err := s.db.DB.Exec(`UPDATE message SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteMessages) MarkRead(readerID, senderID string, upTo int, at time.Time) (int64, error) {
+	query := `UPDATE message SET read_at = ? WHERE sender_id = ? AND receiver_id = ? AND id <= ? AND read_at IS NULL AND deleted_at IS NULL`
+	result, err := s.db.DB.Exec(query, at, senderID, readerID, upTo)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package store
+
+import "real-time-forum/backend/database"
+
+type sqliteModerationLog struct {
+	db *database.Database
+}
+
+func (s *sqliteModerationLog) Add(action *database.ModerationAction) error {
+	query := `INSERT INTO moderation_log (moderator_id, action, target_type, target_id, note, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := s.db.DB.Exec(query, action.ModeratorID.String(), action.Action, action.TargetType, action.TargetID, action.Note, action.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	action.ID = int(id)
+	return nil
+}
+
+func (s *sqliteModerationLog) List(page Page) ([]database.ModerationAction, bool, error) {
+	condition, args, order := keyset("l", page)
+	args = append(args, page.Limit+1)
+
+	query := `
+        SELECT l.id, l.moderator_id, l.action, l.target_type, l.target_id, l.note, l.created_at
+        FROM moderation_log l
+        WHERE ` + condition + `
+        ` + order + `
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	actions := []database.ModerationAction{}
+	for rows.Next() {
+		var action database.ModerationAction
+		if err := rows.Scan(&action.ID, &action.ModeratorID, &action.Action, &action.TargetType, &action.TargetID, &action.Note, &action.CreatedAt); err != nil {
+			return nil, false, err
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	actions, more := finishPage(actions, page)
+	return actions, more, nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"time"
+)
+
+type sqliteNotifications struct {
+	db *database.Database
+}
+
+func (s *sqliteNotifications) Create(n *database.Notification) (bool, error) {
+	query := `
+        INSERT INTO notification (user_id, actor_id, type, post_id, comment_id, message_id, created_at)
+        SELECT ?, ?, ?, ?, ?, ?, ?
+        WHERE NOT EXISTS (
+            SELECT 1 FROM notification
+            WHERE user_id = ? AND actor_id = ? AND type = ? AND read_at IS NULL
+                AND post_id IS ? AND comment_id IS ? AND message_id IS ?
+        )
+    `
+	userID, actorID := n.UserID.String(), n.ActorID.String()
+	result, err := s.db.DB.Exec(query,
+		userID, actorID, n.Type, n.PostID, n.CommentID, n.MessageID, n.CreatedAt,
+		userID, actorID, n.Type, n.PostID, n.CommentID, n.MessageID)
+	if err != nil {
+		return false, err
+	}
+	if count, err := result.RowsAffected(); err != nil || count == 0 {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	n.ID = int(id)
+	return true, nil
+}
+
+func (s *sqliteNotifications) List(userID string, unreadOnly bool, page Page) ([]database.Notification, bool, error) {
+	condition, args, order := keyset("n", page)
+	args = append([]any{userID, unreadOnly}, args...)
+	args = append(args, page.Limit+1)
+
+	query := `
+        SELECT n.id, n.user_id, n.actor_id, n.type, n.post_id, n.comment_id, n.message_id, n.created_at, n.read_at
+        FROM notification n
+        WHERE n.user_id = ? AND (NOT ? OR n.read_at IS NULL) AND ` + condition + `
+        ` + order + `
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	notifications := []database.Notification{}
+	for rows.Next() {
+		var n database.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.ActorID, &n.Type, &n.PostID, &n.CommentID, &n.MessageID, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, false, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	notifications, more := finishPage(notifications, page)
+	return notifications, more, nil
+}
+
+func (s *sqliteNotifications) UnreadCount(userID string) (int, error) {
+	var count int
+	err := s.db.DB.QueryRow(`SELECT COUNT(*) FROM notification WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+func (s *sqliteNotifications) MarkRead(userID string, id int, at time.Time) error {
+	query := `UPDATE notification SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`
+	result, err := s.db.DB.Exec(query, at, id, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteNotifications) MarkAllRead(userID string, at time.Time) (int64, error) {
+	result, err := s.db.DB.Exec(`UPDATE notification SET read_at = ? WHERE user_id = ? AND read_at IS NULL`, at, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (s *sqliteNotifications) Preferences(userID string) (map[string]bool, error) {
+	preferences := make(map[string]bool, len(database.NotificationTypes))
+	for _, kind := range database.NotificationTypes {
+		preferences[kind] = true
+	}
+
+	rows, err := s.db.DB.Query(`SELECT type, enabled FROM notification_preference WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var enabled bool
+		if err := rows.Scan(&kind, &enabled); err != nil {
+			return nil, err
+		}
+		preferences[kind] = enabled
+	}
+	return preferences, rows.Err()
+}
+
+func (s *sqliteNotifications) SetPreferences(userID string, preferences map[string]bool) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO notification_preference (user_id, type, enabled) VALUES (?, ?, ?)
+        ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled
+    `
+	for kind, enabled := range preferences {
+		if _, err := tx.Exec(query, userID, kind, enabled); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"database/sql"
+	"real-time-forum/backend/database"
+	"strings"
+	"time"
+)
+
+type sqlitePosts struct {
+	db *database.Database
+}
+
+func (s *sqlitePosts) List(viewerID string, categories []string, page Page) ([]database.Post, bool, error) {
+	condition, args, order := keyset("p", page)
+	args = append([]any{viewerID}, args...)
+	if len(categories) > 0 {
+		categoryFilter, categoryArgs := categoryCondition("p.id", categories)
+		condition += ` AND ` + categoryFilter
+		args = append(args, categoryArgs...)
+	}
+	args = append(args, page.Limit+1)
+
+	query := `
+        SELECT ` + postColumns() + `
+        FROM post p
+        WHERE p.deleted_at IS NULL AND ` + condition + `
+        ` + order + `
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	posts := []database.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	posts, more := finishPage(posts, page)
+	ids := make([]int, len(posts))
+	into := make([]*[]database.Attachment, len(posts))
+	for i := range posts {
+		ids[i], into[i] = posts[i].ID, &posts[i].Attachments
+	}
+	if err := fillAttachments(s.db.DB, database.TargetPost, ids, into); err != nil {
+		return nil, false, err
+	}
+	return posts, more, nil
+}
+
+func (s *sqlitePosts) Get(viewerID string, id int) (database.Post, error) {
+	query := `SELECT ` + postColumns() + ` FROM post p WHERE p.id = ? AND p.deleted_at IS NULL`
+	post, err := scanPost(s.db.DB.QueryRow(query, viewerID, id))
+	if err == sql.ErrNoRows {
+		return post, ErrNotFound
+	}
+	if err != nil {
+		return post, err
+	}
+	err = fillAttachments(s.db.DB, database.TargetPost, []int{id}, []*[]database.Attachment{&post.Attachments})
+	return post, err
+}
+
+// postColumns returns the select columns of a post aliased p, as scanPost reads them.
+// They take the viewer ID as their only argument.
+func postColumns() string {
+	return `p.id, p.user_id, p.title, p.content, p.content_html, p.created_at, p.edited_at, p.locked_at,
+            ` + categoryColumn("p") + `,
+            ` + mentionColumn(database.TargetPost, "p") + `,
+            ` + reactionColumns("post", "p")
+}
+
+// scanPost reads a row selected with postColumns.
+func scanPost(row interface{ Scan(...any) error }) (database.Post, error) {
+	var post database.Post
+	var slugs, mentions string
+	err := row.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.ContentHTML, &post.CreatedAt, &post.EditedAt, &post.LockedAt, &slugs, &mentions, &post.Likes, &post.Dislikes, &post.MyReaction)
+	post.Categories = splitSlugs(slugs)
+	post.Mentions = splitMentions(mentions)
+	return post, err
+}
+
+// categoryColumn returns the select column listing the category slugs of the post aliased alias.
+func categoryColumn(alias string) string {
+	return `(SELECT COALESCE(group_concat(c.slug), '') FROM post_category pc JOIN category c ON c.id = pc.category_id WHERE pc.post_id = ` + alias + `.id)`
+}
+
+// splitSlugs splits the result of categoryColumn.
+func splitSlugs(slugs string) []string {
+	if slugs == "" {
+		return []string{}
+	}
+	return strings.Split(slugs, ",")
+}
+
+// categoryCondition returns a condition true when the post identified by postID
+// is filed under one of the categories, along with its arguments.
+func categoryCondition(postID string, categories []string) (string, []any) {
+	condition := `EXISTS (
+            SELECT 1 FROM post_category pc
+            JOIN category c ON c.id = pc.category_id
+            WHERE pc.post_id = ` + postID + ` AND c.slug IN (?` + strings.Repeat(", ?", len(categories)-1) + `)
+        )`
+	args := make([]any, len(categories))
+	for i, slug := range categories {
+		args[i] = slug
+	}
+	return condition, args
+}
+
+func (s *sqlitePosts) Create(post *database.Post) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO post (user_id, title, content, content_html, created_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, post.UserID, post.Title, post.Content, post.ContentHTML, post.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := fileUnder(tx, id, post.Categories); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	post.ID = int(id)
+	return nil
+}
+
+// fileUnder links a post to the categories with the given slugs.
+func fileUnder(tx *sql.Tx, postID int64, categories []string) error {
+	query := `INSERT OR IGNORE INTO post_category (post_id, category_id) SELECT ?, id FROM category WHERE slug = ?`
+	for _, slug := range categories {
+		if _, err := tx.Exec(query, postID, slug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlitePosts) Update(post *database.Post, editorID string) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var title, content string
+	err = tx.QueryRow(`SELECT title, content FROM post WHERE id = ? AND deleted_at IS NULL`, post.ID).Scan(&title, &content)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO revision (target_type, target_id, title, content, editor_id, created_at) VALUES ('post', ?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, post.ID, title, content, editorID, post.EditedAt); err != nil {
+		return err
+	}
+	query = `UPDATE post SET title = ?, content = ?, content_html = ?, edited_at = ? WHERE id = ?`
+	if _, err := tx.Exec(query, post.Title, post.Content, post.ContentHTML, post.EditedAt, post.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM post_category WHERE post_id = ?`, post.ID); err != nil {
+		return err
+	}
+	if err := fileUnder(tx, int64(post.ID), post.Categories); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlitePosts) Delete(id int, at time.Time) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE post SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+
+	// the comments of a deleted post go with it
+	if _, err := tx.Exec(`UPDATE comment SET deleted_at = ? WHERE post_id = ? AND deleted_at IS NULL`, at, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlitePosts) SetLocked(id int, at *time.Time) error {
+	result, err := s.db.DB.Exec(`UPDATE post SET locked_at = ? WHERE id = ? AND deleted_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
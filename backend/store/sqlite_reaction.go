@@ -0,0 +1,86 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"real-time-forum/backend/database"
+	"time"
+)
+
+// reactionTables maps reaction targets to the table holding them.
+var reactionTables = map[string]string{
+	database.TargetPost:    "post",
+	database.TargetComment: "comment",
+}
+
+// reactionColumns returns the select columns for the like count, dislike count and the
+// viewer's own reaction on rows of alias, which take the viewer ID as their only argument.
+func reactionColumns(targetType, alias string) string {
+	return fmt.Sprintf(`
+            (SELECT COUNT(*) FROM reaction r WHERE r.target_type = '%[1]s' AND r.target_id = %[2]s.id AND r.kind = 'like'),
+            (SELECT COUNT(*) FROM reaction r WHERE r.target_type = '%[1]s' AND r.target_id = %[2]s.id AND r.kind = 'dislike'),
+            COALESCE((SELECT r.kind FROM reaction r WHERE r.target_type = '%[1]s' AND r.target_id = %[2]s.id AND r.user_id = ?), '')`,
+		targetType, alias)
+}
+
+type sqliteReactions struct {
+	db *database.Database
+}
+
+func (s *sqliteReactions) Toggle(userID, targetType string, targetID int, kind string) (string, error) {
+	table, ok := reactionTables[targetType]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(`SELECT 1 FROM `+table+` WHERE id = ? AND deleted_at IS NULL`, targetID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var current string
+	query := `SELECT kind FROM reaction WHERE user_id = ? AND target_type = ? AND target_id = ?`
+	err = tx.QueryRow(query, userID, targetType, targetID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if current == kind {
+		query = `DELETE FROM reaction WHERE user_id = ? AND target_type = ? AND target_id = ?`
+		_, err = tx.Exec(query, userID, targetType, targetID)
+		kind = ""
+	} else {
+		query = `
+			INSERT INTO reaction (user_id, target_type, target_id, kind, created_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, target_type, target_id) DO UPDATE SET kind = excluded.kind, created_at = excluded.created_at
+		`
+		_, err = tx.Exec(query, userID, targetType, targetID, kind, time.Now())
+	}
+	if err != nil {
+		return "", err
+	}
+	return kind, tx.Commit()
+}
+
+func (s *sqliteReactions) Counts(targetType string, targetID int) (database.ReactionCounts, error) {
+	var counts database.ReactionCounts
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN kind = 'like' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN kind = 'dislike' THEN 1 ELSE 0 END), 0)
+		FROM reaction
+		WHERE target_type = ? AND target_id = ?
+	`
+	err := s.db.DB.QueryRow(query, targetType, targetID).Scan(&counts.Likes, &counts.Dislikes)
+	return counts, err
+}
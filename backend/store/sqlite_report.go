@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"real-time-forum/backend/database"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type sqliteReports struct {
+	db *database.Database
+}
+
+const reportColumns = `r.id, r.reporter_id, r.target_type, r.target_id, r.reason, r.details, r.content, r.status, r.reviewer_id, r.created_at, r.updated_at`
+
+// scanReport reads a row selected with reportColumns.
+func scanReport(row interface{ Scan(...any) error }) (database.Report, error) {
+	var report database.Report
+	err := row.Scan(&report.ID, &report.ReporterID, &report.TargetType, &report.TargetID, &report.Reason, &report.Details,
+		&report.Content, &report.Status, &report.ReviewerID, &report.CreatedAt, &report.UpdatedAt)
+	return report, err
+}
+
+func (s *sqliteReports) Create(report *database.Report) error {
+	query := `
+        INSERT INTO report (reporter_id, target_type, target_id, reason, details, content, status, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `
+	result, err := s.db.DB.Exec(query, report.ReporterID.String(), report.TargetType, report.TargetID, report.Reason,
+		report.Details, report.Content, report.Status, report.CreatedAt, report.UpdatedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return ErrAlreadyReported
+		}
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	report.ID = int(id)
+	return nil
+}
+
+func (s *sqliteReports) Get(id int) (database.Report, error) {
+	report, err := scanReport(s.db.DB.QueryRow(`SELECT `+reportColumns+` FROM report r WHERE r.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return report, ErrNotFound
+	}
+	return report, err
+}
+
+func (s *sqliteReports) List(filter ReportFilter, page Page) ([]database.Report, bool, error) {
+	condition, args, order := keyset("r", page)
+	for _, f := range []struct{ column, value string }{
+		{"status", filter.Status},
+		{"target_type", filter.TargetType},
+		{"reason", filter.Reason},
+	} {
+		if f.value != "" {
+			condition += ` AND r.` + f.column + ` = ?`
+			args = append(args, f.value)
+		}
+	}
+	args = append(args, page.Limit+1)
+
+	query := `
+        SELECT ` + reportColumns + `
+        FROM report r
+        WHERE ` + condition + `
+        ` + order + `
+        LIMIT ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	reports := []database.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	reports, more := finishPage(reports, page)
+	return reports, more, nil
+}
+
+func (s *sqliteReports) SetStatus(report *database.Report, from string) error {
+	var reviewerID any
+	if report.ReviewerID != nil {
+		reviewerID = report.ReviewerID.String()
+	}
+	query := `UPDATE report SET status = ?, reviewer_id = ?, updated_at = ? WHERE id = ? AND status = ?`
+	result, err := s.db.DB.Exec(query, report.Status, reviewerID, report.UpdatedAt, report.ID, from)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return ErrStatusChanged
+	}
+	return nil
+}
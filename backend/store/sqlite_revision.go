@@ -0,0 +1,31 @@
+package store
+
+import "real-time-forum/backend/database"
+
+type sqliteRevisions struct {
+	db *database.Database
+}
+
+func (s *sqliteRevisions) List(targetType string, targetID int) ([]database.Revision, error) {
+	query := `
+        SELECT id, target_type, target_id, title, content, editor_id, created_at
+        FROM revision
+        WHERE target_type = ? AND target_id = ?
+        ORDER BY created_at, id
+    `
+	rows, err := s.db.DB.Query(query, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []database.Revision{}
+	for rows.Next() {
+		var revision database.Revision
+		if err := rows.Scan(&revision.ID, &revision.TargetType, &revision.TargetID, &revision.Title, &revision.Content, &revision.EditorID, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
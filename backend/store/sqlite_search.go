@@ -0,0 +1,186 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"strings"
+)
+
+type sqliteSearch struct {
+	db *database.Database
+}
+
+// matchExpression turns search terms into an FTS5 query matching every term as a prefix.
+// Terms are quoted so that FTS5 operators typed by users are searched for literally.
+func matchExpression(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// snippetColumn returns the select column for the excerpt of an FTS5 table match,
+// with matched terms wrapped in the markers given as its two arguments.
+func snippetColumn(table string) string {
+	return `snippet(` + table + `, -1, ?, ?, '…', 16)`
+}
+
+// searchFilters returns the author, category and date conditions of a query for rows
+// whose author is userID, post is postID and creation time is createdAt, along with
+// their arguments.
+func searchFilters(q SearchQuery, userID, postID, createdAt string) (string, []any) {
+	var conditions string
+	var args []any
+	if q.Author != "" {
+		conditions += ` AND ` + userID + ` = (SELECT id FROM user WHERE username = ? COLLATE NOCASE)`
+		args = append(args, q.Author)
+	}
+	if len(q.Categories) > 0 {
+		condition, categoryArgs := categoryCondition(postID, q.Categories)
+		conditions += ` AND ` + condition
+		args = append(args, categoryArgs...)
+	}
+	if q.Since != nil {
+		conditions += ` AND julianday(` + createdAt + `) >= julianday(?)`
+		args = append(args, *q.Since)
+	}
+	if q.Until != nil {
+		conditions += ` AND julianday(` + createdAt + `) < julianday(?)`
+		args = append(args, *q.Until)
+	}
+	return conditions, args
+}
+
+func (s *sqliteSearch) Posts(q SearchQuery) ([]database.PostResult, error) {
+	filters, filterArgs := searchFilters(q, "p.user_id", "p.id", "p.created_at")
+	start, end := matchMarkers()
+	args := append([]any{q.ViewerID, start, end, matchExpression(q.Terms)}, filterArgs...)
+	args = append(args, q.Limit, q.Offset)
+
+	query := `
+        SELECT p.id, p.user_id, p.title, p.content, p.content_html, p.created_at, p.edited_at, p.locked_at,
+            ` + categoryColumn("p") + `,
+            ` + mentionColumn(database.TargetPost, "p") + `,
+            ` + reactionColumns("post", "p") + `,
+            ` + snippetColumn("post_fts") + `
+        FROM post_fts
+        JOIN post p ON p.id = post_fts.rowid
+        WHERE post_fts MATCH ? AND p.deleted_at IS NULL` + filters + `
+        ORDER BY bm25(post_fts, 10.0, 1.0)
+        LIMIT ? OFFSET ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []database.PostResult{}
+	for rows.Next() {
+		var result database.PostResult
+		var slugs, mentions string
+		p := &result.Post
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Content, &p.ContentHTML, &p.CreatedAt, &p.EditedAt, &p.LockedAt, &slugs, &mentions, &p.Likes, &p.Dislikes, &p.MyReaction, &result.Snippet); err != nil {
+			return nil, err
+		}
+		p.Categories = splitSlugs(slugs)
+		p.Mentions = splitMentions(mentions)
+		result.Snippet = highlight(result.Snippet, start, end)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(results))
+	into := make([]*[]database.Attachment, len(results))
+	for i := range results {
+		ids[i], into[i] = results[i].ID, &results[i].Attachments
+	}
+	if err := fillAttachments(s.db.DB, database.TargetPost, ids, into); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *sqliteSearch) Comments(q SearchQuery) ([]database.CommentResult, error) {
+	filters, filterArgs := searchFilters(q, "cm.user_id", "cm.post_id", "cm.created_at")
+	start, end := matchMarkers()
+	args := append([]any{q.ViewerID, start, end, matchExpression(q.Terms)}, filterArgs...)
+	args = append(args, q.Limit, q.Offset)
+
+	query := `
+        SELECT cm.id, cm.content, cm.content_html, cm.user_id, cm.post_id, cm.parent_id, cm.depth, cm.created_at, cm.edited_at,
+            ` + mentionColumn(database.TargetComment, "cm") + `,
+            ` + reactionColumns("comment", "cm") + `,
+            ` + snippetColumn("comment_fts") + `
+        FROM comment_fts
+        JOIN comment cm ON cm.id = comment_fts.rowid
+        WHERE comment_fts MATCH ? AND cm.deleted_at IS NULL` + filters + `
+        ORDER BY bm25(comment_fts)
+        LIMIT ? OFFSET ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []database.CommentResult{}
+	for rows.Next() {
+		var result database.CommentResult
+		var mentions string
+		c := &result.Comment
+		if err := rows.Scan(&c.ID, &c.Content, &c.ContentHTML, &c.UserID, &c.PostID, &c.ParentID, &c.Depth, &c.CreatedAt, &c.EditedAt, &mentions, &c.Likes, &c.Dislikes, &c.MyReaction, &result.Snippet); err != nil {
+			return nil, err
+		}
+		c.Mentions = splitMentions(mentions)
+		result.Snippet = highlight(result.Snippet, start, end)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqliteSearch) Users(q SearchQuery) ([]database.User, error) {
+	var conditions []string
+	args := []any{database.ProfileLastSeen}
+	for _, term := range q.Terms {
+		conditions = append(conditions, `u.username LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	// usernames starting with the first term rank first, then shorter ones
+	args = append(args, escapeLike(q.Terms[0])+"%", q.Limit, q.Offset)
+
+	query := `
+        SELECT u.id, u.username, u.last_seen_at, COALESCE(pp.visible, 1)
+        FROM user u
+        LEFT JOIN profile_privacy pp ON pp.user_id = u.id AND pp.field = ?
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY u.username LIKE ? ESCAPE '\' DESC, length(u.username), u.username
+        LIMIT ? OFFSET ?
+    `
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []database.User{}
+	for rows.Next() {
+		var user database.User
+		var lastSeenVisible bool
+		if err := rows.Scan(&user.ID, &user.Username, &user.LastSeenAt, &lastSeenVisible); err != nil {
+			return nil, err
+		}
+		if !lastSeenVisible {
+			user.LastSeenAt = nil
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"database/sql"
+	"real-time-forum/backend/database"
+	"time"
+)
+
+type sqliteSessions struct {
+	db *database.Database
+}
+
+func (s *sqliteSessions) Create(session database.Session, tokenHash string) error {
+	query := `INSERT INTO session (id, user_id, token_hash, created_at, last_used_at, expires_at, user_agent, ip) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.DB.Exec(query, session.ID, session.UserID.String(), tokenHash, session.CreatedAt, session.LastUsedAt, session.ExpiresAt, session.UserAgent, session.IP)
+	return err
+}
+
+func (s *sqliteSessions) GetByTokenHash(tokenHash string, now time.Time) (database.Session, error) {
+	var session database.Session
+	query := `
+		SELECT s.id, s.user_id, u.username, u.role, s.created_at, s.last_used_at, s.expires_at, s.user_agent, s.ip
+		FROM session s
+		JOIN user u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.expires_at > ?
+	`
+	row := s.db.DB.QueryRow(query, tokenHash, now)
+	err := row.Scan(&session.ID, &session.UserID, &session.Username, &session.Role, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.UserAgent, &session.IP)
+	if err == sql.ErrNoRows {
+		return session, ErrNotFound
+	}
+	return session, err
+}
+
+func (s *sqliteSessions) Touch(id string, lastUsedAt, expiresAt time.Time) error {
+	query := `UPDATE session SET last_used_at = ?, expires_at = ? WHERE id = ?`
+	_, err := s.db.DB.Exec(query, lastUsedAt, expiresAt, id)
+	return err
+}
+
+func (s *sqliteSessions) ListByUser(userID string, now time.Time) ([]database.Session, error) {
+	query := `SELECT id, user_id, created_at, last_used_at, expires_at, user_agent, ip FROM session WHERE user_id = ? AND expires_at > ? ORDER BY last_used_at DESC`
+	rows, err := s.db.DB.Query(query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []database.Session{}
+	for rows.Next() {
+		var session database.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.UserAgent, &session.IP); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *sqliteSessions) Delete(userID, id string) error {
+	query := `DELETE FROM session WHERE id = ? AND user_id = ?`
+	result, err := s.db.DB.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteSessions) DeleteExpired(userID string, now time.Time) error {
+	query := `DELETE FROM session WHERE user_id = ? AND expires_at <= ?`
+	_, err := s.db.DB.Exec(query, userID, now)
+	return err
+}
+
+func (s *sqliteSessions) DeleteByUser(userID string) error {
+	_, err := s.db.DB.Exec(`DELETE FROM session WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,112 @@
+//go:build sqlite_fts5
+
+package store
+
+import (
+	"path/filepath"
+	"real-time-forum/backend/database"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteStore(t *testing.T) *Store {
+	t.Helper()
+	db := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLiteStore(db)
+}
+
+func TestSQLiteKeysetTies(t *testing.T) {
+	testKeysetTies(t, newTestSQLiteStore(t))
+}
+
+// TestKeysetUsesIndexes checks that pages of every keyset paginated list are read
+// from their index, seeking to the cursor, rather than sorting the whole table.
+func TestKeysetUsesIndexes(t *testing.T) {
+	st := newTestSQLiteStore(t)
+	db := st.Users.(*sqliteUsers).db
+	cursor := &Cursor{CreatedAt: time.Now(), ID: 10}
+
+	tests := []struct {
+		alias, from, where string
+		args               []any
+		index              string
+	}{
+		{"p", "post p", "p.deleted_at IS NULL", nil, "idx_post_created"},
+		{"c", "comment c", "c.post_id = ?", []any{1}, "idx_comment_post_created"},
+		{"c", "comment c", "c.parent_id = ?", []any{1}, "idx_comment_parent_created"},
+		{"n", "notification n", "n.user_id = ?", []any{"user"}, "idx_notification_inbox"},
+		{"r", "report r", "r.status = ?", []any{database.ReportOpen}, "idx_report_queue"},
+		{"l", "moderation_log l", "1", nil, "idx_moderation_log_created"},
+	}
+	for _, tt := range tests {
+		for _, page := range []Page{{Limit: 20}, {Limit: 20, After: cursor}, {Limit: 20, Before: cursor}} {
+			condition, args, order := keyset(tt.alias, page)
+			query := `EXPLAIN QUERY PLAN SELECT ` + tt.alias + `.id FROM ` + tt.from + ` WHERE ` + tt.where + ` AND ` + condition + ` ` + order + ` LIMIT 21`
+			plan := queryPlan(t, db, query, append(tt.args, args...)...)
+
+			if !strings.Contains(plan, "USING INDEX "+tt.index) || strings.Contains(plan, "TEMP B-TREE") {
+				t.Errorf("%s with after=%v before=%v: plan %q, want it read from %s", tt.from, page.After != nil, page.Before != nil, plan, tt.index)
+			}
+			if page.After != nil && !strings.Contains(plan, "<expr><?") || page.Before != nil && !strings.Contains(plan, "<expr>>?") {
+				t.Errorf("%s with after=%v before=%v: plan %q, want a seek to the cursor", tt.from, page.After != nil, page.Before != nil, plan)
+			}
+		}
+	}
+}
+
+// queryPlan returns the details of an EXPLAIN QUERY PLAN query, one step per line.
+func queryPlan(t *testing.T, db *database.Database, query string, args ...any) string {
+	t.Helper()
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var steps []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(steps, "\n")
+}
+
+func TestSQLiteLastSeenPrivacy(t *testing.T) {
+	testLastSeenPrivacy(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteSearchFilters(t *testing.T) {
+	testSearchFilters(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteSearchSnippet(t *testing.T) {
+	testSearchSnippet(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteReactionToggle(t *testing.T) {
+	testReactionToggle(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteCategories(t *testing.T) {
+	testCategories(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteNotificationDedup(t *testing.T) {
+	testNotificationDedup(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteNotificationPreferences(t *testing.T) {
+	testNotificationPreferences(t, newTestSQLiteStore(t))
+}
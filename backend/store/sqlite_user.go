@@ -0,0 +1,298 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"real-time-forum/backend/database"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type sqliteUsers struct {
+	db *database.Database
+}
+
+func (s *sqliteUsers) Create(user database.User, passwordHash string) error {
+	query := `INSERT INTO user (id, username, email, password, first_name, last_name, age, gender, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.DB.Exec(query, user.ID.String(), user.Username, user.Email, passwordHash, user.FirstName, user.LastName, user.Age, user.Gender, user.CreatedAt)
+	return uniqueUserError(err)
+}
+
+// uniqueUserError maps a violation of the username or email unique constraints
+// to ErrUsernameTaken or ErrEmailTaken.
+func uniqueUserError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+	switch message := sqliteErr.Error(); {
+	case strings.Contains(message, "user.username"), strings.Contains(message, "idx_user_username_nocase"):
+		return ErrUsernameTaken
+	case strings.Contains(message, "user.email"), strings.Contains(message, "idx_user_email_nocase"):
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (s *sqliteUsers) GetByLogin(emailOrUsername string) (database.User, error) {
+	var user database.User
+	query := `SELECT id, username, email, password, first_name, last_name, age, gender, role, banned_at, suspended_until FROM user WHERE email = ? COLLATE NOCASE OR username = ? COLLATE NOCASE`
+	row := s.db.DB.QueryRow(query, emailOrUsername, emailOrUsername)
+
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.Age, &user.Gender, &user.Role, &user.BannedAt, &user.SuspendedUntil)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+func (s *sqliteUsers) Get(userID string) (database.User, error) {
+	var user database.User
+	query := `SELECT id, username, last_seen_at, role, banned_at, suspended_until FROM user WHERE id = ?`
+	err := s.db.DB.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.LastSeenAt, &user.Role, &user.BannedAt, &user.SuspendedUntil)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+func (s *sqliteUsers) ListContacts(userID string) ([]database.User, error) {
+	query := `
+        SELECT
+            u.id,
+            u.username,
+            u.last_seen_at,
+            COALESCE(pp.visible, 1),
+            COALESCE(m.unread, 0)
+        FROM
+            user u
+        LEFT JOIN profile_privacy pp ON pp.user_id = u.id AND pp.field = ?
+        LEFT JOIN (
+            SELECT
+                CASE
+                    WHEN sender_id = ? THEN receiver_id
+                    ELSE sender_id
+                END AS user_id,
+                MAX(created_at) AS latest_message_time,
+                SUM(CASE WHEN receiver_id = ? AND read_at IS NULL THEN 1 ELSE 0 END) AS unread
+            FROM message
+            WHERE (sender_id = ? OR receiver_id = ?) AND deleted_at IS NULL
+            GROUP BY
+                CASE
+                    WHEN sender_id = ? THEN receiver_id
+                    ELSE sender_id
+                END
+        ) m ON u.id = m.user_id
+        WHERE
+            u.id <> ?
+        ORDER BY
+            m.latest_message_time DESC,
+            u.username ASC;
+    `
+
+	rows, err := s.db.DB.Query(query, database.ProfileLastSeen, userID, userID, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []database.User{}
+	for rows.Next() {
+		var user database.User
+		var lastSeenVisible bool
+		if err := rows.Scan(&user.ID, &user.Username, &user.LastSeenAt, &lastSeenVisible, &user.Unread); err != nil {
+			return nil, err
+		}
+		if !lastSeenVisible {
+			user.LastSeenAt = nil
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *sqliteUsers) UsernameExists(username string) (bool, error) {
+	var exists bool
+	err := s.db.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM user WHERE username = ? COLLATE NOCASE)`, username).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqliteUsers) EmailExists(email string) (bool, error) {
+	var exists bool
+	err := s.db.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM user WHERE email = ? COLLATE NOCASE)`, email).Scan(&exists)
+	return exists, err
+}
+
+func (s *sqliteUsers) SetLastSeen(userID string, at time.Time) error {
+	query := `UPDATE user SET last_seen_at = ? WHERE id = ?`
+	_, err := s.db.DB.Exec(query, at, userID)
+	return err
+}
+
+func (s *sqliteUsers) SetRole(userID, role string) error {
+	result, err := s.db.DB.Exec(`UPDATE user SET role = ? WHERE id = ?`, role, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteUsers) ListIDsByRole(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return []string{}, nil
+	}
+	args := make([]any, len(roles))
+	for i, role := range roles {
+		args[i] = role
+	}
+
+	rows, err := s.db.DB.Query(`SELECT id FROM user WHERE role IN (?`+strings.Repeat(", ?", len(roles)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *sqliteUsers) ListByUsernames(usernames []string) ([]database.User, error) {
+	if len(usernames) == 0 {
+		return []database.User{}, nil
+	}
+	args := make([]any, len(usernames))
+	for i, username := range usernames {
+		args[i] = username
+	}
+
+	query := `SELECT id, username FROM user WHERE username COLLATE NOCASE IN (?` + strings.Repeat(", ?", len(usernames)-1) + `)`
+	rows, err := s.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []database.User{}
+	for rows.Next() {
+		var user database.User
+		if err := rows.Scan(&user.ID, &user.Username); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *sqliteUsers) SetSanction(userID string, bannedAt, suspendedUntil *time.Time) error {
+	query := `UPDATE user SET banned_at = ?, suspended_until = ? WHERE id = ?`
+	result, err := s.db.DB.Exec(query, bannedAt, suspendedUntil, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteUsers) GetAccount(userID string) (database.User, error) {
+	var user database.User
+	var createdAt *time.Time
+	query := `SELECT id, username, email, first_name, last_name, age, gender, created_at, last_seen_at, display_name, bio, avatar_id, role FROM user WHERE id = ?`
+	err := s.db.DB.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.FirstName, &user.LastName, &user.Age, &user.Gender,
+		&createdAt, &user.LastSeenAt, &user.DisplayName, &user.Bio, &user.AvatarID, &user.Role)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	if createdAt != nil {
+		user.CreatedAt = *createdAt
+	}
+	return user, err
+}
+
+func (s *sqliteUsers) GetProfile(userID string) (database.Profile, error) {
+	var profile database.Profile
+	var posts, comments int
+	query := `
+        SELECT
+            u.id, u.username, u.display_name, u.bio, u.avatar_id, u.created_at, u.last_seen_at,
+            (SELECT COUNT(*) FROM post WHERE user_id = u.id AND deleted_at IS NULL),
+            (SELECT COUNT(*) FROM comment WHERE user_id = u.id AND deleted_at IS NULL)
+        FROM user u
+        WHERE u.id = ?
+    `
+	err := s.db.DB.QueryRow(query, userID).Scan(&profile.ID, &profile.Username, &profile.DisplayName, &profile.Bio, &profile.AvatarID,
+		&profile.JoinedAt, &profile.LastSeenAt, &posts, &comments)
+	if err == sql.ErrNoRows {
+		return profile, ErrNotFound
+	}
+	if err != nil {
+		return profile, err
+	}
+	profile.PostCount, profile.CommentCount = &posts, &comments
+
+	profile.Privacy, err = s.Privacy(userID)
+	return profile, err
+}
+
+func (s *sqliteUsers) UpdateProfile(user database.User) error {
+	query := `
+        UPDATE user
+        SET username = ?, email = ?, first_name = ?, last_name = ?, age = ?, gender = ?, display_name = ?, bio = ?, avatar_id = ?
+        WHERE id = ?
+    `
+	result, err := s.db.DB.Exec(query, user.Username, user.Email, user.FirstName, user.LastName, user.Age, user.Gender,
+		user.DisplayName, user.Bio, user.AvatarID, user.ID.String())
+	if err != nil {
+		return uniqueUserError(err)
+	}
+	return requireAffected(result)
+}
+
+func (s *sqliteUsers) Privacy(userID string) (map[string]bool, error) {
+	privacy := make(map[string]bool, len(database.ProfileFields))
+	for _, field := range database.ProfileFields {
+		privacy[field] = true
+	}
+
+	rows, err := s.db.DB.Query(`SELECT field, visible FROM profile_privacy WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field string
+		var visible bool
+		if err := rows.Scan(&field, &visible); err != nil {
+			return nil, err
+		}
+		privacy[field] = visible
+	}
+	return privacy, rows.Err()
+}
+
+func (s *sqliteUsers) SetPrivacy(userID string, privacy map[string]bool) error {
+	tx, err := s.db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO profile_privacy (user_id, field, visible) VALUES (?, ?, ?)
+        ON CONFLICT (user_id, field) DO UPDATE SET visible = excluded.visible
+    `
+	for field, visible := range privacy {
+		if _, err := tx.Exec(query, userID, field, visible); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
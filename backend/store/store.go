@@ -0,0 +1,300 @@
+package store
+
+import (
+	"errors"
+	"real-time-forum/backend/database"
+	"time"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrUsernameTaken and ErrEmailTaken are returned when creating a user whose
+// username or email, compared without regard to case, already belongs to another.
+var (
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already taken")
+)
+
+// ErrAlreadyReported is returned when a member reports a target they already have a pending report on.
+var ErrAlreadyReported = errors.New("target already reported")
+
+// ErrStatusChanged is returned when a report left the status a transition starts from.
+var ErrStatusChanged = errors.New("report status changed")
+
+// UserStore persists users.
+type UserStore interface {
+	// Create inserts a user with an already hashed password, or returns
+	// ErrUsernameTaken or ErrEmailTaken.
+	Create(user database.User, passwordHash string) error
+	// GetByLogin returns the user whose email or username matches regardless of case,
+	// including the password hash, role and sanctions.
+	GetByLogin(emailOrUsername string) (database.User, error)
+	// Get returns a user's public profile along with their role and sanctions.
+	Get(userID string) (database.User, error)
+	// UsernameExists and EmailExists report whether a user has the username or email,
+	// compared without regard to case.
+	UsernameExists(username string) (bool, error)
+	EmailExists(email string) (bool, error)
+	// ListContacts returns every other user, most recent conversation first,
+	// with the number of messages from them the user has not read.
+	ListContacts(userID string) ([]database.User, error)
+	// SetLastSeen records when the user's last connection closed.
+	SetLastSeen(userID string, at time.Time) error
+	// SetRole changes the role of a user.
+	SetRole(userID, role string) error
+	// SetSanction bans a user from bannedAt or suspends them until suspendedUntil;
+	// passing nil for both lifts any sanction.
+	SetSanction(userID string, bannedAt, suspendedUntil *time.Time) error
+	// ListIDsByRole returns the IDs of the users having one of the roles.
+	ListIDsByRole(roles []string) ([]string, error)
+	// ListByUsernames returns the ID and username of the users whose username is one
+	// of usernames, compared without regard to case.
+	ListByUsernames(usernames []string) ([]database.User, error)
+	// GetAccount returns every detail of a user but their password hash, for them to edit.
+	GetAccount(userID string) (database.User, error)
+	// GetProfile returns the whole profile of a user along with their privacy
+	// settings, leaving it to the caller to hide what the viewer cannot see.
+	GetProfile(userID string) (database.Profile, error)
+	// UpdateProfile saves the details, display name, bio and avatar of a user, or
+	// returns ErrUsernameTaken or ErrEmailTaken.
+	UpdateProfile(user database.User) error
+	// Privacy returns whether each profile field of a user is visible to others,
+	// which they all are until hidden.
+	Privacy(userID string) (map[string]bool, error)
+	// SetPrivacy shows or hides profile fields of a user; the fields left out keep
+	// their setting.
+	SetPrivacy(userID string, privacy map[string]bool) error
+}
+
+// SessionStore persists login sessions. Tokens are only ever stored hashed.
+type SessionStore interface {
+	Create(session database.Session, tokenHash string) error
+	// GetByTokenHash returns the session with the token hash, if it expires after now.
+	GetByTokenHash(tokenHash string, now time.Time) (database.Session, error)
+	// Touch updates the last use and expiry of a session.
+	Touch(id string, lastUsedAt, expiresAt time.Time) error
+	// ListByUser returns a user's sessions that expire after now, most recently used first.
+	ListByUser(userID string, now time.Time) ([]database.Session, error)
+	// Delete removes one of a user's sessions.
+	Delete(userID, id string) error
+	// DeleteExpired removes a user's sessions that expired by now.
+	DeleteExpired(userID string, now time.Time) error
+	// DeleteByUser removes every session of a user.
+	DeleteByUser(userID string) error
+}
+
+// PostStore persists posts.
+type PostStore interface {
+	// List returns a page of posts, newest first, with their categories, reaction
+	// counts and viewerID's own reaction, and whether there are more past the page.
+	// When categories are given only posts filed under at least one of them are listed.
+	List(viewerID string, categories []string, page Page) ([]database.Post, bool, error)
+	// Get returns a post that was not deleted, as List does.
+	Get(viewerID string, id int) (database.Post, error)
+	// Create inserts a post filed under its categories and sets its ID.
+	Create(post *database.Post) error
+	// Update replaces the title, content and categories of a post and stamps it with
+	// post.EditedAt, recording the version it replaces as a revision by editorID.
+	Update(post *database.Post, editorID string) error
+	// Delete soft deletes a post along with its comments.
+	Delete(id int, at time.Time) error
+	// SetLocked locks a post's thread from at, or unlocks it when at is nil.
+	SetLocked(id int, at *time.Time) error
+}
+
+// CategoryStore persists the categories posts are filed under.
+type CategoryStore interface {
+	// List returns every category in display order, with its number of posts.
+	List() ([]database.Category, error)
+	// Create inserts a category and sets its ID.
+	Create(category *database.Category) error
+}
+
+// CommentStore persists comments.
+type CommentStore interface {
+	// ListByPost returns a page of the top-level comments of a post, newest first, with
+	// their reply counts, reaction counts and viewerID's own reaction, and whether there
+	// are more past the page. Deleted comments with replies are listed as placeholders.
+	ListByPost(viewerID string, postID int, page Page) ([]database.Comment, bool, error)
+	// ListReplies returns a page of the direct replies to a comment as ListByPost does,
+	// or ErrNotFound when the comment does not exist.
+	ListReplies(viewerID string, parentID int, page Page) ([]database.Comment, bool, error)
+	// Get returns a comment that was not deleted, as ListByPost does.
+	Get(viewerID string, id int) (database.Comment, error)
+	// Create inserts a comment, a reply when its ParentID is set, and sets its ID.
+	Create(comment *database.Comment) error
+	// Update replaces the content of a comment and stamps it with comment.EditedAt,
+	// recording the version it replaces as a revision by editorID.
+	Update(comment *database.Comment, editorID string) error
+	// Delete soft deletes a comment.
+	Delete(id int, at time.Time) error
+}
+
+// MessageStore persists private messages.
+type MessageStore interface {
+	// Create inserts a message and sets its ID.
+	Create(message *database.Message) error
+	// ListConversation returns a page of the messages between two users, newest first,
+	// and whether there are more past the page.
+	ListConversation(userID, otherUserID string, page Page) ([]database.Message, bool, error)
+	// Get returns a message that was not deleted.
+	Get(id int) (database.Message, error)
+	// Update replaces the content of a message and stamps it with message.EditedAt.
+	Update(message *database.Message) error
+	// Delete soft deletes a message.
+	Delete(id int, at time.Time) error
+	// MarkRead stamps the unread messages from senderID to readerID up to a message ID
+	// and returns how many were updated.
+	MarkRead(readerID, senderID string, upTo int, at time.Time) (int64, error)
+}
+
+// ReactionStore persists likes and dislikes on posts and comments, one per user and target.
+type ReactionStore interface {
+	// Toggle applies a user's reaction to a target: repeating the current reaction
+	// removes it and a different one replaces it. It returns the user's resulting
+	// reaction, empty when removed, or ErrNotFound when the target does not exist.
+	Toggle(userID, targetType string, targetID int, kind string) (string, error)
+	// Counts returns the likes and dislikes left on a target.
+	Counts(targetType string, targetID int) (database.ReactionCounts, error)
+}
+
+// RevisionStore reads the edit history of posts and comments.
+type RevisionStore interface {
+	// List returns the revisions of a target, oldest first.
+	List(targetType string, targetID int) ([]database.Revision, error)
+}
+
+// MentionStore persists the users mentioned in posts, comments and messages, which
+// the other stores return in the Mentions of what they read.
+type MentionStore interface {
+	// Replace sets the mentions in the content of a target, dropping its previous ones.
+	Replace(targetType string, targetID int, mentions []database.Mention) error
+}
+
+// AttachmentStore persists the files uploaded to posts and private messages, which
+// the post and message stores return in the Attachments of what they read.
+type AttachmentStore interface {
+	// Create records an uploaded file, attached to nothing yet, and sets its ID.
+	Create(attachment *database.Attachment) error
+	Get(id int) (database.Attachment, error)
+	// Unattached returns those of the attachments with the given IDs that the user
+	// uploaded and that are not attached to anything yet, nor the avatar of a user.
+	Unattached(uploaderID string, ids []int) ([]database.Attachment, error)
+	// Attach links the attachments with the given IDs that are attached to nothing
+	// yet to a post or message, as targetType says.
+	Attach(ids []int, targetType string, targetID int) error
+	// UsedBytes returns the total size of the files a user uploaded.
+	UsedBytes(uploaderID string) (int64, error)
+}
+
+// Source is the Markdown body of a post or comment and the mentions in it.
+type Source struct {
+	ID       int
+	Content  string
+	Mentions []database.Mention
+}
+
+// ContentStore backfills the rendered HTML of the posts and comments stored before
+// it was kept alongside their Markdown source.
+type ContentStore interface {
+	// Unrendered returns up to limit posts or comments, as targetType says, that have
+	// no HTML and an ID above afterID, in ID order.
+	Unrendered(targetType string, afterID, limit int) ([]Source, error)
+	// SetHTML saves the rendered HTML of a post or comment.
+	SetHTML(targetType string, id int, html string) error
+}
+
+// ReportFilter narrows the moderation queue; empty fields match every report.
+type ReportFilter struct {
+	Status     string
+	TargetType string
+	Reason     string
+}
+
+// ReportStore persists reports of abusive content, which form the moderation queue.
+type ReportStore interface {
+	// Create files a report and sets its ID, or returns ErrAlreadyReported when the
+	// reporter has an open or reviewing report on the same target.
+	Create(report *database.Report) error
+	Get(id int) (database.Report, error)
+	// List returns a page of the reports matching the filter, newest first, and
+	// whether there are more past the page.
+	List(filter ReportFilter, page Page) ([]database.Report, bool, error)
+	// SetStatus moves a report from status from to report.Status, saving its ReviewerID
+	// and UpdatedAt, or returns ErrStatusChanged when it is no longer in from.
+	SetStatus(report *database.Report, from string) error
+}
+
+// ModerationLogStore persists the audit trail of moderator actions.
+type ModerationLogStore interface {
+	// Add records an action and sets its ID.
+	Add(action *database.ModerationAction) error
+	// List returns a page of actions, newest first, and whether there are more past the page.
+	List(page Page) ([]database.ModerationAction, bool, error)
+}
+
+// NotificationStore persists the notification inbox of each user and the kinds of
+// notification they want.
+type NotificationStore interface {
+	// Create stores a notification and sets its ID, unless the recipient has an
+	// identical unread one, in which case it stores nothing and reports false.
+	Create(notification *database.Notification) (bool, error)
+	// List returns a page of a user's notifications, newest first, only the unread
+	// ones when unreadOnly is set, and whether there are more past the page.
+	List(userID string, unreadOnly bool, page Page) ([]database.Notification, bool, error)
+	// UnreadCount returns how many of a user's notifications are unread.
+	UnreadCount(userID string) (int, error)
+	// MarkRead stamps one of a user's notifications read, if it was not already.
+	MarkRead(userID string, id int, at time.Time) error
+	// MarkAllRead stamps every unread notification of a user and returns how many were updated.
+	MarkAllRead(userID string, at time.Time) (int64, error)
+	// Preferences returns whether a user wants each kind of notification; kinds
+	// they never set are enabled.
+	Preferences(userID string) (map[string]bool, error)
+	// SetPreferences saves whether a user wants the kinds of notification given.
+	SetPreferences(userID string, preferences map[string]bool) error
+}
+
+// SearchQuery describes a search. Terms, of which there must be at least one, are
+// matched as word prefixes and must all appear;
+// Categories and Author, a username, narrow post and comment results when set.
+type SearchQuery struct {
+	Terms      []string
+	Categories []string
+	Author     string
+	// Since and Until, when set, keep the matches created in [Since, Until).
+	Since    *time.Time
+	Until    *time.Time
+	ViewerID string
+	Limit    int
+	Offset   int
+}
+
+// SearchStore finds posts, comments and users, best matches first.
+type SearchStore interface {
+	Posts(query SearchQuery) ([]database.PostResult, error)
+	// Comments matches comments; Categories applies to the post they belong to.
+	Comments(query SearchQuery) ([]database.CommentResult, error)
+	// Users matches usernames containing every term.
+	Users(query SearchQuery) ([]database.User, error)
+}
+
+// Store groups the repositories the API depends on.
+type Store struct {
+	Users         UserStore
+	Sessions      SessionStore
+	Posts         PostStore
+	Categories    CategoryStore
+	Comments      CommentStore
+	Messages      MessageStore
+	Reactions     ReactionStore
+	Revisions     RevisionStore
+	Mentions      MentionStore
+	Contents      ContentStore
+	Attachments   AttachmentStore
+	Reports       ReportStore
+	ModerationLog ModerationLogStore
+	Notifications NotificationStore
+	Search        SearchStore
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"real-time-forum/backend/database"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// createTestUser adds a member named username to st.
+func createTestUser(t *testing.T, st *Store, username string) database.User {
+	t.Helper()
+	user := database.User{
+		ID:        uuid.Must(uuid.NewV4()),
+		Username:  username,
+		Email:     username + "@example.com",
+		FirstName: "Test",
+		LastName:  "User",
+		Age:       30,
+		Gender:    "other",
+	}
+	if err := st.Users.Create(user, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
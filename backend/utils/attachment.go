@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder for image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Limits on attachments.
+const (
+	MaxAttachmentSize = 5 << 20  // bytes in one file
+	AttachmentQuota   = 50 << 20 // bytes across every file a user uploaded
+	MaxAttachments    = 4        // files attached to one post or message
+	// MaxImagePixels bounds the images decoded for a thumbnail, which take four
+	// bytes of memory a pixel whatever their file size.
+	MaxImagePixels = 20_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize     = 320
+	maxFilenameLength = 100
+)
+
+// imageTypes are the images that can be attached, the formats the standard library decodes.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// documentTypes are the other files that can be attached to private messages.
+var documentTypes = map[string]bool{
+	"application/pdf":           true,
+	"application/zip":           true,
+	"text/plain; charset=utf-8": true,
+}
+
+// AttachmentType sniffs the MIME type of an uploaded file from its content, whatever
+// the client claimed it was, and reports whether such files can be attached.
+func AttachmentType(data []byte) (string, bool) {
+	mimeType := http.DetectContentType(data)
+	return mimeType, imageTypes[mimeType] || documentTypes[mimeType]
+}
+
+// IsImage reports whether an attachment of the given MIME type is an image.
+func IsImage(mimeType string) bool {
+	return imageTypes[mimeType]
+}
+
+// CleanFilename reduces the name a client gave an uploaded file to a base name
+// without control characters or quotes, safe to offer back in downloads.
+func CleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[len(runes)-maxFilenameLength:])
+	}
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// ProcessImage reads the dimensions of an uploaded image and makes its thumbnail,
+// which is nil when the image is small enough to be its own thumbnail.
+func ProcessImage(data []byte) (width, height int, thumbnail []byte, err error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, FieldErrors{"file": "The image could not be read"}
+	}
+	width, height = config.Width, config.Height
+	if width*height > MaxImagePixels {
+		return 0, 0, nil, FieldErrors{"file": fmt.Sprintf("Images cannot have more than %d megapixels", MaxImagePixels/1_000_000)}
+	}
+	if width <= ThumbnailSize && height <= ThumbnailSize {
+		return width, height, nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, nil, FieldErrors{"file": "The image could not be read"}
+	}
+	var buf bytes.Buffer
+	small := shrink(img, ThumbnailSize)
+	// photos stay JPEG; PNG keeps the transparency of the others
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, small)
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return width, height, buf.Bytes(), nil
+}
+
+// shrink scales img down to fit a size×size square, each pixel of the result
+// averaging the pixels of img it covers.
+func shrink(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
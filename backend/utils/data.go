@@ -1,10 +1,13 @@
 package utils
 
 import (
-	"errors"
-	"log"
+	"fmt"
 	"real-time-forum/backend/database"
+	"real-time-forum/backend/response"
 	"regexp"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/gofrs/uuid/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -20,41 +23,277 @@ func NewUUID() (uuid.UUID, error) {
 	return id, err
 }
 
-func isValidEmail(email string) bool {
-	re := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	return re.MatchString(email)
+// Bounds on the fields of a registration.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 20
+	MinPasswordLength = 8
+	MaxPasswordLength = 72 // bcrypt ignores anything past 72 bytes
+	MaxEmailLength    = 254
+	MaxNameLength     = 50
+	MinAge            = 13
+	MaxAge            = 120
+)
+
+// Bounds on the fields of a profile.
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 500
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	emailPattern    = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+)
+
+// FieldErrors maps each invalid field of an input to the reason it was rejected.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	return response.FieldMessages(e)
 }
 
-func ValidateUser(user database.User) error {
-	if user.Username == "" || user.Email == "" || user.Password == "" || user.FirstName == "" || user.LastName == "" || user.Age == 0 || user.Gender == "" {
-		log.Println(user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.Age, user.Gender)
-		return errors.New("All fields are required")
+// check records message against field when ok is false and the field has no error yet.
+func (e FieldErrors) check(ok bool, field, message string) {
+	if _, exists := e[field]; !ok && !exists {
+		e[field] = message
 	}
-	if !isValidEmail(user.Email) {
-		return errors.New("Invalid email format")
+}
+
+// merge records the fields of errs, the error of another validator, that have no error yet.
+func (e FieldErrors) merge(errs error) {
+	if fields, ok := errs.(FieldErrors); ok {
+		for field, message := range fields {
+			e.check(false, field, message)
+		}
 	}
-	if user.Age <= 0 {
-		return errors.New("Age must be a positive number")
+}
+
+// orNil returns nil when no field is invalid, so callers can compare the result to nil.
+func (e FieldErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
 	}
-	if user.Gender != "male" && user.Gender != "female" && user.Gender != "other" {
-		return errors.New("Gender must be 'male', 'female', or 'other'")
+	return e
+}
+
+// NormalizeEmail trims and lowercases an email, the form in which emails are stored and compared.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeUser trims the text fields of a registration or profile and normalizes its email.
+func NormalizeUser(user *database.User) {
+	user.Username = strings.TrimSpace(user.Username)
+	user.Email = NormalizeEmail(user.Email)
+	user.FirstName = strings.TrimSpace(user.FirstName)
+	user.LastName = strings.TrimSpace(user.LastName)
+	user.Gender = strings.TrimSpace(user.Gender)
+	user.DisplayName = strings.TrimSpace(user.DisplayName)
+	user.Bio = strings.TrimSpace(user.Bio)
+}
+
+// ValidateUsername checks that a username is 3 to 20 letters, digits or underscores.
+func ValidateUsername(username string) error {
+	errs := FieldErrors{}
+	errs.check(username != "", "username", "Username is required")
+	errs.check(len(username) >= MinUsernameLength && len(username) <= MaxUsernameLength, "username",
+		fmt.Sprintf("Username must be %d to %d characters long", MinUsernameLength, MaxUsernameLength))
+	errs.check(usernamePattern.MatchString(username), "username", "Username can only contain letters, digits and underscores")
+	return errs.orNil()
+}
+
+// ValidateEmail checks the format of an already normalized email.
+func ValidateEmail(email string) error {
+	errs := FieldErrors{}
+	errs.check(email != "", "email", "Email is required")
+	errs.check(len(email) <= MaxEmailLength, "email", "Email is too long")
+	errs.check(emailPattern.MatchString(email), "email", "Invalid email format")
+	return errs.orNil()
+}
+
+// validatePassword requires a password of 8 to 72 bytes mixing lowercase and
+// uppercase letters with digits.
+func validatePassword(password string) error {
+	var lower, upper, digit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
 	}
-	if len(user.Username) > 100 || len(user.Password) > 100 {
-		return errors.New("Username and Password must be less than 100 characters")
+
+	errs := FieldErrors{}
+	errs.check(password != "", "password", "Password is required")
+	errs.check(len(password) >= MinPasswordLength, "password", fmt.Sprintf("Password must be at least %d characters long", MinPasswordLength))
+	errs.check(len(password) <= MaxPasswordLength, "password", fmt.Sprintf("Password must be at most %d characters long", MaxPasswordLength))
+	errs.check(lower && upper && digit, "password", "Password must contain a lowercase letter, an uppercase letter and a digit")
+	return errs.orNil()
+}
+
+// ValidateUser checks a normalized registration, reporting every invalid field.
+func ValidateUser(user database.User) error {
+	errs := FieldErrors{}
+	errs.merge(ValidateUsername(user.Username))
+	errs.merge(ValidateEmail(user.Email))
+	errs.merge(validatePassword(user.Password))
+	errs.merge(validateDetails(user))
+	return errs.orNil()
+}
+
+// ValidateProfile checks a normalized profile edit against the current profile,
+// reporting every invalid field. It checks the fields of a registration as
+// ValidateUser does, but the password, which is not edited with the profile. Only
+// the fields that change are checked, so that users keep values accepted by older
+// rules, such as usernames from before their charset was restricted.
+func ValidateProfile(user, current database.User) error {
+	errs := FieldErrors{}
+	errs.merge(ValidateUsername(user.Username))
+	errs.merge(ValidateEmail(user.Email))
+	errs.merge(validateDetails(user))
+	errs.check(len(user.DisplayName) <= MaxDisplayNameLength, "display_name", fmt.Sprintf("Display name must be at most %d characters long", MaxDisplayNameLength))
+	errs.check(len(user.Bio) <= MaxBioLength, "bio", fmt.Sprintf("Bio must be at most %d characters long", MaxBioLength))
+
+	NormalizeUser(&current)
+	unchanged := map[string]bool{
+		"username":     user.Username == current.Username,
+		"email":        user.Email == current.Email,
+		"first_name":   user.FirstName == current.FirstName,
+		"last_name":    user.LastName == current.LastName,
+		"age":          user.Age == current.Age,
+		"gender":       user.Gender == current.Gender,
+		"display_name": user.DisplayName == current.DisplayName,
+		"bio":          user.Bio == current.Bio,
 	}
-	return nil
+	for field := range errs {
+		if unchanged[field] {
+			delete(errs, field)
+		}
+	}
+	return errs.orNil()
+}
+
+// validateDetails checks the names, age and gender of a registration or profile.
+func validateDetails(user database.User) error {
+	errs := FieldErrors{}
+	errs.check(user.FirstName != "", "first_name", "First name is required")
+	errs.check(len(user.FirstName) <= MaxNameLength, "first_name", fmt.Sprintf("First name must be at most %d characters long", MaxNameLength))
+	errs.check(user.LastName != "", "last_name", "Last name is required")
+	errs.check(len(user.LastName) <= MaxNameLength, "last_name", fmt.Sprintf("Last name must be at most %d characters long", MaxNameLength))
+	errs.check(user.Age >= MinAge && user.Age <= MaxAge, "age", fmt.Sprintf("Age must be between %d and %d", MinAge, MaxAge))
+	errs.check(user.Gender == "male" || user.Gender == "female" || user.Gender == "other", "gender", "Gender must be 'male', 'female', or 'other'")
+	return errs.orNil()
+}
+
+// ValidatePrivacy checks that privacy settings only name profile fields that can be hidden.
+func ValidatePrivacy(privacy map[string]bool) error {
+	errs := FieldErrors{}
+	for field := range privacy {
+		errs.check(slices.Contains(database.ProfileFields, field), "privacy", "Privacy can only be set for "+strings.Join(database.ProfileFields, ", "))
+	}
+	return errs.orNil()
 }
 
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func ValidatePost(post database.Post) error {
-	if post.Title == "" || post.Content == "" || post.Category == "" {
-		return errors.New("All fields are required")
+// NormalizeCategories lowercases and trims category slugs, dropping blanks and duplicates.
+func NormalizeCategories(slugs []string) []string {
+	seen := make(map[string]bool, len(slugs))
+	normalized := []string{}
+	for _, slug := range slugs {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		normalized = append(normalized, slug)
 	}
-	if len(post.Title) > 100 || len(post.Content) > 1000 || len(post.Category) > 100 {
-		return errors.New("Title and Category must be less than 100 characters and content must be less than 1000 characters")
+	return normalized
+}
+
+// MaxPostCategories is the most categories a post can be filed under.
+const MaxPostCategories = 3
+
+func ValidatePost(post database.Post) error {
+	errs := FieldErrors{}
+	errs.check(post.Title != "", "title", "Title is required")
+	errs.check(post.Content != "", "content", "Content is required")
+	errs.check(len(post.Categories) > 0, "categories", "At least one category is required")
+	errs.check(len(post.Title) <= 100, "title", "Title must be less than 100 characters")
+	errs.check(len(post.Content) <= 1000, "content", "Content must be less than 1000 characters")
+	errs.check(len(post.Categories) <= MaxPostCategories, "categories", fmt.Sprintf("A post can have at most %d categories", MaxPostCategories))
+	return errs.orNil()
+}
+
+func ValidateComment(comment database.Comment) error {
+	errs := FieldErrors{}
+	errs.check(strings.TrimSpace(comment.Content) != "", "content", "Comment content is required")
+	errs.check(len(comment.Content) <= 1000, "content", "Comment must be less than 1000 characters")
+	errs.check(comment.PostID > 0, "post_id", "Invalid post ID")
+	return errs.orNil()
+}
+
+// MaxCommentDepth is how deeply replies can be nested; top-level comments have depth 0.
+const MaxCommentDepth = 5
+
+// ValidateReply checks that a reply answers a comment of the same post that is not
+// nested as deeply as replies can go.
+func ValidateReply(reply, parent database.Comment) error {
+	errs := FieldErrors{}
+	errs.check(reply.PostID == parent.PostID, "parent_id", "The comment replied to belongs to another post")
+	errs.check(parent.Depth < MaxCommentDepth, "parent_id", fmt.Sprintf("Replies cannot be nested more than %d levels deep", MaxCommentDepth))
+	return errs.orNil()
+}
+
+func ValidateMessage(message database.Message) error {
+	errs := FieldErrors{}
+	errs.check(strings.TrimSpace(message.Content) != "" || len(message.Attachments) > 0, "content", "Message cannot be empty")
+	errs.check(len(message.Content) <= 1000, "content", "Message must be less than 1000 characters")
+	errs.check(message.SenderID != message.ReceiverID, "receiver_id", "Cannot send a message to yourself")
+	return errs.orNil()
+}
+
+// MaxReportTextLength bounds the details of a report and the notes moderators leave on it.
+const MaxReportTextLength = 500
+
+var (
+	reportTargets  = []string{database.TargetPost, database.TargetComment, database.TargetMessage}
+	reportReasons  = []string{database.ReasonSpam, database.ReasonHarassment, database.ReasonHate, database.ReasonInappropriate, database.ReasonOther}
+	reportStatuses = []string{database.ReportOpen, database.ReportReviewing, database.ReportActioned, database.ReportDismissed}
+)
+
+// ValidateReport checks the target and reason of a report; reports for other
+// reasons must explain themselves in their details.
+func ValidateReport(report database.Report) error {
+	errs := FieldErrors{}
+	errs.check(slices.Contains(reportTargets, report.TargetType), "target_type", "Only posts, comments and messages can be reported")
+	errs.check(report.TargetID > 0, "target_id", "Invalid target ID")
+	errs.check(slices.Contains(reportReasons, report.Reason), "reason", "Reason must be one of "+strings.Join(reportReasons, ", "))
+	errs.check(report.Reason != database.ReasonOther || report.Details != "", "details", "Please describe the problem")
+	errs.check(len(report.Details) <= MaxReportTextLength, "details", fmt.Sprintf("Details must be at most %d characters long", MaxReportTextLength))
+	return errs.orNil()
+}
+
+// ValidateReportFilter checks the optional filters of the moderation queue.
+func ValidateReportFilter(status, targetType, reason string) error {
+	errs := FieldErrors{}
+	errs.check(status == "" || slices.Contains(reportStatuses, status), "status", "Status must be one of "+strings.Join(reportStatuses, ", "))
+	errs.check(targetType == "" || slices.Contains(reportTargets, targetType), "target_type", "Target type must be one of "+strings.Join(reportTargets, ", "))
+	errs.check(reason == "" || slices.Contains(reportReasons, reason), "reason", "Reason must be one of "+strings.Join(reportReasons, ", "))
+	return errs.orNil()
+}
+
+// ValidateNotificationPreferences checks that preferences only name kinds of notification.
+func ValidateNotificationPreferences(preferences map[string]bool) error {
+	errs := FieldErrors{}
+	for kind := range preferences {
+		errs.check(slices.Contains(database.NotificationTypes, kind), kind, "Notification type must be one of "+strings.Join(database.NotificationTypes, ", "))
 	}
-	return nil
-}
\ No newline at end of file
+	return errs.orNil()
+}
@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/store"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Post and comment bodies are written in a small subset of Markdown: paragraphs,
+// fenced code blocks, block quotes, lists, emphasis, code spans and links, with
+// bare URLs and @mentions linked. Every character of the source is escaped on its
+// way out, so HTML written in the source shows as text and the only markup in the
+// result is the markup the renderer writes itself.
+
+// maxBlockDepth is how deeply quotes and lists can nest; deeper ones are rendered as text.
+const maxBlockDepth = 8
+
+var (
+	fencePattern    = regexp.MustCompile("^ {0,3}(```+)\\s*([a-zA-Z0-9_+-]*)")
+	listItemPattern = regexp.MustCompile(`^ {0,3}(?:([-*+])|(\d{1,9})[.)])(?: +(.*))?$`)
+	urlPattern      = regexp.MustCompile("^https?://[^\\s<>`]+")
+)
+
+// RenderMarkdown renders a post or comment body to HTML, linking the @mentions of
+// the users among mentions.
+func RenderMarkdown(source string, mentions []database.Mention) string {
+	r := renderer{mentions: make(map[string]database.Mention, len(mentions))}
+	for _, mention := range mentions {
+		r.mentions[strings.ToLower(mention.Username)] = mention
+	}
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	return strings.Join(r.blocks(lines, 0), "\n")
+}
+
+// RenderMissingContent renders the posts and comments stored without their HTML
+// and returns how many there were.
+func RenderMissingContent(contents store.ContentStore) (int, error) {
+	rendered := 0
+	for _, targetType := range []string{database.TargetPost, database.TargetComment} {
+		for afterID := 0; ; {
+			sources, err := contents.Unrendered(targetType, afterID, 100)
+			if err != nil {
+				return rendered, err
+			}
+			if len(sources) == 0 {
+				break
+			}
+			for _, source := range sources {
+				if err := contents.SetHTML(targetType, source.ID, RenderMarkdown(source.Content, source.Mentions)); err != nil {
+					return rendered, err
+				}
+				afterID = source.ID
+				rendered++
+			}
+		}
+	}
+	return rendered, nil
+}
+
+type renderer struct {
+	// mentions holds the users that can be mentioned, by lowercased username
+	mentions map[string]database.Mention
+}
+
+// blocks renders lines as a sequence of blocks nested depth quotes or lists deep.
+func (r *renderer) blocks(lines []string, depth int) []string {
+	var blocks []string
+	for i := 0; i < len(lines); {
+		var block string
+		switch line := lines[i]; {
+		case strings.TrimSpace(line) == "":
+			i++
+			continue
+		case fencePattern.MatchString(line):
+			block, i = r.codeBlock(lines, i)
+		case depth < maxBlockDepth && isQuote(line):
+			block, i = r.quote(lines, i, depth)
+		case depth < maxBlockDepth && listItemPattern.MatchString(line):
+			block, i = r.list(lines, i, depth)
+		default:
+			block, i = r.paragraph(lines, i, depth)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// startsBlock reports whether line opens a block other than a paragraph.
+func startsBlock(line string, depth int) bool {
+	if fencePattern.MatchString(line) {
+		return true
+	}
+	return depth < maxBlockDepth && (isQuote(line) || listItemPattern.MatchString(line))
+}
+
+// codeBlock renders the fenced code block opening at lines[i], which runs to the
+// closing fence or the end of the source, and returns the index past it.
+func (r *renderer) codeBlock(lines []string, i int) (string, int) {
+	m := fencePattern.FindStringSubmatch(lines[i])
+	fence, language := m[1], m[2]
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	open := "<pre><code>"
+	if language != "" {
+		open = `<pre><code class="language-` + language + `">`
+	}
+	return open + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>", i
+}
+
+func isQuote(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, " "), ">") && indent(line) <= 3
+}
+
+// quote renders the block quote opening at lines[i] and returns the index past it.
+func (r *renderer) quote(lines []string, i int, depth int) (string, int) {
+	var quoted []string
+	for ; i < len(lines) && isQuote(lines[i]); i++ {
+		line := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+		quoted = append(quoted, strings.TrimPrefix(line, " "))
+	}
+	return "<blockquote>\n" + strings.Join(r.blocks(quoted, depth+1), "\n") + "\n</blockquote>", i
+}
+
+// list renders the list opening at lines[i] and returns the index past it. Lines
+// indented under an item, including nested lists, belong to it.
+func (r *renderer) list(lines []string, i int, depth int) (string, int) {
+	first := listItemPattern.FindStringSubmatch(lines[i])
+	bullet, number := first[1], first[2]
+	nested := indent(lines[i]) + 2
+
+	var items [][]string
+	contentIndent := 0
+	for i < len(lines) {
+		line := lines[i]
+		if m := listItemPattern.FindStringSubmatch(line); m != nil && indent(line) < nested && m[1] == bullet && (m[2] == "") == (number == "") {
+			items = append(items, []string{m[3]})
+			contentIndent = len(line) - len(m[3])
+			i++
+			continue
+		}
+		last := len(items) - 1
+		if strings.TrimSpace(line) != "" && indent(line) >= nested {
+			items[last] = append(items[last], dedent(line, contentIndent))
+			i++
+			continue
+		}
+		// a blank line keeps the list going when more of it follows
+		if strings.TrimSpace(line) == "" && i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" &&
+			(indent(lines[i+1]) >= nested || listItemPattern.MatchString(lines[i+1])) {
+			items[last] = append(items[last], "")
+			i++
+			continue
+		}
+		break
+	}
+
+	tag, open := "ul", "<ul>"
+	if number != "" {
+		tag, open = "ol", "<ol>"
+		if start, _ := strconv.Atoi(number); start != 1 {
+			open = `<ol start="` + strconv.Itoa(start) + `">`
+		}
+	}
+	var b strings.Builder
+	b.WriteString(open + "\n")
+	for _, item := range items {
+		blocks := r.blocks(item, depth+1)
+		// the paragraphs of an item without blank lines are written without <p>
+		if !slices.Contains(item, "") {
+			for j, block := range blocks {
+				if strings.HasPrefix(block, "<p>") {
+					blocks[j] = strings.TrimSuffix(strings.TrimPrefix(block, "<p>"), "</p>")
+				}
+			}
+		}
+		b.WriteString("<li>" + strings.Join(blocks, "\n") + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">")
+	return b.String(), i
+}
+
+// paragraph renders the paragraph starting at lines[i], which runs to a blank line
+// or the start of another block, and returns the index past it. Line breaks are kept.
+func (r *renderer) paragraph(lines []string, i int, depth int) (string, int) {
+	text := []string{strings.TrimSpace(lines[i])}
+	for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines[i], depth); i++ {
+		text = append(text, strings.TrimSpace(lines[i]))
+	}
+	return "<p>" + r.inline(strings.Join(text, "\n"), true) + "</p>", i
+}
+
+// indent returns the width of the leading whitespace of line, counting tabs as four spaces.
+func indent(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// dedent removes up to width columns of leading whitespace from line.
+func dedent(line string, width int) string {
+	for removed := 0; removed < width && line != ""; line = line[1:] {
+		switch line[0] {
+		case ' ':
+			removed++
+		case '\t':
+			removed += 4
+		default:
+			return line
+		}
+	}
+	return line
+}
+
+// inline renders the text of a block. Inside link text, links is false so that
+// links do not nest.
+func (r *renderer) inline(s string, links bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if markup, n := r.inlineAt(s, i, links); n > 0 {
+			b.WriteString(markup)
+			i += n
+			continue
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// inlineAt renders the inline element starting at s[i], if any, and returns the
+// number of bytes of s it spans, 0 when there is none.
+func (r *renderer) inlineAt(s string, i int, links bool) (string, int) {
+	switch c := s[i]; {
+	case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()#+-.!>@", s[i+1]) >= 0:
+		return html.EscapeString(s[i+1 : i+2]), 2
+	case c == '\n':
+		return "<br>\n", 1
+	case c == '`':
+		return codeSpan(s[i:])
+	case c == '*' || c == '_':
+		return r.emphasis(s, i, links)
+	case links && c == '[':
+		return r.link(s[i:])
+	case links && c == 'h' && (i == 0 || !isAlphanumeric(s[i-1])):
+		return autolink(s[i:])
+	case links && c == '@' && (i == 0 || !isUsernameByte(s[i-1])):
+		return r.mention(s[i:])
+	}
+	return "", 0
+}
+
+// codeSpan renders the code span opening s, closed by a run of as many backticks.
+// A run that is never closed is written as it is.
+func codeSpan(s string) (string, int) {
+	run := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:run]
+	for j := run; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			break
+		}
+		j += k
+		closing := len(s[j:]) - len(strings.TrimLeft(s[j:], "`"))
+		if closing == run {
+			code := strings.ReplaceAll(s[run:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+				code = code[1 : len(code)-1]
+			}
+			return "<code>" + html.EscapeString(code) + "</code>", j + run
+		}
+		j += closing
+	}
+	return html.EscapeString(fence), run
+}
+
+// emphasis renders the emphasis opening at s[i]: strong for a doubled * or _,
+// emphasised for a single one. An _ within a word does not open or close one.
+func (r *renderer) emphasis(s string, i int, links bool) (string, int) {
+	c := s[i]
+	if c == '_' && i > 0 && isAlphanumeric(s[i-1]) {
+		return "", 0
+	}
+	delimiter, tag := s[i:i+1], "em"
+	if i+1 < len(s) && s[i+1] == c {
+		delimiter, tag = s[i:i+2], "strong"
+	}
+	start := i + len(delimiter)
+	if start >= len(s) || isSpace(s[start]) {
+		return "", 0
+	}
+
+	for j := start; j < len(s); {
+		k := strings.Index(s[j:], delimiter)
+		if k < 0 {
+			break
+		}
+		j += k
+		end := j
+		for end < len(s) && s[end] == c {
+			end++
+		}
+		// a single delimiter does not close on a doubled one, which is a strong
+		// of its own, and a longer run closes on its last delimiters
+		if len(delimiter) == 1 && end-j == 2 {
+			j = end
+			continue
+		}
+		j = end - len(delimiter)
+		if j > start && !isSpace(s[j-1]) && (c == '*' || end == len(s) || !isAlphanumeric(s[end])) {
+			return "<" + tag + ">" + r.inline(s[start:j], links) + "</" + tag + ">", end - i
+		}
+		j = end
+	}
+	return "", 0
+}
+
+// link renders the [text](url) link opening s. A link to anything but an absolute
+// http, https or mailto URL keeps only its text.
+func (r *renderer) link(s string) (string, int) {
+	textEnd := strings.Index(s, "](")
+	if textEnd < 0 {
+		return "", 0
+	}
+	// the URL runs to the parenthesis closing the one before it
+	urlEnd, open := -1, 1
+	for j, c := range s[textEnd+2:] {
+		if c == '(' {
+			open++
+		} else if c == ')' {
+			if open--; open == 0 {
+				urlEnd = j
+				break
+			}
+		}
+	}
+	if urlEnd < 0 {
+		return "", 0
+	}
+	text, target := s[1:textEnd], strings.TrimSpace(s[textEnd+2:textEnd+2+urlEnd])
+	if text == "" || strings.ContainsAny(target, " \t\n") {
+		return "", 0
+	}
+	n := textEnd + 2 + urlEnd + 1
+	if !safeURL(target) {
+		return r.inline(text, false), n
+	}
+	return `<a href="` + html.EscapeString(target) + `" rel="nofollow">` + r.inline(text, false) + "</a>", n
+}
+
+// autolink renders the bare URL opening s, leaving out the punctuation that ends
+// the sentence around it.
+func autolink(s string) (string, int) {
+	target := urlPattern.FindString(s)
+	for target != "" {
+		last := target[len(target)-1]
+		if strings.IndexByte(".,:;!?'\"*_", last) >= 0 || last == ')' && strings.Count(target, "(") < strings.Count(target, ")") {
+			target = target[:len(target)-1]
+			continue
+		}
+		break
+	}
+	if !safeURL(target) {
+		return "", 0
+	}
+	escaped := html.EscapeString(target)
+	return `<a href="` + escaped + `" rel="nofollow">` + escaped + "</a>", len(target)
+}
+
+// safeURL reports whether target is an absolute http, https or mailto URL.
+func safeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+// mention renders the @username opening s as a link to the user it names, when
+// they can be mentioned.
+func (r *renderer) mention(s string) (string, int) {
+	end := 1
+	for end < len(s) && isUsernameByte(s[end]) && s[end] != '@' {
+		end++
+	}
+	mention, ok := r.mentions[strings.ToLower(s[1:end])]
+	if !ok {
+		return "", 0
+	}
+	return `<a href="#" class="mention" data-user-id="` + mention.UserID.String() + `" data-username="` +
+		html.EscapeString(mention.Username) + `">` + html.EscapeString(s[:end]) + "</a>", end
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n'
+}
+
+func isAlphanumeric(b byte) bool {
+	return ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
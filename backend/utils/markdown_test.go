@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"real-time-forum/backend/database"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestRenderMarkdownEscapesHTML(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"attribute in emphasis", "**<img src=x onerror=alert(1)>**", "<p><strong>&lt;img src=x onerror=alert(1)&gt;</strong></p>"},
+		{"code span", "`<b>` code", "<p><code>&lt;b&gt;</code> code</p>"},
+		{"code block", "```\n</code><script>\n```", "<pre><code>&lt;/code&gt;&lt;script&gt;</code></pre>"},
+		{"quote", "> <iframe>", "<blockquote>\n<p>&lt;iframe&gt;</p>\n</blockquote>"},
+		{"list item", "- <a href=x>", "<ul>\n<li>&lt;a href=x&gt;</li>\n</ul>"},
+		{"link text", "[<img>](http://a.com)", `<p><a href="http://a.com" rel="nofollow">&lt;img&gt;</a></p>`},
+		{"quote in link target", `[x](http://a.com/"onmouseover="alert(1))`, `<p><a href="http://a.com/&#34;onmouseover=&#34;alert(1)" rel="nofollow">x</a></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.source, nil); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownURLSchemes(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"javascript", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed case javascript", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"entity encoded javascript", "[x](&#106;avascript:alert(1))", "<p>x</p>"},
+		{"vbscript", "[x](vbscript:msgbox)", "<p>x</p>"},
+		{"data", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"protocol relative", "[x](//evil.com)", "<p>x</p>"},
+		{"bare javascript", "javascript:alert(1)", "<p>javascript:alert(1)</p>"},
+		{"https", "[x](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow">x</a></p>`},
+		{"mailto", "[x](mailto:bob@example.com)", `<p><a href="mailto:bob@example.com" rel="nofollow">x</a></p>`},
+		{"bare URL", "see https://example.com/page.", `<p>see <a href="https://example.com/page" rel="nofollow">https://example.com/page</a>.</p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.source, nil); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownMentions(t *testing.T) {
+	id := uuid.Must(uuid.NewV4())
+	mentions := []database.Mention{{UserID: id, Username: "Bob"}}
+
+	want := `<p>hi <a href="#" class="mention" data-user-id="` + id.String() + `" data-username="Bob">@bob</a> and @carol</p>`
+	if got := RenderMarkdown("hi @bob and @carol", mentions); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"real-time-forum/backend/database"
+	"regexp"
+	"unicode/utf16"
+)
+
+// mentionPattern matches an @ followed by the characters a username can contain.
+var mentionPattern = regexp.MustCompile(`@[a-zA-Z0-9_]+`)
+
+// FindMentions returns the @username references in content, in order, with their
+// spans set and their UserID left for the caller to resolve. An @ following a
+// username character, as in an email address, does not start a mention, and names
+// too short or too long to be usernames are skipped.
+func FindMentions(content string) []database.Mention {
+	mentions := []database.Mention{}
+	for _, match := range mentionPattern.FindAllStringIndex(content, -1) {
+		start, end := match[0], match[1]
+		if start > 0 && isUsernameByte(content[start-1]) {
+			continue
+		}
+		username := content[start+1 : end]
+		if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+			continue
+		}
+		offset := utf16Len(content[:start])
+		mentions = append(mentions, database.Mention{
+			Username: username,
+			Start:    offset,
+			End:      offset + utf16Len(content[start:end]),
+		})
+	}
+	return mentions
+}
+
+// isUsernameByte reports whether b can be part of a username.
+func isUsernameByte(b byte) bool {
+	return b == '_' || b == '@' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/store"
+	"time"
+)
+
+// SessionCookie is the name of the cookie carrying the session token.
+const SessionCookie = "session_token"
+
+// SessionDuration is how long a session stays valid after its last use.
+const SessionDuration = 24 * time.Hour
+
+// sessionRefreshInterval limits how often a session's expiry is pushed back.
+const sessionRefreshInterval = 5 * time.Minute
+
+// ErrSessionNotFound is returned when a token matches no live session.
+var ErrSessionNotFound = errors.New("session not found")
+
+// HashToken returns the hex encoded SHA-256 of a session token, as stored in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession starts a new session for a user and returns its token.
+func CreateSession(sessions store.SessionStore, userID string, r *http.Request) (string, database.Session, error) {
+	var session database.Session
+
+	token, err := NewUUID()
+	if err != nil {
+		return "", session, err
+	}
+	id, err := NewUUID()
+	if err != nil {
+		return "", session, err
+	}
+
+	now := time.Now()
+	session = database.Session{
+		ID:         id.String(),
+		LastUsedAt: now,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(SessionDuration),
+		UserAgent:  r.UserAgent(),
+		IP:         ClientIP(r),
+	}
+	if err := session.UserID.Parse(userID); err != nil {
+		return "", session, err
+	}
+
+	// drop the user's expired sessions while we are at it
+	if err := sessions.DeleteExpired(userID, now); err != nil {
+		return "", session, err
+	}
+
+	if err := sessions.Create(session, HashToken(token.String())); err != nil {
+		return "", session, err
+	}
+	return token.String(), session, nil
+}
+
+// GetSession returns the live session matching a token, along with its user's name.
+func GetSession(sessions store.SessionStore, token string) (database.Session, error) {
+	session, err := sessions.GetByTokenHash(HashToken(token), time.Now())
+	if err != nil {
+		return session, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// RefreshSession slides the expiry of a session forward, at most once per refresh
+// interval. It reports whether the session was extended.
+func RefreshSession(sessions store.SessionStore, session *database.Session) (bool, error) {
+	now := time.Now()
+	if now.Sub(session.LastUsedAt) < sessionRefreshInterval {
+		return false, nil
+	}
+
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(SessionDuration)
+	if err := sessions.Touch(session.ID, session.LastUsedAt, session.ExpiresAt); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSessions returns the live sessions of a user, most recently used first.
+func ListSessions(sessions store.SessionStore, userID string) ([]database.Session, error) {
+	return sessions.ListByUser(userID, time.Now())
+}
+
+// RevokeSession deletes one of a user's sessions.
+func RevokeSession(sessions store.SessionStore, userID, sessionID string) error {
+	err := sessions.Delete(userID, sessionID)
+	if err == store.ErrNotFound {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// SetSessionCookie writes the session cookie; a zero expiry clears it.
+func SetSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
+	if expires.IsZero() {
+		expires = time.Unix(0, 0)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expires,
+	})
+}
+
+// ClientIP returns the IP address of the remote end of a request.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RevokeUserSessions deletes every session of a user, logging them out everywhere.
+func RevokeUserSessions(sessions store.SessionStore, userID string) error {
+	return sessions.DeleteByUser(userID)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"real-time-forum/backend/database"
+	"real-time-forum/backend/store"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// recordingSessions is a session store remembering the token hashes it was given.
+type recordingSessions struct {
+	store.SessionStore
+	hashes []string
+}
+
+func (s *recordingSessions) Create(session database.Session, tokenHash string) error {
+	s.hashes = append(s.hashes, tokenHash)
+	return s.SessionStore.Create(session, tokenHash)
+}
+
+// newTestSessions returns the session store of a new in-memory store holding one user.
+func newTestSessions(t *testing.T) (*recordingSessions, string) {
+	t.Helper()
+	st := store.NewMemoryStore()
+	user := database.User{ID: uuid.Must(uuid.NewV4()), Username: "alice", Email: "alice@example.com"}
+	if err := st.Users.Create(user, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	return &recordingSessions{SessionStore: st.Sessions}, user.ID.String()
+}
+
+func TestCreateSessionStoresTokenHash(t *testing.T) {
+	sessions, userID := newTestSessions(t)
+
+	token, session, err := CreateSession(sessions, userID, httptest.NewRequest("POST", "/api/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions.hashes) != 1 || sessions.hashes[0] != HashToken(token) || sessions.hashes[0] == token {
+		t.Fatalf("stored token hashes %v, want only the hash of %q", sessions.hashes, token)
+	}
+
+	found, err := GetSession(sessions, token)
+	if err != nil || found.ID != session.ID {
+		t.Errorf("GetSession(token) = %q, %v, want session %q", found.ID, err, session.ID)
+	}
+	if _, err := GetSession(sessions, sessions.hashes[0]); err != ErrSessionNotFound {
+		t.Errorf("GetSession(stored hash) error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestRefreshSession(t *testing.T) {
+	sessions, userID := newTestSessions(t)
+	token, session, err := CreateSession(sessions, userID, httptest.NewRequest("POST", "/api/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshed, err := RefreshSession(sessions, &session); err != nil || refreshed {
+		t.Errorf("refreshing a session just created = %v, %v, want it left alone", refreshed, err)
+	}
+
+	// last used before the refresh interval, close to expiring
+	lastUsed := time.Now().Add(-2 * sessionRefreshInterval)
+	if err := sessions.Touch(session.ID, lastUsed, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	session, err = GetSession(sessions, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if refreshed, err := RefreshSession(sessions, &session); err != nil || !refreshed {
+		t.Fatalf("refreshing an idle session = %v, %v, want it extended", refreshed, err)
+	}
+	stored, err := GetSession(sessions, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ExpiresAt.Before(before.Add(SessionDuration)) || !stored.LastUsedAt.After(lastUsed) {
+		t.Errorf("stored session last used %v and expiring %v, want them pushed back", stored.LastUsedAt, stored.ExpiresAt)
+	}
+
+	if err := sessions.Touch(session.ID, lastUsed, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetSession(sessions, token); err != ErrSessionNotFound {
+		t.Errorf("GetSession of an expired session error = %v, want ErrSessionNotFound", err)
+	}
+}
@@ -1,32 +1,92 @@
 import { renderPage } from '../../router.js';
-import { showAlert, TimeAgo } from '../../utils.js';
+import { showAlert, TimeAgo, sendJSON, editedMarker, ownerActions, reportControls, bindReport, linkMentions, uploadFiles, attachmentList } from '../../utils.js';
+import { sendEvent } from '../../websocket.js';
 
 export let inChat = false;
 export let chatingWith = null;
 
+const TYPING_INTERVAL = 2000;
+
 function messageBubble(message) {
     const container = document.createElement('div');
     container.classList.add('message-bubble');
+    container.dataset.messageId = message.id;
     container.innerHTML = `
         <div class="message" sender-id="${message.sender_id}">
-            <p>${message.content}</p>
-            <span class="timestamp">${TimeAgo(message.created_at)}</span>
+            <p class="message-content">${linkMentions(message.content, message.mentions)}</p>
+            ${attachmentList(message.attachments)}
+            <span class="timestamp">${TimeAgo(message.created_at)} ${editedMarker(message)}</span>
+            ${ownerActions(message.sender_id)}
+            ${reportControls(message.sender_id)}
+            <form class="edit-message-form" style="display:none;">
+                <input type="text" name="content" required>
+                <button type="submit">Save</button>
+            </form>
         </div>
     `;
+    bindMessageActions(container, message);
+    bindReport(container, 'message', message.id);
     return container;
 }
 
+// Only the sender sees the actions, so the conversation is with the receiver
+function bindMessageActions(container, message) {
+    const actions = container.querySelector('.owner-actions');
+    if (!actions) return;
+
+    const url = `/api/messages/${message.receiver_id}/${message.id}`;
+    const editForm = container.querySelector('.edit-message-form');
+    actions.querySelector('.edit-item').addEventListener('click', () => {
+        const editing = editForm.style.display !== 'none';
+        editForm.querySelector('input[name="content"]').value = container.querySelector('.message-content').textContent;
+        editForm.style.display = editing ? 'none' : 'block';
+    });
+
+    editForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        try {
+            applyMessageUpdate(await sendJSON('PATCH', url, {
+                content: editForm.querySelector('input[name="content"]').value,
+            }));
+            editForm.style.display = 'none';
+        } catch (error) {
+            showAlert(error.message || 'Failed to edit message', 'error');
+        }
+    });
+
+    actions.querySelector('.delete-item').addEventListener('click', async () => {
+        if (!confirm('Delete this message?')) return;
+        try {
+            await sendJSON('DELETE', url);
+            removeMessage(message);
+        } catch (error) {
+            showAlert(error.message || 'Failed to delete message', 'error');
+        }
+    });
+}
+
+// applyMessageUpdate refreshes an edited message if it is on screen.
+export function applyMessageUpdate(message) {
+    const bubble = document.querySelector(`.message-bubble[data-message-id="${message.id}"]`);
+    if (!bubble) return;
+    bubble.querySelector('.message-content').innerHTML = linkMentions(message.content, message.mentions);
+    bubble.querySelector('.timestamp .edited').hidden = !message.edited_at;
+}
+
+export function removeMessage({ id }) {
+    document.querySelector(`.message-bubble[data-message-id="${id}"]`)?.remove();
+}
+
 export default async function Chat(userId, username) {
     const container = document.createElement('div');
     container.id = 'chat';
 
     // Pagination state
-    let offset = 0;
-    let hasMore = true;
+    let next = null;
     let isLoading = false;
 
     // Initial messages load
-    const response = await fetch(`/api/messages/${userId}?limit=10&offset=0`, {
+    const response = await fetch(`/api/messages/${userId}?limit=10`, {
         method: 'GET',
         credentials: 'include',
     });
@@ -37,8 +97,9 @@ export default async function Chat(userId, username) {
         return;
     }
 
-    const messages = await response.json();
-    hasMore = messages.length === 10;
+    const page = await response.json();
+    const messages = page.items;
+    next = page.next || null;
     inChat = true;
     chatingWith = userId;
 
@@ -46,10 +107,14 @@ export default async function Chat(userId, username) {
         <div class="chat-header">
             <button id="exit-chat">Exit</button>
             <h2>Chatting with <span class="chat-username">${username}</span></h2>
+            <span id="typing-indicator" class="typing hidden">typing...</span>
         </div>
         <div class="messages"></div>
         <form id="message-form">
-            <input type="text" id="message-input" placeholder="Type your message here..." required>
+            <input type="text" id="message-input" placeholder="Type your message here...">
+            <label class="attach-files" title="Attach files">
+                📎 <input type="file" id="message-files" accept="image/png,image/jpeg,image/gif,application/pdf,application/zip,text/plain" multiple>
+            </label>
             <button type="submit" id="send-button">Send</button>
         </form>
     `;
@@ -57,22 +122,22 @@ export default async function Chat(userId, username) {
     const messagesContainer = container.querySelector('.messages');
 
     messagesContainer.addEventListener('scroll', async () => {
-        if (isLoading || !hasMore) return;
+        if (isLoading || !next) return;
         
         // Load more when 80px from top
         if (messagesContainer.scrollTop < 80) {
             isLoading = true;
-            const newOffset = offset + 10;
             
             try {
-                const response = await fetch(`/api/messages/${userId}?limit=10&offset=${newOffset}`, {
+                const response = await fetch(`/api/messages/${userId}?limit=10&after=${encodeURIComponent(next)}`, {
                     credentials: 'include',
                 });
                 
                 if (!response.ok) throw new Error('Failed to load messages');
                 
-                const newMessages = await response.json();
-                hasMore = newMessages.length === 10;
+                const page = await response.json();
+                const newMessages = page.items;
+                next = page.next || null;
                 
                 if (newMessages.length > 0) {
                     const oldHeight = messagesContainer.scrollHeight;
@@ -83,7 +148,6 @@ export default async function Chat(userId, username) {
                     });
                     
                     messagesContainer.prepend(fragment);
-                    offset = newOffset;
                     
                     // Maintain scroll position
                     messagesContainer.scrollTop = messagesContainer.scrollHeight - oldHeight;
@@ -113,43 +177,46 @@ export default async function Chat(userId, username) {
     }
 
     scrollToBottom();
+    markRead(messages);
+
+    // Let the other user know we are typing, at most once per interval
+    let lastTyping = 0;
+    container.querySelector('#message-input').addEventListener('input', () => {
+        const now = Date.now();
+        if (now - lastTyping < TYPING_INTERVAL) return;
+        lastTyping = now;
+        sendEvent('typing_start', { receiver_id: userId });
+    });
 
     // Message submission handler
     container.querySelector('#message-form').addEventListener('submit', async (e) => {
         e.preventDefault();
         const messageInput = container.querySelector('#message-input');
+        const filesInput = container.querySelector('#message-files');
         const message = messageInput.value.trim();
+        const files = [...filesInput.files];
 
-        if (!message) {
+        if (!message && files.length === 0) {
             showAlert('Please enter a message', 'error');
             return;
         }
+        if (files.length > 4) {
+            showAlert('Attach at most 4 files', 'error');
+            return;
+        }
 
+        let attachmentIds;
         try {
-            const sendResponse = await fetch(`/api/messages/${userId}`, {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                credentials: 'include',
-                body: JSON.stringify({ content: message }),
-            });
+            attachmentIds = await uploadFiles(files);
+        } catch (err) {
+            showAlert(err.message, 'error');
+            return;
+        }
 
-            if (!sendResponse.ok) throw new Error('Failed to send message');
-            
-            // Optimistically add new message
-            const newMessage = {
-                content: message,
-                sender_id: Number(localStorage.getItem('user_id')),
-                created_at: new Date().toISOString(), // Use current time for the new message
-            };
-            messagesContainer.appendChild(messageBubble(newMessage));
-            scrollToBottom();
+        // The stored message is pushed back over the socket and appended by appendMessage
+        if (sendEvent('send_message', { receiver_id: userId, content: message, attachment_ids: attachmentIds })) {
             messageInput.value = '';
-            updateChat(userId);
-            hasMore = true;
-            offset = 0;
-        } catch (error) {
-            console.error('Message send failed:', error);
-            showAlert('Failed to send message, please try again', 'error');
+            filesInput.value = '';
         }
     });
 
@@ -161,10 +228,36 @@ export default async function Chat(userId, username) {
     return container;
 }
 
+export function appendMessage(message) {
+    const messagesContainer = document.querySelector('.messages');
+    if (!messagesContainer) return;
+
+    if (!messagesContainer.querySelector('.message-bubble')) {
+        messagesContainer.innerHTML = '';
+    }
+    messagesContainer.appendChild(messageBubble(message));
+    messagesContainer.scrollTop = messagesContainer.scrollHeight;
+    markRead([message]);
+}
+
+export function setTyping(userId, typing) {
+    const indicator = document.getElementById('typing-indicator');
+    if (!indicator || chatingWith !== userId) return;
+    indicator.classList.toggle('hidden', !typing);
+}
+
+// markRead marks the conversation read up to the newest message received from the other user
+function markRead(messages) {
+    const latest = messages.find(message => message.sender_id === chatingWith);
+    if (latest) {
+        sendEvent('mark_read', { user_id: chatingWith, up_to: latest.id });
+    }
+}
+
 export function updateChat(userId) {
-    fetch(`/api/messages/${userId}?limit=10&offset=0`)
+    fetch(`/api/messages/${userId}?limit=10`)
         .then(response => response.json())
-        .then(messages => {
+        .then(({ items: messages }) => {
             const messagesContainer = document.querySelector('.messages');
             if (!messagesContainer) return;
 
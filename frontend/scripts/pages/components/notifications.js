@@ -0,0 +1,169 @@
+import { TimeAgo, sendJSON, showAlert, escapeHTML } from "../../utils.js";
+import { getUser } from "./userlist.js";
+import { onUserClick } from "../home.js";
+
+// Cursor of the next page of notifications, null when there are no more
+let notificationCursor = null;
+const limit = 10;
+
+const NOTIFICATION_LABELS = {
+    comment: "Comments on my posts",
+    reply: "Replies to my comments",
+    reaction: "Reactions",
+    mention: "Mentions",
+    message: "Private messages",
+};
+
+// describe phrases what a notification tells the user.
+function describe(notification) {
+    const actor = `<strong>${escapeHTML(getUser(notification.actor_id))}</strong>`;
+    switch (notification.type) {
+        case "comment":
+            return `${actor} commented on your post #${notification.post_id}`;
+        case "reply":
+            return `${actor} replied to your comment on post #${notification.post_id}`;
+        case "reaction":
+            return notification.comment_id
+                ? `${actor} reacted to your comment on post #${notification.post_id}`
+                : `${actor} reacted to your post #${notification.post_id}`;
+        case "mention":
+            return `${actor} mentioned you`;
+        case "message":
+            return `${actor} sent you a message`;
+    }
+    return `${actor} did something`;
+}
+
+// setUnread shows the number of unread notifications on the bell.
+function setUnread(count, badge = document.querySelector("#notification-badge")) {
+    if (!badge) return;
+    badge.textContent = count > 99 ? "99+" : count;
+    badge.hidden = count <= 0;
+}
+
+function notificationItem(notification) {
+    const item = document.createElement("li");
+    item.classList.add("notification-item");
+    item.classList.toggle("unread", !notification.read_at);
+    item.dataset.notificationId = notification.id;
+    item.innerHTML = `
+        <p>${describe(notification)}</p>
+        <span class="notification-time">${TimeAgo(notification.created_at)}</span>
+    `;
+    item.addEventListener("click", async () => {
+        if (item.classList.contains("unread")) {
+            try {
+                const { unread } = await sendJSON("POST", `/api/notifications/${notification.id}/read`);
+                item.classList.remove("unread");
+                setUnread(unread);
+            } catch (error) {
+                showAlert(error.message || "An error occurred while updating the notification", "error");
+            }
+        }
+        if (notification.type === "message") {
+            document.querySelector("#notification-panel").hidden = true;
+            onUserClick(notification.actor_id, getUser(notification.actor_id));
+        }
+    });
+    return item;
+}
+
+async function loadNotifications(list, loadMore) {
+    const after = notificationCursor ? `&after=${encodeURIComponent(notificationCursor)}` : "";
+    try {
+        const { items, next } = await sendJSON("GET", `/api/notifications?limit=${limit}${after}`);
+        items.forEach(notification => list.appendChild(notificationItem(notification)));
+        if (list.children.length === 0) list.innerHTML = `<li class="notification-empty">No notifications yet</li>`;
+        notificationCursor = next || null;
+        loadMore.style.display = notificationCursor ? "block" : "none";
+    } catch (error) {
+        showAlert(error.message || "An error occurred while loading notifications", "error");
+    }
+}
+
+async function loadPreferences(form) {
+    try {
+        const preferences = await sendJSON("GET", "/api/notifications/preferences");
+        form.innerHTML = Object.entries(NOTIFICATION_LABELS).map(([type, label]) => `
+            <label><input type="checkbox" name="${type}"${preferences[type] ? " checked" : ""}> ${label}</label>
+        `).join("");
+    } catch (error) {
+        showAlert(error.message || "An error occurred while loading preferences", "error");
+    }
+}
+
+// receiveNotification shows a notification pushed over the WebSocket.
+export function receiveNotification({ notification, unread }) {
+    setUnread(unread);
+    const list = document.querySelector("#notification-list");
+    if (!list || list.innerHTML === "") return;
+    list.querySelector(".notification-empty")?.remove();
+    list.prepend(notificationItem(notification));
+}
+
+// applyNotificationsRead mirrors notifications read from another tab or device.
+export function applyNotificationsRead({ id, unread }) {
+    setUnread(unread);
+    const selector = id ? `.notification-item[data-notification-id="${id}"]` : ".notification-item";
+    document.querySelectorAll(selector).forEach(item => item.classList.remove("unread"));
+}
+
+export default async function Notifications() {
+    notificationCursor = null;
+    const container = document.createElement("div");
+    container.classList.add("notifications");
+    container.innerHTML = `
+        <button id="notification-bell" aria-label="Notifications">🔔<span id="notification-badge" hidden></span></button>
+        <div id="notification-panel" hidden>
+            <div class="notification-actions">
+                <button class="mark-all-read">Mark all read</button>
+                <button class="toggle-preferences">Settings</button>
+            </div>
+            <form class="notification-preferences" hidden></form>
+            <ul id="notification-list"></ul>
+            <button class="load-more-notifications" style="display:none;">Show more</button>
+        </div>
+    `;
+
+    const panel = container.querySelector("#notification-panel");
+    const list = container.querySelector("#notification-list");
+    const loadMore = container.querySelector(".load-more-notifications");
+    const preferences = container.querySelector(".notification-preferences");
+
+    container.querySelector("#notification-bell").addEventListener("click", () => {
+        panel.hidden = !panel.hidden;
+        if (!panel.hidden && list.innerHTML === "") loadNotifications(list, loadMore);
+    });
+    loadMore.addEventListener("click", () => loadNotifications(list, loadMore));
+
+    container.querySelector(".mark-all-read").addEventListener("click", async () => {
+        try {
+            applyNotificationsRead(await sendJSON("POST", "/api/notifications/read-all"));
+        } catch (error) {
+            showAlert(error.message || "An error occurred while updating notifications", "error");
+        }
+    });
+
+    container.querySelector(".toggle-preferences").addEventListener("click", () => {
+        preferences.hidden = !preferences.hidden;
+        if (!preferences.hidden) loadPreferences(preferences);
+    });
+    preferences.addEventListener("change", async (e) => {
+        try {
+            await sendJSON("PUT", "/api/notifications/preferences", { [e.target.name]: e.target.checked });
+        } catch (error) {
+            e.target.checked = !e.target.checked;
+            showAlert(error.message || "An error occurred while saving preferences", "error");
+        }
+    });
+
+    try {
+        const { unread } = await sendJSON("GET", "/api/notifications/unread-count");
+        // the bell is not in the document until home renders
+        setUnread(unread, container.querySelector("#notification-badge"));
+    } catch (error) {
+        console.error("Fetch unread notifications error:", error);
+    }
+
+    return container;
+}
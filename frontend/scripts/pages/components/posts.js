@@ -1,21 +1,254 @@
-import { TimeAgo } from "../../utils.js";
+import { TimeAgo, sendJSON, editedMarker, ownerActions, isModerator, reportControls, bindReport, escapeHTML, uploadFiles, attachmentList } from "../../utils.js";
 import { getUser } from "./userlist.js";
 
-let postOffset = 0;
-let commentOffsets = {};
+// Cursors of the next page of posts and of each post's comments, null when there are no more
+let postCursor = null;
+let commentCursors = {};
 const limit = 10;
+let categoryNames = {};
+let categoryFilter = "";
+
+async function fetchCategories() {
+    try {
+        const response = await fetch("/api/categories", {
+            method: "GET",
+            credentials: "include",
+        });
+
+        if (!response.ok) throw new Error("Failed to fetch categories");
+
+        const categories = await response.json();
+        categoryNames = Object.fromEntries(categories.map(category => [category.slug, category.name]));
+        return categories;
+    } catch (error) {
+        console.error("Fetch categories error:", error);
+        return [];
+    }
+}
+
+function postsURL() {
+    const filter = categoryFilter ? `&category=${encodeURIComponent(categoryFilter)}` : "";
+    const after = postCursor ? `&after=${encodeURIComponent(postCursor)}` : "";
+    return `/api/get-posts?limit=${limit}${after}${filter}`;
+}
+
+function reactionBar(targetType, item) {
+    return `
+        <div class="reactions" data-target-type="${targetType}" data-target-id="${item.id}">
+            <button class="reaction ${item.my_reaction === "like" ? "active" : ""}" data-reaction="like">👍 <span class="count">${item.likes || 0}</span></button>
+            <button class="reaction ${item.my_reaction === "dislike" ? "active" : ""}" data-reaction="dislike">👎 <span class="count">${item.dislikes || 0}</span></button>
+        </div>
+    `;
+}
+
+function bindReactions(container) {
+    const bar = container.querySelector(".reactions");
+    bar.querySelectorAll(".reaction").forEach(button => {
+        button.addEventListener("click", async () => {
+            try {
+                const response = await fetch("/api/reactions", {
+                    method: "POST",
+                    headers: {
+                        "Content-Type": "application/json",
+                    },
+                    credentials: "include",
+                    body: JSON.stringify({
+                        target_type: bar.dataset.targetType,
+                        target_id: Number(bar.dataset.targetId),
+                        reaction: button.dataset.reaction,
+                    }),
+                });
+
+                if (!response.ok) {
+                    const errorData = await response.json();
+                    throw new Error(errorData.message || "Failed to react");
+                }
+                applyReaction(await response.json());
+            } catch (error) {
+                showAlert(error.message || "An error occurred while reacting", "error");
+            }
+        });
+    });
+}
+
+// applyReaction updates every rendered copy of a post or comment with new reaction counts.
+export function applyReaction(reaction) {
+    const selector = `.reactions[data-target-type="${reaction.target_type}"][data-target-id="${reaction.target_id}"]`;
+    document.querySelectorAll(selector).forEach(bar => {
+        bar.querySelectorAll(".reaction").forEach(button => {
+            const kind = button.dataset.reaction;
+            button.querySelector(".count").textContent = kind === "like" ? reaction.likes : reaction.dislikes;
+            if (reaction.user_id === localStorage.getItem("userId")) {
+                button.classList.toggle("active", reaction.reaction === kind);
+            }
+        });
+    });
+}
+
+function categoryList(slugs) {
+    return `Categories: ${(slugs || []).map(slug => categoryNames[slug] || slug).join(", ") || "Uncategorized"}`;
+}
+
+// applyPostUpdate refreshes every rendered copy of an edited post.
+export function applyPostUpdate(post) {
+    document.querySelectorAll(`.post-card[data-post-id="${post.id}"]`).forEach(card => {
+        card.dataset.categories = (post.categories || []).join(",");
+        card.dataset.content = post.content;
+        card.querySelector(".post-title").textContent = post.title;
+        card.querySelector(".post-content").innerHTML = post.content_html;
+        card.querySelector(".post-categories").textContent = categoryList(post.categories);
+        card.querySelector(".post-timestamp .edited").hidden = !post.edited_at;
+        card.querySelector(".post-locked").hidden = !post.locked_at;
+        const lockButton = card.querySelector(".lock-post");
+        if (lockButton) lockButton.textContent = post.locked_at ? "Unlock" : "Lock";
+    });
+}
+
+// removePost takes a deleted post, and its comments with it, off the page.
+export function removePost({ id }) {
+    document.querySelectorAll(`.post-card[data-post-id="${id}"]`).forEach(card => card.remove());
+}
+
+// applyCommentUpdate refreshes every rendered copy of an edited comment.
+export function applyCommentUpdate(comment) {
+    document.querySelectorAll(`.comment-bubble[data-comment-id="${comment.id}"]`).forEach(bubble => {
+        bubble.dataset.content = comment.content;
+        bubble.querySelector(".comment-content").innerHTML = comment.content_html;
+        bubble.querySelector(".comment-timestamp .edited").hidden = !comment.edited_at;
+    });
+}
+
+// removeComment takes a deleted comment off the page, leaving a placeholder in its
+// place when it has replies so that the thread stays readable.
+export function removeComment({ id }) {
+    document.querySelectorAll(`.comment-bubble[data-comment-id="${id}"]`).forEach(bubble => {
+        if (Number(bubble.dataset.replyCount) > 0) {
+            const body = bubble.querySelector(":scope > .comment");
+            if (body.classList.contains("comment-deleted")) return;
+            body.outerHTML = deletedCommentBody(bubble.dataset.createdAt);
+            bubble.querySelector(":scope > .comment-thread > .reply-item")?.remove();
+            bubble.querySelector(":scope > .comment-thread > .reply-form")?.remove();
+            return;
+        }
+        const parent = bubble.parentElement.closest(".comment-bubble");
+        bubble.remove();
+        if (parent) setReplyCount(parent, Number(parent.dataset.replyCount) - 1);
+    });
+}
+
+// bindLock lets moderators lock and unlock the thread of a post.
+function bindLock(container, post) {
+    const lockButton = container.querySelector(".lock-post");
+    if (!lockButton) return;
+
+    lockButton.addEventListener("click", async () => {
+        const locked = !container.querySelector(".post-locked").hidden;
+        try {
+            applyPostUpdate(await sendJSON(locked ? "DELETE" : "POST", `/api/posts/${post.id}/lock`));
+        } catch (error) {
+            showAlert(error.message || "An error occurred while locking the post", "error");
+        }
+    });
+}
+
+function bindPostActions(container, post) {
+    const actions = container.querySelector(".owner-actions");
+    if (!actions) return;
+
+    const editForm = container.querySelector(".edit-post-form");
+    actions.querySelector(".edit-item")?.addEventListener("click", () => {
+        const editing = editForm.style.display !== "none";
+        editForm.querySelector('input[name="title"]').value = container.querySelector(".post-title").textContent;
+        editForm.querySelector('textarea[name="content"]').value = container.dataset.content;
+        editForm.style.display = editing ? "none" : "block";
+    });
+
+    editForm.addEventListener("submit", async (e) => {
+        e.preventDefault();
+        try {
+            const updated = await sendJSON("PATCH", `/api/posts/${post.id}`, {
+                title: editForm.querySelector('input[name="title"]').value,
+                content: editForm.querySelector('textarea[name="content"]').value,
+                categories: container.dataset.categories.split(",").filter(Boolean),
+            });
+            applyPostUpdate(updated);
+            editForm.style.display = "none";
+        } catch (error) {
+            showAlert(error.message || "An error occurred while editing the post", "error");
+        }
+    });
+
+    actions.querySelector(".delete-item").addEventListener("click", async () => {
+        if (!confirm("Delete this post and its comments?")) return;
+        try {
+            await sendJSON("DELETE", `/api/posts/${post.id}`);
+            removePost(post);
+        } catch (error) {
+            showAlert(error.message || "An error occurred while deleting the post", "error");
+        }
+    });
+}
+
+function bindCommentActions(container, comment) {
+    const actions = container.querySelector(".owner-actions");
+    if (!actions) return;
+
+    const editForm = container.querySelector(".edit-comment-form");
+    actions.querySelector(".edit-item")?.addEventListener("click", () => {
+        const editing = editForm.style.display !== "none";
+        editForm.querySelector('textarea[name="content"]').value = container.dataset.content;
+        editForm.style.display = editing ? "none" : "block";
+    });
+
+    editForm.addEventListener("submit", async (e) => {
+        e.preventDefault();
+        try {
+            const updated = await sendJSON("PATCH", `/api/comments/${comment.id}`, {
+                content: editForm.querySelector('textarea[name="content"]').value,
+            });
+            applyCommentUpdate(updated);
+            editForm.style.display = "none";
+        } catch (error) {
+            showAlert(error.message || "An error occurred while editing the comment", "error");
+        }
+    });
+
+    actions.querySelector(".delete-item").addEventListener("click", async () => {
+        if (!confirm("Delete this comment?")) return;
+        try {
+            await sendJSON("DELETE", `/api/comments/${comment.id}`);
+            removeComment(comment);
+        } catch (error) {
+            showAlert(error.message || "An error occurred while deleting the comment", "error");
+        }
+    });
+}
 
 function createPostCard(post) {
     if (!post || !post.id || !post.title || !post.content) return document.createElement("div");
 
     const container = document.createElement("div");
     container.classList.add("post-card");
+    container.dataset.postId = post.id;
+    container.dataset.categories = (post.categories || []).join(",");
+    container.dataset.content = post.content;
     container.innerHTML = `
-        <h3 class="post-title">${post.title}</h3>
-        <p class="post-username">${getUser(post.user_id) || "Unknown User"}</p>
-        <p class="post-content">${post.content}</p>
-        <p class="post-categories">Category: ${post.category || "Uncategorized"}</p>
-        <p class="post-timestamp">${TimeAgo(post.created_at)}</p>
+        <h3 class="post-title">${escapeHTML(post.title)}</h3>
+        <p class="post-username"><a href="#" class="profile-link" data-user-id="${post.user_id}">${escapeHTML(getUser(post.user_id) || "Unknown User")}</a></p>
+        <div class="post-content">${post.content_html}</div>
+        ${attachmentList(post.attachments)}
+        <p class="post-categories">${categoryList(post.categories)}</p>
+        <p class="post-timestamp">${TimeAgo(post.created_at)} ${editedMarker(post)}</p>
+        <p class="post-locked"${post.locked_at ? "" : " hidden"}>🔒 Locked: only moderators can comment</p>
+        ${ownerActions(post.user_id, true)}
+        ${reportControls(post.user_id)}
+        ${isModerator() ? `<button type="button" class="lock-post">${post.locked_at ? "Unlock" : "Lock"}</button>` : ""}
+        <form class="edit-post-form" style="display:none;">
+            <input type="text" name="title" required>
+            <textarea name="content" required></textarea>
+            <button type="submit">Save</button>
+        </form>
+        ${reactionBar("post", post)}
         <button data-post-id="${post.id}" class="show-comments">Comments</button>
         <form class="comment-form" data-post-id="${post.id}" style="display:none;">
             <input type="text" name="content" placeholder="Comment..." required>
@@ -25,6 +258,11 @@ function createPostCard(post) {
         <button class="load-more-comments" data-post-id="${post.id}" style="display:none;">Show more comments</button>
     `;
 
+    bindReactions(container);
+    bindPostActions(container, post);
+    bindLock(container, post);
+    bindReport(container, "post", post.id);
+
     const commentButton = container.querySelector(".show-comments");
     const commentForm = container.querySelector(".comment-form");
     const loadMoreButton = container.querySelector(".load-more-comments");
@@ -53,7 +291,6 @@ function createPostCard(post) {
             try {
                 await postComment(post.id, content);
                 commentForm.reset();
-                commentOffsets[post.id] = 0;
                 commentsDropdown.innerHTML = "";
                 loadComments(post.id, commentsDropdown, loadMoreButton, commentForm);
             } catch (error) {
@@ -65,18 +302,50 @@ function createPostCard(post) {
     return container;
 }
 
-async function loadComments(postId, commentsDropdown, loadMoreButton, commentForm) {
-    if (commentOffsets[postId] === undefined) commentOffsets[postId] = 0;
+// renderSearchResults lists search matches; snippets are escaped by the server
+// and only carry <mark> highlighting.
+function renderSearchResults(resultsDiv, results) {
+    const { posts, comments, users } = results;
+    if (posts.length + comments.length + users.length === 0) {
+        resultsDiv.innerHTML = `<p class="search-empty">No results</p>`;
+        return;
+    }
 
+    resultsDiv.innerHTML = `
+        <button class="close-search">Close</button>
+        ${posts.map(post => `
+            <div class="search-result">
+                <p class="comment-username">Post by ${escapeHTML(getUser(post.user_id) || "Unknown User")}</p>
+                <p>${post.snippet}</p>
+            </div>
+        `).join("")}
+        ${comments.map(comment => `
+            <div class="search-result">
+                <p class="comment-username">Comment by ${escapeHTML(getUser(comment.user_id) || "Unknown User")} on post #${comment.post_id}</p>
+                <p>${comment.snippet}</p>
+            </div>
+        `).join("")}
+        ${users.map(user => `
+            <div class="search-result">
+                <p class="comment-username ${user.status}">${escapeHTML(user.username)}</p>
+            </div>
+        `).join("")}
+    `;
+    resultsDiv.querySelector(".close-search").addEventListener("click", () => {
+        resultsDiv.innerHTML = "";
+    });
+}
+
+async function loadComments(postId, commentsDropdown, loadMoreButton, commentForm) {
     try {
-        const response = await fetch(`/api/get-comments?post_id=${postId}&limit=${limit}&offset=${commentOffsets[postId]}`, {
+        const response = await fetch(`/api/get-comments?post_id=${postId}&limit=${limit}`, {
             method: "GET",
             credentials: "include",
         });
 
         if (!response.ok) throw new Error("Failed to fetch comments");
 
-        const comments = await response.json();
+        const { items: comments, next } = await response.json();
         if (comments.length === 0) {
             loadMoreButton.style.display = "none";
             commentsDropdown.innerHTML = "No comments yet";
@@ -88,8 +357,8 @@ async function loadComments(postId, commentsDropdown, loadMoreButton, commentFor
             commentsDropdown.appendChild(createCommentBubble(comment));
         });
 
-        commentOffsets[postId] += comments.length;
-        loadMoreButton.style.display = comments.length === limit ? "block" : "none";
+        commentCursors[postId] = next || null;
+        loadMoreButton.style.display = next ? "block" : "none";
         loadMoreButton.onclick = () => loadMoreComments(postId, commentsDropdown, loadMoreButton);
         commentForm.style.display = "block";
     } catch (error) {
@@ -99,38 +368,149 @@ async function loadComments(postId, commentsDropdown, loadMoreButton, commentFor
 
 async function loadMoreComments(postId, commentsDropdown, loadMoreButton) {
     try {
-        const response = await fetch(`/api/get-comments?post_id=${postId}&limit=${limit}&offset=${commentOffsets[postId]}`, {
+        const after = encodeURIComponent(commentCursors[postId]);
+        const response = await fetch(`/api/get-comments?post_id=${postId}&limit=${limit}&after=${after}`, {
             method: "GET",
             credentials: "include",
         });
 
         if (!response.ok) throw new Error("Failed to fetch more comments");
 
-        const comments = await response.json();
+        const { items: comments, next } = await response.json();
         comments.forEach(comment => {
             commentsDropdown.appendChild(createCommentBubble(comment));
         });
 
-        commentOffsets[postId] += comments.length;
-        loadMoreButton.style.display = comments.length < limit ? "none" : "block";
+        commentCursors[postId] = next || null;
+        loadMoreButton.style.display = next ? "block" : "none";
     } catch (error) {
         console.error("Fetch more comments error:", error);
     }
 }
 
+// Replies nest as deeply as the server allows; comments at that depth cannot be answered.
+const MAX_COMMENT_DEPTH = 5;
+
 function createCommentBubble(comment) {
     const container = document.createElement("div");
     container.classList.add("comment-bubble");
+    container.dataset.commentId = comment.id;
+    container.dataset.createdAt = comment.created_at;
+    container.dataset.replyCount = comment.reply_count || 0;
+    container.dataset.content = comment.content;
+    const canReply = !comment.deleted && comment.depth < MAX_COMMENT_DEPTH;
     container.innerHTML = `
+        ${comment.deleted ? deletedCommentBody(comment.created_at) : `
         <div class="comment" sender-id="${comment.user_id}">
-            <p class="comment-username">${getUser(comment.user_id)}</p>
-            <p class="comment-content">${comment.content}</p>
-            <span class="comment-timestamp">${TimeAgo(comment.created_at)}</span>
+            <p class="comment-username"><a href="#" class="profile-link" data-user-id="${comment.user_id}">${escapeHTML(getUser(comment.user_id))}</a></p>
+            <div class="comment-content">${comment.content_html}</div>
+            <span class="comment-timestamp">${TimeAgo(comment.created_at)} ${editedMarker(comment)}</span>
+            ${ownerActions(comment.user_id, true)}
+            ${reportControls(comment.user_id)}
+            <form class="edit-comment-form" style="display:none;">
+                <textarea name="content" required></textarea>
+                <button type="submit">Save</button>
+            </form>
+            ${reactionBar("comment", comment)}
+        </div>`}
+        <div class="comment-thread">
+            ${canReply ? `
+            <button class="reply-item">Reply</button>
+            <form class="reply-form" style="display:none;">
+                <input type="text" name="content" placeholder="Reply..." required>
+                <button type="submit">Reply</button>
+            </form>` : ""}
+            <button class="show-replies" hidden></button>
+            <div class="replies"></div>
+            <button class="load-more-replies" style="display:none;">Show more replies</button>
         </div>
     `;
+    setReplyCount(container, comment.reply_count || 0);
+    if (!comment.deleted) {
+        bindReactions(container);
+        bindCommentActions(container, comment);
+        bindReport(container, "comment", comment.id);
+    }
+    bindThread(container, comment);
     return container;
 }
 
+// deletedCommentBody renders the placeholder left for a deleted comment with replies.
+function deletedCommentBody(createdAt) {
+    return `
+        <div class="comment comment-deleted">
+            <p class="comment-content">[deleted]</p>
+            <span class="comment-timestamp">${TimeAgo(createdAt)}</span>
+        </div>`;
+}
+
+// setReplyCount records how many replies a comment has and labels its toggle with it.
+function setReplyCount(bubble, count) {
+    bubble.dataset.replyCount = count;
+    const toggle = bubble.querySelector(":scope > .comment-thread > .show-replies");
+    const open = bubble.querySelector(":scope > .comment-thread > .replies").innerHTML !== "";
+    toggle.hidden = count <= 0;
+    toggle.textContent = open ? "Hide replies" : `View ${count} ${count === 1 ? "reply" : "replies"}`;
+}
+
+// bindThread lets users answer a comment and expand its replies, which are loaded a page at a time.
+function bindThread(container, comment) {
+    const thread = container.querySelector(".comment-thread");
+    const toggle = thread.querySelector(".show-replies");
+    const replies = thread.querySelector(".replies");
+    const loadMore = thread.querySelector(".load-more-replies");
+    const replyForm = thread.querySelector(".reply-form");
+    let cursor = null;
+
+    const loadReplies = async () => {
+        const after = cursor ? `&after=${encodeURIComponent(cursor)}` : "";
+        try {
+            const { items, next } = await sendJSON("GET", `/api/comments/${comment.id}/replies?limit=${limit}${after}`);
+            items.forEach(reply => replies.appendChild(createCommentBubble(reply)));
+            cursor = next || null;
+            loadMore.style.display = cursor ? "block" : "none";
+            setReplyCount(container, Number(container.dataset.replyCount));
+        } catch (error) {
+            showAlert(error.message || "An error occurred while loading replies", "error");
+        }
+    };
+
+    toggle.addEventListener("click", () => {
+        if (replies.innerHTML !== "") {
+            replies.innerHTML = "";
+            cursor = null;
+            loadMore.style.display = "none";
+            setReplyCount(container, Number(container.dataset.replyCount));
+            return;
+        }
+        loadReplies();
+    });
+    loadMore.addEventListener("click", loadReplies);
+
+    if (!replyForm) return;
+    thread.querySelector(".reply-item").addEventListener("click", () => {
+        replyForm.style.display = replyForm.style.display === "none" ? "flex" : "none";
+    });
+    replyForm.addEventListener("submit", async (e) => {
+        e.preventDefault();
+        try {
+            const reply = await sendJSON("POST", `/api/comments/${comment.id}/replies`, {
+                content: replyForm.querySelector('input[name="content"]').value,
+            });
+            replyForm.reset();
+            replyForm.style.display = "none";
+            setReplyCount(container, Number(container.dataset.replyCount) + 1);
+            if (replies.innerHTML === "") {
+                loadReplies();
+            } else {
+                replies.prepend(createCommentBubble(reply));
+            }
+        } catch (error) {
+            showAlert(error.message || "An error occurred while posting the reply", "error");
+        }
+    });
+}
+
 async function postComment(postId, content) {
     try {
         const response = await fetch(`/api/create-comment`, {
@@ -165,20 +545,66 @@ function showAlert(message, type) {
 }
 
 export default async function Posts() {
+    const categories = await fetchCategories();
     const container = document.createElement("div");
     container.innerHTML = `
         <form id="post-form" class="post-form">
             <h2>Posts</h2>
             <input type="text" id="title" name="title" placeholder="Title..." required>
             <textarea name="content" placeholder="Content..." required></textarea>
-            <input type="text" id="categories" name="category" placeholder="Categories (separated by commas)" required>
+            <label class="attach-files">
+                Images <input type="file" name="files" accept="image/png,image/jpeg,image/gif" multiple>
+            </label>
+            <div class="category-options">
+                ${categories.map(category => `
+                    <label title="${category.description}">
+                        <input type="checkbox" name="categories" value="${category.slug}"> ${category.name}
+                    </label>
+                `).join("")}
+            </div>
             <button type="submit">Post</button>
         </form>
+        <form id="search-form" class="search-form">
+            <input type="search" name="q" placeholder="Search posts, comments and members..." required>
+            <button type="submit">Search</button>
+        </form>
+        <div id="search-results" class="search-results"></div>
+        <select id="category-filter" class="category-filter">
+            <option value="">All categories</option>
+            ${categories.map(category => `<option value="${category.slug}">${category.name} (${category.post_count})</option>`).join("")}
+        </select>
         <div id="post-list" class="post-list"></div>
     `;
 
+    container.querySelector("#search-form").addEventListener("submit", async (e) => {
+        e.preventDefault();
+        const q = new FormData(e.target).get("q");
+        const resultsDiv = container.querySelector("#search-results");
+        const filter = categoryFilter ? `&category=${encodeURIComponent(categoryFilter)}` : "";
+
+        try {
+            const response = await fetch(`/api/search?q=${encodeURIComponent(q)}&limit=${limit}${filter}`, {
+                method: "GET",
+                credentials: "include",
+            });
+
+            if (!response.ok) {
+                const errorData = await response.json();
+                throw new Error(errorData.message || "Search failed");
+            }
+            renderSearchResults(resultsDiv, await response.json());
+        } catch (error) {
+            showAlert(error.message || "An error occurred while searching", "error");
+        }
+    });
+
+    container.querySelector("#category-filter").addEventListener("change", (e) => {
+        categoryFilter = e.target.value;
+        loadPosts(true);
+    });
+
     async function loadPosts(reset = false) {
-        if (reset) postOffset = 0;
+        if (reset) postCursor = null;
 
         const postList = container.querySelector("#post-list");
         if (!postList) return;
@@ -186,33 +612,33 @@ export default async function Posts() {
         postList.innerHTML = "";
 
         try {
-            const response = await fetch(`/api/get-posts?limit=${limit}&offset=${postOffset}`, {
+            const response = await fetch(postsURL(), {
                 method: "GET",
                 credentials: "include",
             });
 
             if (!response.ok) throw new Error("Failed to fetch posts");
 
-            const posts = await response.json();
+            const { items: posts, next } = await response.json();
             if (posts.length === 0) return;
 
             posts.forEach(post => {
                 postList.appendChild(createPostCard(post));
             });
 
-            postOffset += posts.length;
-            addLoadMoreButton(posts.length);
+            postCursor = next || null;
+            addLoadMoreButton();
         } catch (error) {
             console.error("Fetch posts error:", error);
         }
     }
 
-    function addLoadMoreButton(postsLength) {
+    function addLoadMoreButton() {
         const postList = container.querySelector("#post-list");
         const existingButton = postList.querySelector("button.show-more-posts");
         if (existingButton) existingButton.remove();
 
-        if (postsLength === limit) {
+        if (postCursor) {
             const loadMoreButton = document.createElement("button");
             loadMoreButton.textContent = "Show more posts";
             loadMoreButton.classList.add("show-more-posts");
@@ -223,14 +649,14 @@ export default async function Posts() {
 
     async function loadMorePosts() {
         try {
-            const response = await fetch(`/api/get-posts?limit=${limit}&offset=${postOffset}`, {
+            const response = await fetch(postsURL(), {
                 method: "GET",
                 credentials: "include",
             });
 
             if (!response.ok) throw new Error("Failed to fetch more posts");
 
-            const posts = await response.json();
+            const { items: posts, next } = await response.json();
             if (posts.length === 0) return;
 
             const postList = container.querySelector("#post-list");
@@ -238,8 +664,8 @@ export default async function Posts() {
                 postList.appendChild(createPostCard(post));
             });
 
-            postOffset += posts.length;
-            addLoadMoreButton(posts.length);
+            postCursor = next || null;
+            addLoadMoreButton();
         } catch (error) {
             console.error("Fetch more posts error:", error);
         }
@@ -249,9 +675,28 @@ export default async function Posts() {
     postForm.addEventListener("submit", async (e) => {
         e.preventDefault();
         const formData = new FormData(postForm);
-        const data = Object.fromEntries(formData.entries());
-
-        if (!data.title || !data.content || !data.category) return;
+        const data = {
+            title: formData.get("title"),
+            content: formData.get("content"),
+            categories: formData.getAll("categories"),
+        };
+
+        if (!data.title || !data.content) return;
+        if (data.categories.length === 0) {
+            showAlert("Pick at least one category", "error");
+            return;
+        }
+        const files = formData.getAll("files").filter(file => file.size > 0);
+        if (files.length > 4) {
+            showAlert("Attach at most 4 images", "error");
+            return;
+        }
+        try {
+            data.attachment_ids = await uploadFiles(files);
+        } catch (err) {
+            showAlert(err.message, "error");
+            return;
+        }
 
         const response = await fetch("/api/create-post", {
             method: "POST",
@@ -266,7 +711,7 @@ export default async function Posts() {
             await loadPosts(true);
         } else {
             const errorData = await response.json();
-            console.error(errorData.message || "Failed to create post");
+            showAlert(errorData.message || "Failed to create post", "error");
         }
     });
 
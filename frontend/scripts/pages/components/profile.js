@@ -0,0 +1,148 @@
+import { renderPage } from '../../router.js';
+import { showAlert, TimeAgo, sendJSON, escapeHTML, isOwn, uploadFiles } from '../../utils.js';
+import { onUserClick } from '../home.js';
+
+const PRIVACY_LABELS = {
+    display_name: 'Display name',
+    bio: 'Bio',
+    avatar: 'Avatar',
+    joined: 'Join date',
+    activity: 'Post and comment counts',
+    last_seen: 'Last seen',
+};
+
+const GENDERS = ['male', 'female', 'other'];
+
+// avatar renders the avatar of a profile, or the initial of its username when it has none.
+function avatar(profile) {
+    if (profile.avatar_id) {
+        return `<img class="avatar" src="/api/attachments/${profile.avatar_id}/thumbnail" alt="">`;
+    }
+    return `<span class="avatar avatar-initial">${escapeHTML(profile.username.charAt(0).toUpperCase())}</span>`;
+}
+
+// Fields hidden by the user are missing from the profile and left out here.
+function profileCard(profile) {
+    const facts = [];
+    if (profile.joined_at) facts.push(`Joined ${new Date(profile.joined_at).toLocaleDateString()}`);
+    if (profile.post_count !== undefined) facts.push(`${profile.post_count} posts, ${profile.comment_count} comments`);
+    if (profile.status && profile.status !== 'offline') {
+        facts.push(profile.status);
+    } else if (profile.last_seen_at) {
+        facts.push(`Last seen ${TimeAgo(profile.last_seen_at)}`);
+    }
+
+    return `
+        <div class="profile-card">
+            ${avatar(profile)}
+            <div>
+                <h2>${escapeHTML(profile.display_name || profile.username)}</h2>
+                <p class="profile-username">@${escapeHTML(profile.username)}</p>
+            </div>
+        </div>
+        ${profile.bio ? `<p class="profile-bio">${escapeHTML(profile.bio)}</p>` : ''}
+        <p class="profile-facts">${facts.join(' · ')}</p>
+    `;
+}
+
+function editForm(account) {
+    return `
+        <form class="profile-form">
+            <h3>Edit profile</h3>
+            <label>Display name <input type="text" name="display_name" maxlength="50" value="${escapeHTML(account.display_name)}"></label>
+            <label>Bio <textarea name="bio" maxlength="500">${escapeHTML(account.bio)}</textarea></label>
+            <label>Avatar <input type="file" name="avatar" accept="image/png,image/jpeg,image/gif"></label>
+            ${account.avatar_id ? '<label><input type="checkbox" name="remove_avatar"> Remove avatar</label>' : ''}
+            <label>Username <input type="text" name="username" required value="${escapeHTML(account.username)}"></label>
+            <label>Email <input type="email" name="email" required value="${escapeHTML(account.email)}"></label>
+            <label>First name <input type="text" name="first_name" required value="${escapeHTML(account.first_name)}"></label>
+            <label>Last name <input type="text" name="last_name" required value="${escapeHTML(account.last_name)}"></label>
+            <label>Age <input type="number" name="age" required value="${account.age}"></label>
+            <label>Gender
+                <select name="gender">
+                    ${GENDERS.map(gender => `<option value="${gender}"${gender === account.gender ? ' selected' : ''}>${gender}</option>`).join('')}
+                </select>
+            </label>
+            <fieldset class="profile-privacy">
+                <legend>Shown to others</legend>
+                ${Object.entries(PRIVACY_LABELS).map(([field, label]) => `
+                    <label><input type="checkbox" name="privacy" value="${field}"${account.privacy[field] ? ' checked' : ''}> ${label}</label>
+                `).join('')}
+            </fieldset>
+            <button type="submit">Save</button>
+        </form>
+    `;
+}
+
+function bindEditForm(form, userId) {
+    form.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const formData = new FormData(form);
+        const shown = formData.getAll('privacy');
+        const body = {
+            display_name: formData.get('display_name'),
+            bio: formData.get('bio'),
+            username: formData.get('username'),
+            email: formData.get('email'),
+            first_name: formData.get('first_name'),
+            last_name: formData.get('last_name'),
+            age: Number(formData.get('age')),
+            gender: formData.get('gender'),
+            privacy: Object.fromEntries(Object.keys(PRIVACY_LABELS).map(field => [field, shown.includes(field)])),
+        };
+        try {
+            const file = formData.get('avatar');
+            if (file && file.size > 0) {
+                [body.avatar_id] = await uploadFiles([file]);
+            } else if (formData.get('remove_avatar')) {
+                body.avatar_id = 0;
+            }
+            const account = await sendJSON('PATCH', '/api/me', body);
+            localStorage.setItem('username', account.username);
+            showAlert('Profile saved', 'success');
+            showProfile(userId);
+        } catch (error) {
+            showAlert(error.message || 'An error occurred while saving your profile', 'error');
+        }
+    });
+}
+
+export default async function Profile(userId) {
+    const container = document.createElement('div');
+    container.classList.add('profile');
+
+    let profile;
+    try {
+        profile = await sendJSON('GET', `/api/users/${userId}`);
+    } catch (error) {
+        showAlert(error.message || 'An error occurred while loading the profile', 'error');
+        return container;
+    }
+
+    container.innerHTML = `
+        <button type="button" class="exit-profile">Back</button>
+        ${profileCard(profile)}
+        ${isOwn(userId) ? '' : '<button type="button" class="message-user">Send a message</button>'}
+    `;
+    container.querySelector('.exit-profile').addEventListener('click', () => renderPage('/'));
+    container.querySelector('.message-user')?.addEventListener('click', () => onUserClick(userId, profile.username));
+
+    if (isOwn(userId)) {
+        try {
+            const account = await sendJSON('GET', '/api/me');
+            container.insertAdjacentHTML('beforeend', editForm(account));
+            bindEditForm(container.querySelector('.profile-form'), userId);
+        } catch (error) {
+            showAlert(error.message || 'An error occurred while loading your details', 'error');
+        }
+    }
+    return container;
+}
+
+// showProfile replaces the page content with the profile of a user.
+export async function showProfile(userId) {
+    const profile = await Profile(userId);
+    const contentDiv = document.querySelector('#content');
+    contentDiv.innerHTML = '';
+    contentDiv.appendChild(profile);
+}
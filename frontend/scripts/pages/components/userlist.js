@@ -1,5 +1,5 @@
 import { renderPage } from "../../router.js";
-import { showAlert } from "../../utils.js";
+import { escapeHTML, showAlert } from "../../utils.js";
 import { onUserClick } from "../home.js";
 
 let users = [];
@@ -33,8 +33,9 @@ export default async function UserList() {
             ${Array.isArray(users) && users.length > 0 ? 
                 users.map(user => `
                     <p data-user-id="${user.id}" class="user">
-                        ${user.username} 
-                        <span class="${user.online ? 'online' : 'offline'}"></span>
+                        <span class="username">${escapeHTML(user.username)}</span>
+                        <span class="${user.status}"></span>
+                        ${user.unread > 0 ? `<span class="unread">${user.unread}</span>` : ''}
                     </p>`).join('') : 
                 'No users found'}
         </div>
@@ -44,7 +45,7 @@ export default async function UserList() {
     userElements.forEach(userElement => {
         userElement.addEventListener('click', () => {
             const userId = userElement.getAttribute('data-user-id');
-            onUserClick(userId, userElement.textContent);
+            onUserClick(userId, userElement.querySelector('.username').textContent);
         });
     });
 
@@ -62,8 +63,9 @@ export function updateUserList() {
                     ${Array.isArray(data) && data.length > 0 ? 
                         data.map(user => `
                             <p data-user-id="${user.id}" class="user">
-                                ${user.username} 
-                                <span class="${user.online ? 'online' : 'offline'}"></span>
+                                <span class="username">${escapeHTML(user.username)}</span>
+                                <span class="${user.status}"></span>
+                        ${user.unread > 0 ? `<span class="unread">${user.unread}</span>` : ''}
                             </p>`).join('') : 
                         'No users found'}
                 </div>
@@ -72,7 +74,7 @@ export function updateUserList() {
             userElements.forEach(userElement => {
                 userElement.addEventListener('click', () => {
                     const userId = userElement.getAttribute('data-user-id');
-                    onUserClick(userId, userElement.textContent);
+                    onUserClick(userId, userElement.querySelector('.username').textContent);
                 });
             });
         })
@@ -1,9 +1,11 @@
 import { renderPage } from "../router.js";
-import { showAlert } from "../utils.js";
+import { showAlert, isOwn } from "../utils.js";
 import UserList from "./components/userlist.js";
 import Chat from "./components/chat.js";
 import { initWebSocket } from "../websocket.js";
 import Posts from "./components/posts.js";
+import Notifications from "./components/notifications.js";
+import { showProfile } from "./components/profile.js";
 
 export default async function home() {
     initWebSocket();
@@ -11,17 +13,38 @@ export default async function home() {
     container.innerHTML = `
         <nav class="navbar">
             <h1>Welcome ${localStorage.getItem("username")}</h1>
+            <button id="profile-button">Profile</button>
             <button id="logout-button">Logout</button>
         </nav>
         <div id="content"></div>
     `;
 
+    container.querySelector(".navbar h1").after(await Notifications());
+
     const userList = await UserList();
     container.appendChild(userList);
 
     const postsComponent = await Posts();
     container.querySelector("#content").appendChild(postsComponent);
 
+    // a mentioned user opens a chat with them, an author their profile
+    container.addEventListener("click", (e) => {
+        const author = e.target.closest(".profile-link");
+        if (author) {
+            e.preventDefault();
+            showProfile(author.dataset.userId);
+            return;
+        }
+        const mention = e.target.closest(".mention");
+        if (!mention) return;
+        e.preventDefault();
+        if (!isOwn(mention.dataset.userId)) onUserClick(mention.dataset.userId, mention.dataset.username);
+    });
+
+    container.querySelector("#profile-button").addEventListener("click", () => {
+        showProfile(localStorage.getItem("userId"));
+    });
+
     container.querySelector("#logout-button").addEventListener("click", async () => {
         const response = await fetch("/api/logout", {
             method: "POST",
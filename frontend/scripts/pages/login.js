@@ -33,6 +33,7 @@ export default function register() {
             const user = await response.json();
             localStorage.setItem('userId', user.id);
             localStorage.setItem('username', user.username);
+            localStorage.setItem('role', user.role);
             showAlert('Welcome, ' + user.username + '!', 'success', 'success');
             e.target.reset();
             setTimeout(() => {
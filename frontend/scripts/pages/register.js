@@ -1,6 +1,40 @@
 import { renderPage } from "../router.js";
 import { showAlert } from "../utils.js";
 
+const CHECK_DELAY = 400;
+
+// showFieldError displays the error of a form field, or clears it when message is empty
+function showFieldError(form, field, message) {
+    const error = form.querySelector(`.field-error[data-field="${field}"]`);
+    if (error) error.textContent = message || '';
+}
+
+// watchAvailability checks a field against the server as the user types
+function watchAvailability(form, field, endpoint) {
+    let timer = null;
+    form.querySelector(`[name="${field}"]`).addEventListener('input', (e) => {
+        clearTimeout(timer);
+        const value = e.target.value.trim();
+        if (!value) {
+            showFieldError(form, field, '');
+            return;
+        }
+        timer = setTimeout(async () => {
+            try {
+                const response = await fetch(`${endpoint}?${field}=${encodeURIComponent(value)}`);
+                const result = await response.json();
+                if (!response.ok) {
+                    showFieldError(form, field, result.fields?.[field] || result.message);
+                } else {
+                    showFieldError(form, field, result.available ? '' : result.message);
+                }
+            } catch (error) {
+                console.error('Availability check error:', error);
+            }
+        }, CHECK_DELAY);
+    });
+}
+
 export default function register() {
     const container = document.createElement('div');
     container.innerHTML = `
@@ -8,17 +42,24 @@ export default function register() {
   <h2>Registration</h2>
   <form id="registration-form">
     <input type="text" name="username" placeholder="Username" required>
+    <span class="field-error" data-field="username"></span>
     <input type="email" name="email" placeholder="Email" required>
+    <span class="field-error" data-field="email"></span>
     <input type="password" name="password" placeholder="Password" required>
+    <span class="field-error" data-field="password"></span>
     <input type="text" name="first_name" placeholder="First Name" required>
+    <span class="field-error" data-field="first_name"></span>
     <input type="text" name="last_name" placeholder="Last Name" required>
+    <span class="field-error" data-field="last_name"></span>
     <input type="number" name="age" placeholder="Age" required>
+    <span class="field-error" data-field="age"></span>
     <select name="gender" required>
       <option value="">Select Gender</option>
       <option value="male">Male</option>
       <option value="female">Female</option>
       <option value="other">Other</option>
     </select>
+    <span class="field-error" data-field="gender"></span>
     <button type="submit">Register</button>
     <p>Already have an account? <a href="#" id="login-link">Login</a></p>
   </form>
@@ -26,7 +67,11 @@ export default function register() {
 
     `;
 
-    container.querySelector('#registration-form').addEventListener('submit', async (e) => {
+    const form = container.querySelector('#registration-form');
+    watchAvailability(form, 'username', '/api/check-username');
+    watchAvailability(form, 'email', '/api/check-email');
+
+    form.addEventListener('submit', async (e) => {
         e.preventDefault();
         const formData = new FormData(e.target);
         const data = Object.fromEntries(formData.entries());
@@ -54,6 +99,8 @@ export default function register() {
             }, 1000);
         } else {
             const error = await response.json();
+            form.querySelectorAll('.field-error').forEach(span => span.textContent = '');
+            Object.entries(error.fields || {}).forEach(([field, message]) => showFieldError(form, field, message));
             showAlert(error.message || 'Registration failed, please try again', 'error');
         }
     });
@@ -38,3 +38,160 @@ export function TimeAgo(timestamp) {
         return `${days}d ago`;
     }
 }
+
+// sendJSON sends a request with an optional JSON body and returns the decoded
+// response, throwing the server's error message when the request fails.
+export async function sendJSON(method, url, body) {
+    const response = await fetch(url, {
+        method,
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        credentials: 'include',
+        body: body === undefined ? undefined : JSON.stringify(body),
+    });
+
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+        throw new Error(data.message || 'Request failed');
+    }
+    return data;
+}
+
+// escapeHTML escapes text for use inside HTML.
+export function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
+// linkMentions renders content as HTML with its @mentions linked to the users
+// they name. Mention spans are in UTF-16 code units, as JavaScript strings are.
+export function linkMentions(content, mentions = []) {
+    let html = '';
+    let offset = 0;
+    for (const mention of mentions || []) {
+        html += escapeHTML(content.slice(offset, mention.start));
+        html += `<a href="#" class="mention" data-user-id="${mention.user_id}" data-username="${escapeHTML(mention.username)}">${escapeHTML(content.slice(mention.start, mention.end))}</a>`;
+        offset = mention.end;
+    }
+    return html + escapeHTML(content.slice(offset));
+}
+
+// uploadFiles uploads files one at a time and returns the IDs of their attachments,
+// throwing the server's error message when one is rejected.
+export async function uploadFiles(files) {
+    const ids = [];
+    for (const file of files) {
+        const body = new FormData();
+        body.append('file', file);
+        const response = await fetch('/api/attachments', {
+            method: 'POST',
+            credentials: 'include',
+            body,
+        });
+        const data = await response.json().catch(() => ({}));
+        if (!response.ok) {
+            throw new Error(`${file.name}: ${data.message || 'Upload failed'}`);
+        }
+        ids.push(data.id);
+    }
+    return ids;
+}
+
+// formatSize renders a size in bytes for people.
+function formatSize(bytes) {
+    if (bytes < 1024) return `${bytes} B`;
+    if (bytes < 1024 * 1024) return `${Math.round(bytes / 1024)} KB`;
+    return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+}
+
+// attachmentList renders the attachments of a post or message: image thumbnails
+// linking to the full image, and download links for other files.
+export function attachmentList(attachments = []) {
+    if (!attachments || attachments.length === 0) return '';
+    return `
+        <div class="attachments">
+            ${attachments.map(attachment => {
+                const url = `/api/attachments/${attachment.id}`;
+                const name = escapeHTML(attachment.filename);
+                if (attachment.width) {
+                    return `<a class="attachment-image" href="${url}" target="_blank" rel="noopener"><img src="${url}/thumbnail" alt="${name}" loading="lazy"></a>`;
+                }
+                return `<a class="attachment-file" href="${url}" download="${name}">📎 ${name} <span class="attachment-size">${formatSize(attachment.size)}</span></a>`;
+            }).join('')}
+        </div>
+    `;
+}
+
+// isOwn reports whether an item was written by the logged in user.
+export function isOwn(authorId) {
+    return authorId === localStorage.getItem('userId');
+}
+
+// editedMarker renders the "(edited)" marker, hidden until the item is edited.
+export function editedMarker(item) {
+    return `<span class="edited"${item.edited_at ? '' : ' hidden'}>(edited)</span>`;
+}
+
+// isModerator reports whether the logged in user can moderate posts and comments.
+export function isModerator() {
+    return ['moderator', 'admin'].includes(localStorage.getItem('role'));
+}
+
+// ownerActions renders the edit and delete buttons shown to the author of an item;
+// moderators also get the delete button on moderated items written by others.
+export function ownerActions(authorId, moderated = false) {
+    const own = isOwn(authorId);
+    if (!own && !(moderated && isModerator())) return '';
+    return `
+        <div class="owner-actions">
+            ${own ? '<button type="button" class="edit-item">Edit</button>' : ''}
+            <button type="button" class="delete-item">Delete</button>
+        </div>
+    `;
+}
+
+const REPORT_REASONS = ['spam', 'harassment', 'hate', 'inappropriate', 'other'];
+
+// reportControls renders the report button and form offered on items written by others.
+export function reportControls(authorId) {
+    if (isOwn(authorId)) return '';
+    return `
+        <button type="button" class="report-item">Report</button>
+        <form class="report-form" style="display:none;">
+            <select name="reason">
+                ${REPORT_REASONS.map(reason => `<option value="${reason}">${reason}</option>`).join('')}
+            </select>
+            <input type="text" name="details" placeholder="Details (required for other)">
+            <button type="submit">Send report</button>
+        </form>
+    `;
+}
+
+// bindReport lets the report controls rendered in container report the target.
+export function bindReport(container, targetType, targetId) {
+    const button = container.querySelector('.report-item');
+    if (!button) return;
+
+    const form = container.querySelector('.report-form');
+    button.addEventListener('click', () => {
+        form.style.display = form.style.display === 'none' ? 'block' : 'none';
+    });
+    form.addEventListener('submit', async (e) => {
+        e.preventDefault();
+        try {
+            await sendJSON('POST', '/api/reports', {
+                target_type: targetType,
+                target_id: targetId,
+                reason: form.querySelector('select[name="reason"]').value,
+                details: form.querySelector('input[name="details"]').value,
+            });
+            form.reset();
+            form.style.display = 'none';
+            showAlert('Thanks, a moderator will review your report', 'success');
+        } catch (error) {
+            showAlert(error.message || 'Failed to send report', 'error');
+        }
+    });
+}
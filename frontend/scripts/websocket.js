@@ -1,10 +1,20 @@
 import { showAlert } from "./utils.js";
-import { inChat, chatingWith, updateChat } from "./pages/components/chat.js";
+import { inChat, chatingWith, appendMessage, setTyping, applyMessageUpdate, removeMessage } from "./pages/components/chat.js";
 import { getUser, updateUserList } from "./pages/components/userlist.js";
+import { applyReaction, applyPostUpdate, removePost, applyCommentUpdate, removeComment } from "./pages/components/posts.js";
+import { receiveNotification, applyNotificationsRead } from "./pages/components/notifications.js";
 import { renderPage } from "./router.js";
 
 let socket;
 const currentUserId = localStorage.getItem('userId');
+const PROTOCOL_VERSION = 1;
+const HEARTBEAT_INTERVAL = 30000;
+
+let heartbeat;
+let active = true;
+['mousemove', 'keydown', 'click', 'scroll'].forEach(type => {
+    addEventListener(type, () => { active = true; }, { passive: true });
+});
 
 export function initWebSocket() {
     if (socket && socket.readyState === WebSocket.OPEN) return;
@@ -14,6 +24,11 @@ export function initWebSocket() {
 
     socket.onopen = () => {
         console.log('WebSocket connection established');
+        clearInterval(heartbeat);
+        heartbeat = setInterval(() => {
+            sendEvent('heartbeat', { active });
+            active = false;
+        }, HEARTBEAT_INTERVAL);
     };
 
     socket.onmessage = handleMessage;
@@ -24,6 +39,7 @@ export function initWebSocket() {
     };
 
     socket.onclose = () => {
+        clearInterval(heartbeat);
         showAlert('WebSocket connection closed, please log in again', 'error');
         setTimeout(() => {
             renderPage('/login');
@@ -31,33 +47,92 @@ export function initWebSocket() {
     };
 }
 
+export function sendEvent(type, payload) {
+    if (!socket || socket.readyState !== WebSocket.OPEN) {
+        showAlert('Connection lost, please try again', 'error');
+        return null;
+    }
+    const id = crypto.randomUUID();
+    socket.send(JSON.stringify({
+        type,
+        version: PROTOCOL_VERSION,
+        id,
+        timestamp: new Date().toISOString(),
+        payload,
+    }));
+    return id;
+}
+
 function handleMessage(event) {
     const message = JSON.parse(event.data);
 
     switch (message.type) {
         case 'message':
-            handleChatMessage(message.content);
+            handleChatMessage(message.payload);
             break;
-        case 'user_connected':
-        case 'user_disconnected':
+        case 'presence':
             updateUserList();
             break;
+        case 'typing_start':
+        case 'typing_stop':
+            setTyping(message.payload.user_id, message.type === 'typing_start');
+            break;
+        case 'messages_read':
+            updateUserList();
+            break;
+        case 'reaction':
+            applyReaction(message.payload);
+            break;
+        case 'post_updated':
+            applyPostUpdate(message.payload);
+            break;
+        case 'post_deleted':
+            removePost(message.payload);
+            break;
+        case 'comment_updated':
+            applyCommentUpdate(message.payload);
+            break;
+        case 'comment_deleted':
+            removeComment(message.payload);
+            break;
+        case 'message_updated':
+            applyMessageUpdate(message.payload);
+            break;
+        case 'message_deleted':
+            removeMessage(message.payload);
+            break;
+        case 'report_created':
+            showAlert(`New ${message.payload.reason} report on a ${message.payload.target_type}`, 'success');
+            break;
+        case 'report_updated':
+            break;
+        case 'notification':
+            receiveNotification(message.payload);
+            break;
+        case 'notifications_read':
+            applyNotificationsRead(message.payload);
+            break;
+        case 'ack':
+            break;
+        case 'error':
+            console.error('WebSocket event rejected:', message.payload);
+            showAlert(message.payload.message, 'error');
+            break;
         default:
             console.warn('Unknown message type:', message.type);
     }
 }
 
 function handleChatMessage(message) {
-    const ids = message.split(',');
-    let sender_id = ids[0];
-    let receiver_id = ids[1];
+    const { sender_id, receiver_id } = message;
+    const otherUserId = sender_id === currentUserId ? receiver_id : sender_id;
 
     if (receiver_id === currentUserId) {
         showAlert(`New message from ${getUser(sender_id)}`, 'success');
-        if (inChat && chatingWith === sender_id) {
-            updateChat(receiver_id);
-            updateChat(sender_id);
-        }
+    }
+    if (inChat && chatingWith === otherUserId) {
+        setTyping(sender_id, false);
+        appendMessage(message);
     }
     if (sender_id === currentUserId || receiver_id === currentUserId) {
         updateUserList();